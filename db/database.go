@@ -33,6 +33,7 @@ func InitDB(redisURL string) (*redis.Client, error) {
 	}
 
 	log.Println("Connected to Redis")
+	migrateLegacyKeys()
 	return RDb, nil
 }
 
@@ -40,7 +41,7 @@ func Get(key string) string {
 	if RDb == nil {
 		return ""
 	}
-	val, _ := RDb.Get(ctx, key).Result()
+	val, _ := RDb.Get(ctx, Key(key)).Result()
 	return val
 }
 
@@ -48,64 +49,81 @@ func Set(key, value string) error {
 	if RDb == nil {
 		return nil
 	}
-	return RDb.Set(ctx, key, value, 0).Err()
+	return RDb.Set(ctx, Key(key), value, 0).Err()
 }
 
-func Del(key string) error {
+// SetEx stores a value that Redis removes by itself after ttl.
+func SetEx(key, value string, ttl time.Duration) error {
 	if RDb == nil {
 		return nil
 	}
-	return RDb.Del(ctx, key).Err()
+	return RDb.Set(ctx, Key(key), value, ttl).Err()
 }
 
-func Exists(key string) bool {
+// Incr atomically increments an integer key and returns the new value.
+func Incr(key string) (int64, error) {
 	if RDb == nil {
-		return false
+		return 0, nil
 	}
-	val, _ := RDb.Exists(ctx, key).Result()
-	return val > 0
+	return RDb.Incr(ctx, Key(key)).Result()
 }
 
-func Keys(pattern string) ([]string, error) {
+func Del(key string) error {
 	if RDb == nil {
-		return nil, nil
+		return nil
+	}
+	return RDb.Del(ctx, Key(key)).Err()
+}
+
+func Exists(key string) bool {
+	if RDb == nil {
+		return false
 	}
-	return RDb.Keys(ctx, pattern).Result()
+	val, _ := RDb.Exists(ctx, Key(key)).Result()
+	return val > 0
 }
 
 func SAdd(key string, members ...interface{}) error {
 	if RDb == nil {
 		return nil
 	}
-	return RDb.SAdd(ctx, key, members...).Err()
+	return RDb.SAdd(ctx, Key(key), members...).Err()
 }
 
 func SRem(key string, members ...interface{}) error {
 	if RDb == nil {
 		return nil
 	}
-	return RDb.SRem(ctx, key, members...).Err()
+	return RDb.SRem(ctx, Key(key), members...).Err()
 }
 
 func SMembers(key string) ([]string, error) {
 	if RDb == nil {
 		return nil, nil
 	}
-	return RDb.SMembers(ctx, key).Result()
+	return RDb.SMembers(ctx, Key(key)).Result()
 }
 
 func SIsMember(key string, member interface{}) bool {
 	if RDb == nil {
 		return false
 	}
-	return RDb.SIsMember(ctx, key, member).Val()
+	return RDb.SIsMember(ctx, Key(key), member).Val()
 }
 
-func FlushAll() error {
-	if RDb == nil {
+// HSet stores fields of a hash, leaving its other fields alone.
+func HSet(key string, fields map[string]string) error {
+	if RDb == nil || len(fields) == 0 {
 		return nil
 	}
-	return RDb.FlushAll(ctx).Err()
+	return RDb.HSet(ctx, Key(key), fields).Err()
+}
+
+func HGetAll(key string) (map[string]string, error) {
+	if RDb == nil {
+		return nil, nil
+	}
+	return RDb.HGetAll(ctx, Key(key)).Result()
 }
 
 func Close() error {
@@ -0,0 +1,176 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SchemaVersionKey holds the version of the stored data layout.
+const SchemaVersionKey = "SCHEMA_VERSION"
+
+// Migration upgrades stored data from Version-1 to Version. Migrations run in
+// order at startup and the reached version is recorded after each one, so an
+// interrupted run resumes where it stopped. Up must be safe to run on data
+// that is already migrated.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func() error
+}
+
+var migrations = []Migration{
+	{Version: 1, Name: "split GBANS into one GBAN:<id> key per user", Up: splitGbans},
+	{Version: 2, Name: "store CLONE_MAP:<id> reply maps as hashes", Up: hashCloneMaps},
+}
+
+// LatestSchema is the schema version this build writes.
+func LatestSchema() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// SchemaVersion returns the version recorded in the database, 0 if none.
+func SchemaVersion() int {
+	v, _ := strconv.Atoi(Get(SchemaVersionKey))
+	return v
+}
+
+func setSchemaVersion(v int) error {
+	return Set(SchemaVersionKey, strconv.Itoa(v))
+}
+
+// Migrate runs every pending migration. A fresh database is stamped with the
+// latest version directly. It fails if the data was written by a newer build.
+func Migrate() error {
+	if RDb == nil {
+		return nil
+	}
+
+	current := SchemaVersion()
+	latest := LatestSchema()
+
+	if current > latest {
+		return fmt.Errorf("database schema version %d is newer than this build supports (%d)", current, latest)
+	}
+	if current == 0 && !Exists(SchemaVersionKey) {
+		if keys, err := Scan("*"); err == nil && len(keys) == 0 {
+			return setSchemaVersion(latest)
+		}
+	}
+
+	for _, m := range migrations {
+		if m.Version <= current {
+			continue
+		}
+		log.Printf("Running migration %d: %s", m.Version, m.Name)
+		if err := m.Up(); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", m.Version, m.Name, err)
+		}
+		if err := setSchemaVersion(m.Version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Schema describes a JSON value stored under a key or key pattern, so Verify
+// can check it decodes before anything reads it.
+type Schema struct {
+	Pattern string
+	New     func() any
+}
+
+// Verify decodes every stored value matching the schemas and reports all the
+// keys that fail, instead of letting callers silently read zero values.
+func Verify(schemas []Schema) error {
+	if RDb == nil {
+		return nil
+	}
+
+	var corrupt []string
+	for _, schema := range schemas {
+		keys := []string{schema.Pattern}
+		if strings.ContainsAny(schema.Pattern, "*?[") {
+			var err error
+			if keys, err = Scan(schema.Pattern); err != nil {
+				return err
+			}
+		}
+
+		for _, key := range keys {
+			data := Get(key)
+			if data == "" {
+				continue
+			}
+			if err := json.Unmarshal([]byte(data), schema.New()); err != nil {
+				corrupt = append(corrupt, fmt.Sprintf("%s (%v)", key, err))
+			}
+		}
+	}
+
+	if len(corrupt) > 0 {
+		slices.Sort(corrupt)
+		return fmt.Errorf("corrupted data in %d keys: %s", len(corrupt), strings.Join(corrupt, "; "))
+	}
+	return nil
+}
+
+// splitGbans moves the single GBANS map into GBAN:<id> keys so a gban or
+// ungban no longer rewrites every entry.
+func splitGbans() error {
+	data := Get("GBANS")
+	if data == "" {
+		return nil
+	}
+
+	var bans map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(data), &bans); err != nil {
+		return fmt.Errorf("GBANS is not valid JSON: %w", err)
+	}
+
+	for id, info := range bans {
+		if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+			return fmt.Errorf("GBANS has an invalid user id %q", id)
+		}
+		if err := Set("GBAN:"+id, string(info)); err != nil {
+			return err
+		}
+	}
+	return Del("GBANS")
+}
+
+// hashCloneMaps turns the JSON reply maps of clone jobs into hashes, so a
+// copied message adds one field instead of rewriting the whole map.
+func hashCloneMaps() error {
+	keys, err := Scan("CLONE_MAP:*")
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if RDb.Type(ctx, Key(key)).Val() != "string" {
+			continue
+		}
+		var ids map[string]int32
+		if err := json.Unmarshal([]byte(Get(key)), &ids); err != nil {
+			return fmt.Errorf("%s is not valid JSON: %w", key, err)
+		}
+		fields := make(map[string]string, len(ids))
+		for src, dst := range ids {
+			fields[src] = strconv.Itoa(int(dst))
+		}
+		pipe := RDb.TxPipeline()
+		pipe.Del(ctx, Key(key))
+		if len(fields) > 0 {
+			pipe.HSet(ctx, Key(key), fields)
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package db
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultPrefix namespaces every key Nova stores, so the userbot can share a
+// Redis server with other applications.
+const DefaultPrefix = "nova:"
+
+const scanBatch = 200
+
+var (
+	prefix         = DefaultPrefix
+	errEmptyPrefix = errors.New("refusing to delete without a key prefix")
+)
+
+// legacyKeys are the keys older versions stored without a prefix. Entries
+// ending in "*" are patterns.
+var legacyKeys = []string{
+	"CMD_HANDLER", "ALIVE_IMAGE", "LOG_CHAT", "LOG_ROUTES", "BOT_LANGUAGE",
+	"GIT_TOKEN", "UPSTREAM_REPO", "UPSTREAM_BRANCH", "GEMINI_API_KEY", "PM_AI_PROMT",
+	"TAG_LOGGER", "TAG_BATCH", "TAG_KEYWORDS", "TAG_MUTED", "MSG_LOGGER",
+	"GBANS", "AFK_DATA", "AUTO_AFK", "REMINDERS", "GDRIVE_CONFIG",
+	"SUDOS", "APPROVED_USERS", "ANTISPAM",
+	"USER_LANG_*", "CHAT_LANG_*", "BANGUARD:*",
+}
+
+// SetPrefix changes the key namespace. It must be called before InitDB.
+func SetPrefix(p string) {
+	prefix = p
+}
+
+func Prefix() string {
+	return prefix
+}
+
+// Key returns the namespaced Redis key for name.
+func Key(name string) string {
+	return prefix + name
+}
+
+// Scan returns the names of all keys in the namespace matching pattern,
+// without the prefix. It uses SCAN so large databases are not blocked.
+func Scan(pattern string) ([]string, error) {
+	if RDb == nil {
+		return nil, nil
+	}
+
+	keys, err := scanRaw(Key(pattern))
+	for i, key := range keys {
+		keys[i] = strings.TrimPrefix(key, prefix)
+	}
+	return keys, err
+}
+
+func scanRaw(pattern string) ([]string, error) {
+	var keys []string
+	iter := RDb.Scan(ctx, 0, pattern, scanBatch).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	return keys, iter.Err()
+}
+
+// DeleteNamespace removes every key under the prefix and nothing else. With
+// an empty prefix it refuses, since that would mean the whole database.
+func DeleteNamespace() (int, error) {
+	if RDb == nil {
+		return 0, nil
+	}
+	if prefix == "" {
+		return 0, errEmptyPrefix
+	}
+
+	names, err := Scan("*")
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for start := 0; start < len(names); start += scanBatch {
+		end := min(start+scanBatch, len(names))
+		keys := make([]string, 0, end-start)
+		for _, name := range names[start:end] {
+			keys = append(keys, Key(name))
+		}
+		n, err := RDb.Del(ctx, keys...).Result()
+		deleted += int(n)
+		if err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, nil
+}
+
+// Entry is a single key as exported by Dump. ExpiresAt is the Unix time in
+// milliseconds a volatile key expires at.
+type Entry struct {
+	Type      string            `json:"type"`
+	Value     string            `json:"value,omitempty"`
+	Members   []string          `json:"members,omitempty"`
+	Fields    map[string]string `json:"fields,omitempty"`
+	ExpiresAt int64             `json:"expires_at,omitempty"`
+}
+
+// Dump reads every string, set and hash in the namespace, keyed by name.
+func Dump() (map[string]Entry, error) {
+	if RDb == nil {
+		return nil, nil
+	}
+
+	names, err := Scan("*")
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]Entry, len(names))
+	for _, name := range names {
+		key := Key(name)
+		switch RDb.Type(ctx, key).Val() {
+		case "string":
+			out[name] = Entry{Type: "string", Value: RDb.Get(ctx, key).Val()}
+		case "set":
+			out[name] = Entry{Type: "set", Members: RDb.SMembers(ctx, key).Val()}
+		case "hash":
+			out[name] = Entry{Type: "hash", Fields: RDb.HGetAll(ctx, key).Val()}
+		default:
+			log.Warnf("Skipping %s: unsupported type", name)
+			continue
+		}
+		if ttl := RDb.PTTL(ctx, key).Val(); ttl > 0 {
+			entry := out[name]
+			entry.ExpiresAt = time.Now().Add(ttl).UnixMilli()
+			out[name] = entry
+		}
+	}
+	return out, nil
+}
+
+// migrateLegacyKeys moves unprefixed keys written by older versions into the
+// namespace. It only runs while the namespace is still empty, so it never
+// overwrites newer data.
+func migrateLegacyKeys() {
+	if prefix == "" {
+		return
+	}
+	if existing, err := Scan("*"); err != nil || len(existing) > 0 {
+		return
+	}
+
+	moved := 0
+	for _, name := range legacyKeys {
+		keys := []string{name}
+		if strings.HasSuffix(name, "*") {
+			var err error
+			if keys, err = scanRaw(name); err != nil {
+				continue
+			}
+		}
+		for _, key := range keys {
+			if ok, _ := RDb.RenameNX(ctx, key, Key(key)).Result(); ok {
+				moved++
+			}
+		}
+	}
+	if moved > 0 {
+		log.Printf("Moved %d legacy keys under %q", moved, prefix)
+	}
+}
+
+// Restore writes entries exported by Dump back into the namespace. Existing
+// keys with the same name are replaced; other keys are left alone.
+func Restore(entries map[string]Entry) (int, error) {
+	if RDb == nil {
+		return 0, nil
+	}
+
+	pipe := RDb.TxPipeline()
+	restored := 0
+	for name, entry := range entries {
+		key := Key(name)
+		var ttl time.Duration
+		if entry.ExpiresAt != 0 {
+			if ttl = time.Until(time.UnixMilli(entry.ExpiresAt)); ttl <= 0 {
+				continue
+			}
+		}
+
+		switch entry.Type {
+		case "string":
+			pipe.Set(ctx, key, entry.Value, 0)
+		case "set":
+			pipe.Del(ctx, key)
+			if len(entry.Members) > 0 {
+				members := make([]interface{}, len(entry.Members))
+				for i, m := range entry.Members {
+					members[i] = m
+				}
+				pipe.SAdd(ctx, key, members...)
+			}
+		case "hash":
+			pipe.Del(ctx, key)
+			if len(entry.Fields) > 0 {
+				pipe.HSet(ctx, key, entry.Fields)
+			}
+		default:
+			continue
+		}
+		if ttl > 0 {
+			pipe.PExpire(ctx, key, ttl)
+		}
+		restored++
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return restored, nil
+}
@@ -1,6 +1,7 @@
 package locales
 
 import (
+	"NovaUserbot/db"
 	"context"
 	"embed"
 	"fmt"
@@ -9,7 +10,6 @@ import (
 
 	"github.com/go-redis/redis/v8"
 	log "github.com/sirupsen/logrus"
-	"gopkg.in/yaml.v3"
 )
 
 //go:embed locales/*.yml
@@ -18,8 +18,16 @@ var localeFiles embed.FS
 type Translations struct {
 	mu          sync.RWMutex
 	languages   map[string]map[string]interface{}
+	embedded    map[string]map[string]interface{}
 	defaultLang string
 	db          *redis.Client
+
+	// language preferences read from Redis, cached after the first lookup;
+	// "" marks an id without an override
+	prefMu     sync.RWMutex
+	globalLang string
+	userLangs  map[int64]string
+	chatLangs  map[int64]string
 }
 
 var (
@@ -31,15 +39,25 @@ func GetInstance() *Translations {
 	once.Do(func() {
 		instance = &Translations{
 			languages:   make(map[string]map[string]interface{}),
+			embedded:    make(map[string]map[string]interface{}),
 			defaultLang: "en",
+			userLangs:   make(map[int64]string),
+			chatLangs:   make(map[int64]string),
 		}
 	})
 	return instance
 }
 
-func Init(db *redis.Client) error {
+func Init(rdb *redis.Client) error {
 	t := GetInstance()
-	t.db = db
+	t.db = rdb
+
+	if rdb != nil {
+		lang, _ := rdb.Get(context.Background(), globalLangKey()).Result()
+		t.prefMu.Lock()
+		t.globalLang = lang
+		t.prefMu.Unlock()
+	}
 
 	entries, err := localeFiles.ReadDir("locales")
 	if err != nil {
@@ -55,7 +73,11 @@ func Init(db *redis.Client) error {
 		}
 	}
 
-	log.Printf("Loaded %d languages", len(t.languages))
+	if _, err := LoadDir(PackDir()); err != nil {
+		log.Printf("Warning: Could not load language packs from %s: %v", PackDir(), err)
+	}
+
+	log.Printf("Loaded %d languages", len(GetAvailableLanguages()))
 	return nil
 }
 
@@ -65,53 +87,163 @@ func (t *Translations) loadLanguageFile(filename string) error {
 		return fmt.Errorf("failed to read file: %w", err)
 	}
 
-	var langData map[string]interface{}
-	if err := yaml.Unmarshal(data, &langData); err != nil {
-		return fmt.Errorf("failed to parse YAML: %w", err)
+	langData, err := parsePack(data)
+	if err != nil {
+		return err
 	}
 
 	langCode := strings.TrimSuffix(filename, ".yml")
 
 	t.mu.Lock()
+	t.embedded[langCode] = langData
 	t.languages[langCode] = langData
 	t.mu.Unlock()
 
 	return nil
 }
 
-func (t *Translations) GetUserLanguage(userID int64) string {
-	if t.db == nil {
+func globalLangKey() string           { return db.Key("BOT_LANGUAGE") }
+func userLangKey(userID int64) string { return db.Key(fmt.Sprintf("USER_LANG_%d", userID)) }
+func chatLangKey(chatID int64) string { return db.Key(fmt.Sprintf("CHAT_LANG_%d", chatID)) }
+
+func (t *Translations) prefCache(chat bool) map[int64]string {
+	if chat {
+		return t.chatLangs
+	}
+	return t.userLangs
+}
+
+func prefKey(chat bool, id int64) string {
+	if chat {
+		return chatLangKey(id)
+	}
+	return userLangKey(id)
+}
+
+// cachedPref returns the chat or user override for id, loading it from Redis
+// once and serving it from memory afterwards.
+func (t *Translations) cachedPref(chat bool, id int64) string {
+	t.prefMu.RLock()
+	lang, ok := t.prefCache(chat)[id]
+	t.prefMu.RUnlock()
+	if ok || t.db == nil {
+		return lang
+	}
+
+	lang, _ = t.db.Get(context.Background(), prefKey(chat, id)).Result()
+	t.prefMu.Lock()
+	t.prefCache(chat)[id] = lang
+	t.prefMu.Unlock()
+	return lang
+}
+
+func (t *Translations) GetGlobalLanguage() string {
+	t.prefMu.RLock()
+	defer t.prefMu.RUnlock()
+	if t.globalLang == "" {
 		return t.defaultLang
 	}
+	return t.globalLang
+}
+
+func (t *Translations) GetUserLanguage(userID int64) string {
+	if lang := t.cachedPref(false, userID); lang != "" {
+		return lang
+	}
+	return t.GetGlobalLanguage()
+}
 
-	lang, err := t.db.Get(context.Background(), fmt.Sprintf("USER_LANG_%d", userID)).Result()
-	if err != nil || lang == "" {
-		lang, err = t.db.Get(context.Background(), "BOT_LANGUAGE").Result()
-		if err != nil || lang == "" {
-			return t.defaultLang
+// Resolve picks the language for a reply: the chat's override first, then
+// the user's own preference, then the global language.
+func (t *Translations) Resolve(userID, chatID int64) string {
+	if chatID != 0 {
+		if lang := t.cachedPref(true, chatID); lang != "" {
+			return lang
 		}
 	}
-	return lang
+	return t.GetUserLanguage(userID)
 }
 
-func (t *Translations) SetUserLanguage(userID int64, lang string) error {
-	if t.db == nil {
-		return nil
+// GetChatLanguage returns the chat override, or "" if the chat has none.
+func (t *Translations) GetChatLanguage(chatID int64) string {
+	return t.cachedPref(true, chatID)
+}
+
+// HasUserLanguage reports whether the user has a language of their own.
+func (t *Translations) HasUserLanguage(userID int64) bool {
+	return t.cachedPref(false, userID) != ""
+}
+
+func (t *Translations) setPref(chat bool, id int64, lang string) error {
+	if t.db != nil {
+		var err error
+		if lang == "" {
+			err = t.db.Del(context.Background(), prefKey(chat, id)).Err()
+		} else {
+			err = t.db.Set(context.Background(), prefKey(chat, id), lang, 0).Err()
+		}
+		if err != nil {
+			return err
+		}
 	}
-	return t.db.Set(context.Background(), fmt.Sprintf("USER_LANG_%d", userID), lang, 0).Err()
+
+	t.prefMu.Lock()
+	t.prefCache(chat)[id] = lang
+	t.prefMu.Unlock()
+	return nil
+}
+
+// SetUserLanguage stores a per-user language; an empty lang removes it.
+func (t *Translations) SetUserLanguage(userID int64, lang string) error {
+	return t.setPref(false, userID, lang)
+}
+
+// SetChatLanguage stores a per-chat language; an empty lang removes it.
+func (t *Translations) SetChatLanguage(chatID int64, lang string) error {
+	return t.setPref(true, chatID, lang)
 }
 
 func (t *Translations) SetGlobalLanguage(lang string) error {
-	if t.db == nil {
-		return nil
+	if t.db != nil {
+		if err := t.db.Set(context.Background(), globalLangKey(), lang, 0).Err(); err != nil {
+			return err
+		}
+	}
+
+	t.prefMu.Lock()
+	t.globalLang = lang
+	t.prefMu.Unlock()
+	return nil
+}
+
+// ReloadPreferences drops the cached overrides so they are read from Redis
+// again, e.g. after the keys were changed with .setvar.
+func (t *Translations) ReloadPreferences() {
+	var global string
+	if t.db != nil {
+		global, _ = t.db.Get(context.Background(), globalLangKey()).Result()
 	}
-	return t.db.Set(context.Background(), "BOT_LANGUAGE", lang, 0).Err()
+
+	t.prefMu.Lock()
+	t.globalLang = global
+	t.userLangs = make(map[int64]string)
+	t.chatLangs = make(map[int64]string)
+	t.prefMu.Unlock()
 }
 
 func (t *Translations) Get(lang, key string) string {
+	return t.GetN(lang, key, -1)
+}
+
+// GetN looks up key like Get, picking the plural form for n when the value is
+// a map of forms ({one, other, ...}). A negative n selects "other".
+func (t *Translations) GetN(lang, key string, n int) string {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
+	return t.lookup(lang, key, n)
+}
 
+func (t *Translations) lookup(lang, key string, n int) string {
 	langData, exists := t.languages[lang]
 	if !exists {
 		langData = t.languages[t.defaultLang]
@@ -129,18 +261,29 @@ func (t *Translations) Get(lang, key string) string {
 			current = v[part]
 		default:
 			if lang != t.defaultLang {
-				return t.Get(t.defaultLang, key)
+				return t.lookup(t.defaultLang, key, n)
 			}
 			return key
 		}
 	}
 
+	if forms, ok := current.(map[string]interface{}); ok && isPluralNode(forms) {
+		form := "other"
+		if n >= 0 {
+			form = pluralForm(lang, n)
+		}
+		if _, ok := forms[form]; !ok {
+			form = "other"
+		}
+		current = forms[form]
+	}
+
 	if str, ok := current.(string); ok {
 		return str
 	}
 
 	if lang != t.defaultLang {
-		return t.Get(t.defaultLang, key)
+		return t.lookup(t.defaultLang, key, n)
 	}
 	return key
 }
@@ -150,8 +293,17 @@ func (t *Translations) GetForUser(userID int64, key string) string {
 	return t.Get(lang, key)
 }
 
+// Tr translates key in the global language. Replies to a command should use
+// Resolve (via TrFor) so per-chat and per-user languages apply.
 func Tr(key string) string {
-	return GetInstance().Get(GetInstance().defaultLang, key)
+	t := GetInstance()
+	return t.Get(t.GetGlobalLanguage(), key)
+}
+
+// TrFor translates key for a message sent by userID in chatID.
+func TrFor(userID, chatID int64, key string) string {
+	t := GetInstance()
+	return t.Get(t.Resolve(userID, chatID), key)
 }
 
 func TrUser(userID int64, key string) string {
@@ -162,6 +314,17 @@ func TrLang(lang, key string) string {
 	return GetInstance().Get(lang, key)
 }
 
+// TrN translates a plural key for count n in the global language.
+func TrN(key string, n int) string {
+	t := GetInstance()
+	return t.GetN(t.GetGlobalLanguage(), key, n)
+}
+
+// TrLangN translates a plural key for count n in lang.
+func TrLangN(lang, key string, n int) string {
+	return GetInstance().GetN(lang, key, n)
+}
+
 func GetAvailableLanguages() []string {
 	t := GetInstance()
 	t.mu.RLock()
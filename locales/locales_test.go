@@ -0,0 +1,206 @@
+package locales
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+// keyArgs maps translation helpers to the position of their key argument.
+var keyArgs = map[string]int{
+	"locales.Tr":      0,
+	"locales.TrN":     0,
+	"locales.TrUser":  1,
+	"locales.TrLang":  1,
+	"locales.TrLangN": 1,
+	"locales.TrFor":   2,
+	"tr":              1,
+}
+
+func loadForTest(t *testing.T) {
+	t.Helper()
+	if err := Init(nil); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	if len(GetInstance().embedded[GetInstance().defaultLang]) == 0 {
+		t.Fatal("en.yml is not embedded")
+	}
+}
+
+func callName(fun ast.Expr) string {
+	switch f := fun.(type) {
+	case *ast.Ident:
+		return f.Name
+	case *ast.SelectorExpr:
+		if x, ok := f.X.(*ast.Ident); ok {
+			return x.Name + "." + f.Sel.Name
+		}
+	}
+	return ""
+}
+
+// referencedKeys returns every literal translation key used in modules/*.go,
+// mapped to the first place it appears.
+func referencedKeys(t *testing.T) map[string]string {
+	t.Helper()
+
+	files, err := filepath.Glob(filepath.Join("..", "modules", "*.go"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no module sources found: %v", err)
+	}
+
+	keys := make(map[string]string)
+	fset := token.NewFileSet()
+	for _, path := range files {
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			t.Fatalf("parse %s: %v", path, err)
+		}
+
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			idx, ok := keyArgs[callName(call.Fun)]
+			if !ok || idx >= len(call.Args) {
+				return true
+			}
+			lit, ok := call.Args[idx].(*ast.BasicLit)
+			if !ok || lit.Kind != token.STRING {
+				return true
+			}
+			key, err := strconv.Unquote(lit.Value)
+			if err != nil {
+				return true
+			}
+			if _, seen := keys[key]; !seen {
+				keys[key] = fset.Position(lit.Pos()).String()
+			}
+			return true
+		})
+	}
+	return keys
+}
+
+func TestReferencedKeysExistInEnglish(t *testing.T) {
+	loadForTest(t)
+
+	en := make(map[string]string)
+	flatten("", GetInstance().embedded["en"], en)
+
+	keys := referencedKeys(t)
+	for key, pos := range keys {
+		if _, ok := en[key]; !ok {
+			t.Errorf("%s: key %q is not defined in en.yml", pos, key)
+		}
+	}
+	t.Logf("%d keys referenced from modules, %d defined in en.yml", len(keys), len(en))
+}
+
+func TestLanguagePlaceholders(t *testing.T) {
+	loadForTest(t)
+
+	reports := ValidateAll()
+	if len(reports) == 0 {
+		t.Skip("no translations besides en")
+	}
+
+	for _, report := range reports {
+		t.Logf("%s: %.1f%% coverage, %d missing, %d unknown", report.Lang, report.Coverage(), len(report.Missing), len(report.Unknown))
+		for _, key := range report.Mismatched {
+			t.Errorf("%s: placeholders of %q differ from en.yml", report.Lang, key)
+		}
+	}
+}
+
+func TestPlaceholderSignature(t *testing.T) {
+	cases := []struct {
+		a, b string
+		same bool
+	}{
+		{"<b>%s</b> (%d)", "%s को (%d)", true},
+		{"%s and %d", "%d and %s", false},
+		{"%s", "%s %s", false},
+		{"100%% done: %s", "%s", true},
+		{"{user} and {chat}", "{chat}: {user}", true},
+		{"{user}", "{name}", false},
+	}
+
+	for _, c := range cases {
+		if got := placeholderSignature(c.a) == placeholderSignature(c.b); got != c.same {
+			t.Errorf("signature(%q) == signature(%q) = %v, want %v", c.a, c.b, got, c.same)
+		}
+	}
+}
+
+func TestValidatePack(t *testing.T) {
+	base := map[string]interface{}{
+		"afk": map[string]interface{}{
+			"reply":   "AFK for %s: %s",
+			"back_in": "back in %s",
+			"count":   map[string]interface{}{"one": "{count} message", "other": "{count} messages"},
+		},
+	}
+	pack := map[string]interface{}{
+		"afk": map[string]interface{}{
+			"reply": "%d AFK",
+			"count": map[string]interface{}{"one": "{count} संदेश", "other": "{count} संदेश"},
+			"extra": "x",
+		},
+	}
+
+	report := validate("xx", base, pack)
+	if !slices.Equal(report.Missing, []string{"afk.back_in"}) {
+		t.Errorf("Missing = %v", report.Missing)
+	}
+	if !slices.Equal(report.Mismatched, []string{"afk.reply"}) {
+		t.Errorf("Mismatched = %v", report.Mismatched)
+	}
+	if !slices.Equal(report.Unknown, []string{"afk.extra"}) {
+		t.Errorf("Unknown = %v", report.Unknown)
+	}
+	if report.OK() {
+		t.Error("report with mismatches should not be OK")
+	}
+}
+
+func TestPluralsAndNamedPlaceholders(t *testing.T) {
+	tr := &Translations{
+		defaultLang: "en",
+		languages: map[string]map[string]interface{}{
+			"en": {"x": map[string]interface{}{"items": map[string]interface{}{"one": "{count} item", "other": "{count} items"}}},
+			"hi": {"x": map[string]interface{}{"items": map[string]interface{}{"one": "{count} चीज़", "other": "{count} चीज़ें"}}},
+		},
+	}
+
+	cases := []struct {
+		lang string
+		n    int
+		want string
+	}{
+		{"en", 1, "1 item"},
+		{"en", 0, "0 items"},
+		{"en", 5, "5 items"},
+		{"hi", 0, "0 चीज़"},
+		{"hi", 2, "2 चीज़ें"},
+		{"fr", 1, "1 item"},
+	}
+	for _, c := range cases {
+		got := Format(tr.GetN(c.lang, "x.items", c.n), Args{"count": c.n})
+		if got != c.want {
+			t.Errorf("GetN(%s, %d) = %q, want %q", c.lang, c.n, got, c.want)
+		}
+	}
+
+	if got := tr.Get("en", "x.items"); got != "{count} items" {
+		t.Errorf("Get on plural key = %q, want the other form", got)
+	}
+	if got := Format("{user} in {chat}", Args{"user": "nova"}); got != "nova in {chat}" {
+		t.Errorf("Format kept unknown placeholder wrong: %q", got)
+	}
+}
@@ -0,0 +1,324 @@
+package locales
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// Report describes how a language pack compares to en.yml.
+type Report struct {
+	Lang       string
+	Total      int
+	Missing    []string
+	Unknown    []string
+	Mismatched []string
+}
+
+// Coverage is the share of en.yml keys the pack translates, in percent.
+func (r Report) Coverage() float64 {
+	if r.Total == 0 {
+		return 100
+	}
+	return float64(r.Total-len(r.Missing)) * 100 / float64(r.Total)
+}
+
+// OK reports whether the pack can be loaded. Missing keys fall back to
+// English, but a placeholder mismatch would break formatting.
+func (r Report) OK() bool {
+	return len(r.Mismatched) == 0
+}
+
+var (
+	langCodePattern    = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})?$`)
+	printfVerbPattern  = regexp.MustCompile(`%[-+# 0]*[0-9]*(\.[0-9]+)?[a-zA-Z%]`)
+	namedPlaceholder   = regexp.MustCompile(`\{([a-zA-Z_][a-zA-Z0-9_]*)\}`)
+	pluralForms        = []string{"zero", "one", "two", "few", "many", "other"}
+	defaultPackDirName = "langs"
+)
+
+// PackDir is the directory external .yml packs are loaded from and uploaded
+// packs are saved to. It can be changed with LOCALES_DIR.
+func PackDir() string {
+	if dir := os.Getenv("LOCALES_DIR"); dir != "" {
+		return dir
+	}
+	return defaultPackDirName
+}
+
+// ValidLangCode reports whether code is usable as a pack name, like "hi" or
+// "pt-br".
+func ValidLangCode(code string) bool {
+	return langCodePattern.MatchString(code)
+}
+
+func parsePack(data []byte) (map[string]interface{}, error) {
+	var langData map[string]interface{}
+	if err := yaml.Unmarshal(data, &langData); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+	if langData == nil {
+		return nil, fmt.Errorf("empty language pack")
+	}
+	return langData, nil
+}
+
+// isPluralNode reports whether v is a map of plural forms ({one, other, ...})
+// rather than a section of keys.
+func isPluralNode(v interface{}) bool {
+	m, ok := v.(map[string]interface{})
+	if !ok || m["other"] == nil {
+		return false
+	}
+	for k := range m {
+		if !slices.Contains(pluralForms, k) {
+			return false
+		}
+	}
+	return true
+}
+
+// flatten turns nested sections into dotted keys. Plural nodes are kept as a
+// single key represented by their "other" form.
+func flatten(prefix string, v interface{}, out map[string]string) {
+	switch node := v.(type) {
+	case map[string]interface{}:
+		if isPluralNode(node) {
+			out[prefix] = fmt.Sprint(node["other"])
+			return
+		}
+		for k, child := range node {
+			key := k
+			if prefix != "" {
+				key = prefix + "." + k
+			}
+			flatten(key, child, out)
+		}
+	case string:
+		out[prefix] = node
+	}
+}
+
+// placeholderSignature lists the printf verbs in order and the named
+// placeholders sorted, so two strings with the same signature format alike.
+func placeholderSignature(s string) string {
+	var verbs []string
+	for _, v := range printfVerbPattern.FindAllString(s, -1) {
+		if v != "%%" {
+			verbs = append(verbs, v)
+		}
+	}
+
+	var names []string
+	for _, match := range namedPlaceholder.FindAllStringSubmatch(s, -1) {
+		if !slices.Contains(names, match[1]) {
+			names = append(names, match[1])
+		}
+	}
+	slices.Sort(names)
+
+	return strings.Join(verbs, " ") + " | " + strings.Join(names, " ")
+}
+
+func validate(code string, base, pack map[string]interface{}) Report {
+	baseKeys := make(map[string]string)
+	packKeys := make(map[string]string)
+	flatten("", base, baseKeys)
+	flatten("", pack, packKeys)
+
+	report := Report{Lang: code, Total: len(baseKeys)}
+	for key, value := range baseKeys {
+		translated, ok := packKeys[key]
+		switch {
+		case !ok:
+			report.Missing = append(report.Missing, key)
+		case placeholderSignature(value) != placeholderSignature(translated):
+			report.Mismatched = append(report.Mismatched, key)
+		}
+	}
+	for key := range packKeys {
+		if _, ok := baseKeys[key]; !ok {
+			report.Unknown = append(report.Unknown, key)
+		}
+	}
+
+	slices.Sort(report.Missing)
+	slices.Sort(report.Unknown)
+	slices.Sort(report.Mismatched)
+	return report
+}
+
+// Validate compares a loaded language against en.yml.
+func Validate(code string) (Report, error) {
+	t := GetInstance()
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	pack, ok := t.languages[code]
+	if !ok {
+		return Report{}, fmt.Errorf("language %q is not loaded", code)
+	}
+	return validate(code, t.embedded[t.defaultLang], pack), nil
+}
+
+// ValidateAll validates every loaded language except en itself.
+func ValidateAll() []Report {
+	langs := GetAvailableLanguages()
+	slices.Sort(langs)
+
+	var reports []Report
+	for _, code := range langs {
+		if code == GetInstance().defaultLang {
+			continue
+		}
+		if report, err := Validate(code); err == nil {
+			reports = append(reports, report)
+		}
+	}
+	return reports
+}
+
+// mergePack overlays pack onto base, so an external pack can fix a few
+// strings of an embedded language without repeating the rest.
+func mergePack(base, pack map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(base))
+	for k, v := range base {
+		out[k] = v
+	}
+	for k, v := range pack {
+		if baseSection, ok := out[k].(map[string]interface{}); ok && !isPluralNode(baseSection) {
+			if packSection, ok := v.(map[string]interface{}); ok && !isPluralNode(packSection) {
+				out[k] = mergePack(baseSection, packSection)
+				continue
+			}
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// LoadPack validates a YAML pack and makes it available as code. Packs with
+// placeholder mismatches are rejected; the report says which keys.
+func LoadPack(code string, data []byte) (Report, error) {
+	if !ValidLangCode(code) {
+		return Report{}, fmt.Errorf("invalid language code %q", code)
+	}
+
+	pack, err := parsePack(data)
+	if err != nil {
+		return Report{}, err
+	}
+
+	t := GetInstance()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if existing, ok := t.embedded[code]; ok {
+		pack = mergePack(existing, pack)
+	}
+
+	report := validate(code, t.embedded[t.defaultLang], pack)
+	if !report.OK() {
+		return report, fmt.Errorf("%d keys have mismatched placeholders", len(report.Mismatched))
+	}
+
+	t.languages[code] = pack
+	return report, nil
+}
+
+// SavePack writes a pack to PackDir after it loaded successfully, so it
+// survives restarts.
+func SavePack(code string, data []byte) error {
+	dir := PackDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, code+".yml"), data, 0o644)
+}
+
+// LoadDir loads every .yml pack in dir on top of the current languages. A
+// missing directory is not an error.
+func LoadDir(dir string) ([]Report, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var reports []Report
+	var errs []string
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".yml") {
+			continue
+		}
+
+		code := strings.TrimSuffix(entry.Name(), ".yml")
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", entry.Name(), err))
+			continue
+		}
+
+		report, err := LoadPack(code, data)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", entry.Name(), err))
+			continue
+		}
+		reports = append(reports, report)
+	}
+
+	if len(errs) > 0 {
+		return reports, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return reports, nil
+}
+
+// Reload rebuilds all languages from the embedded files and PackDir, dropping
+// packs whose files were removed.
+func Reload() ([]Report, error) {
+	t := GetInstance()
+	t.mu.Lock()
+	t.languages = make(map[string]map[string]interface{}, len(t.embedded))
+	for code, data := range t.embedded {
+		t.languages[code] = data
+	}
+	t.mu.Unlock()
+
+	reports, err := LoadDir(PackDir())
+	log.Printf("Reloaded languages: %d loaded", len(GetAvailableLanguages()))
+	return reports, err
+}
+
+// Args holds values for named {placeholders}.
+type Args map[string]interface{}
+
+// Format replaces {name} placeholders in s. Unknown names are left as is.
+func Format(s string, args Args) string {
+	return namedPlaceholder.ReplaceAllStringFunc(s, func(match string) string {
+		if v, ok := args[match[1:len(match)-1]]; ok {
+			return fmt.Sprint(v)
+		}
+		return match
+	})
+}
+
+// pluralForm picks the CLDR category for n. Only the cardinal rules of the
+// bundled languages are needed: hi treats 0 as "one", everything else follows
+// English.
+func pluralForm(lang string, n int) string {
+	switch {
+	case n == 1, n == 0 && lang == "hi":
+		return "one"
+	case n == 0:
+		return "zero"
+	}
+	return "other"
+}
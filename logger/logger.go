@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -21,8 +22,30 @@ const (
 	LevelFatal
 )
 
+// Category groups log events so they can be routed to different chats.
+type Category string
+
+const (
+	CategorySecurity   Category = "security"
+	CategoryModeration Category = "moderation"
+	CategoryPM         Category = "pm"
+	CategorySystem     Category = "system"
+	CategoryError      Category = "error"
+)
+
+var Categories = []Category{CategorySecurity, CategoryModeration, CategoryPM, CategorySystem, CategoryError}
+
+func ParseCategory(s string) (Category, bool) {
+	for _, c := range Categories {
+		if string(c) == strings.ToLower(s) {
+			return c, true
+		}
+	}
+	return "", false
+}
+
 type TelegramSender interface {
-	SendLog(msg string) error
+	SendLog(category Category, msg string) error
 }
 
 type Logger struct {
@@ -30,6 +53,7 @@ type Logger struct {
 	tgSender     TelegramSender
 	logToChannel bool
 	minLevel     LogLevel
+	pending      sync.WaitGroup
 }
 
 var instance *Logger
@@ -96,7 +120,7 @@ func SetMinLevel(level LogLevel) {
 	l.minLevel = level
 }
 
-func (l *Logger) sendToChannel(level, msg string) {
+func (l *Logger) sendToChannel(category Category, level, msg string) {
 	l.mu.RLock()
 	sender := l.tgSender
 	shouldLog := l.logToChannel
@@ -106,11 +130,30 @@ func (l *Logger) sendToChannel(level, msg string) {
 		return
 	}
 
+	l.pending.Add(1)
 	go func() {
+		defer l.pending.Done()
 		formatted := fmt.Sprintf("<b>[%s]</b> <code>%s</code>\n%s",
 			level, time.Now().Format("15:04:05"), msg)
-		sender.SendLog(formatted)
+		sender.SendLog(category, formatted)
+	}()
+}
+
+// Flush waits for log messages still being sent to Telegram, up to timeout.
+// It reports whether all of them finished.
+func Flush(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		GetInstance().pending.Wait()
+		close(done)
 	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
 }
 
 func Debug(args ...interface{}) {
@@ -132,49 +175,57 @@ func Infof(format string, args ...interface{}) {
 func Warn(args ...interface{}) {
 	msg := fmt.Sprint(args...)
 	log.Warn(msg)
-	GetInstance().sendToChannel("⚠️ WARN", msg)
+	GetInstance().sendToChannel(CategoryError, "⚠️ WARN", msg)
 }
 
 func Warnf(format string, args ...interface{}) {
 	msg := fmt.Sprintf(format, args...)
 	log.Warn(msg)
-	GetInstance().sendToChannel("⚠️ WARN", msg)
+	GetInstance().sendToChannel(CategoryError, "⚠️ WARN", msg)
 }
 
 func Error(args ...interface{}) {
 	msg := fmt.Sprint(args...)
 	log.Error(msg)
-	GetInstance().sendToChannel("❌ ERROR", msg)
+	GetInstance().sendToChannel(CategoryError, "❌ ERROR", msg)
 }
 
 func Errorf(format string, args ...interface{}) {
 	msg := fmt.Sprintf(format, args...)
 	log.Error(msg)
-	GetInstance().sendToChannel("❌ ERROR", msg)
+	GetInstance().sendToChannel(CategoryError, "❌ ERROR", msg)
 }
 
 func Fatal(args ...interface{}) {
 	msg := fmt.Sprint(args...)
-	GetInstance().sendToChannel("💀 FATAL", msg)
+	GetInstance().sendToChannel(CategoryError, "💀 FATAL", msg)
 	log.Fatal(msg)
 }
 
 func Fatalf(format string, args ...interface{}) {
 	msg := fmt.Sprintf(format, args...)
-	GetInstance().sendToChannel("💀 FATAL", msg)
+	GetInstance().sendToChannel(CategoryError, "💀 FATAL", msg)
 	log.Fatal(msg)
 }
 
 func Event(event string, args ...interface{}) {
-	msg := fmt.Sprint(args...)
-	log.WithField("event", event).Info(msg)
-	GetInstance().sendToChannel("📋 "+event, msg)
+	LogEvent(CategorySystem, event, args...)
 }
 
 func Eventf(event, format string, args ...interface{}) {
-	msg := fmt.Sprintf(format, args...)
-	log.WithField("event", event).Info(msg)
-	GetInstance().sendToChannel(fmt.Sprintf("📋 %s", event), msg)
+	LogEventf(CategorySystem, event, format, args...)
+}
+
+// LogEvent records an event under a category, which decides the chat it is
+// posted to.
+func LogEvent(category Category, event string, args ...interface{}) {
+	msg := fmt.Sprint(args...)
+	log.WithFields(log.Fields{"event": event, "category": category}).Info(msg)
+	GetInstance().sendToChannel(category, "📋 "+event, msg)
+}
+
+func LogEventf(category Category, event, format string, args ...interface{}) {
+	LogEvent(category, event, fmt.Sprintf(format, args...))
 }
 
 func Command(user, command string) {
@@ -186,10 +237,10 @@ func Command(user, command string) {
 
 func Startup(msg string) {
 	log.Info(msg)
-	GetInstance().sendToChannel("🚀 STARTUP", msg)
+	GetInstance().sendToChannel(CategorySystem, "🚀 STARTUP", msg)
 }
 
 func Shutdown(msg string) {
 	log.Info(msg)
-	GetInstance().sendToChannel("🛑 SHUTDOWN", msg)
+	GetInstance().sendToChannel(CategorySystem, "🛑 SHUTDOWN", msg)
 }
@@ -1,9 +1,28 @@
 package main
 
 import (
+	"NovaUserbot/config"
 	"NovaUserbot/modules"
+	"flag"
+	"fmt"
+	"os"
 )
 
 func main() {
+	checkConfig := flag.Bool("check-config", false, "validate the configuration and exit")
+	flag.StringVar(&config.File, "config", "", "path to a YAML config file (default config.yml)")
+	flag.Parse()
+
+	if *checkConfig {
+		cfg, err := config.LoadConfig()
+		fmt.Print(cfg.Summary())
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println("Configuration OK")
+		return
+	}
+
 	modules.InitUb()
 }
@@ -1,7 +1,6 @@
 package modules
 
 import (
-	"NovaUserbot/locales"
 	"fmt"
 	"strings"
 
@@ -11,215 +10,226 @@ import (
 func BanUser(m *telegram.NewMessage) error {
 	userId, userName, reason := ExtractUserMsg(m)
 	if userId == 0 {
-		_, err := eOR(m, locales.Tr("admin.usage_ban"))
+		_, err := eOR(m, tr(m, "admin.usage_ban"))
 		return err
 	}
 	if reason == "" {
-		reason = locales.Tr("common.no_reason")
+		reason = tr(m, "common.no_reason")
 	}
-	msg, _ := eOR(m, locales.Tr("admin.banning"))
+	msg, _ := eOR(m, tr(m, "admin.banning"))
 	_, err := m.Client.EditBanned(m.ChatID(), userId, &telegram.BannedOptions{Ban: true})
 	if err != nil {
-		_, err := msg.Edit(locales.Tr("admin.ban_error"))
+		_, err := msg.Edit(tr(m, "admin.ban_error"))
 		return err
 	}
-	_, err = msg.Edit(fmt.Sprintf(locales.Tr("admin.banned"), userId, userName, reason))
+	auditAction(m, "ban", userId, userName, reason)
+	_, err = msg.Edit(fmt.Sprintf(tr(m, "admin.banned"), userId, userName, reason))
 	return err
 }
 
 func UnbanUser(m *telegram.NewMessage) error {
 	userId, userName := ExtractUser(m)
 	if userId == 0 {
-		_, err := eOR(m, locales.Tr("admin.usage_unban"))
+		_, err := eOR(m, tr(m, "admin.usage_unban"))
 		return err
 	}
 
-	msg, _ := eOR(m, locales.Tr("admin.unbanning"))
+	msg, _ := eOR(m, tr(m, "admin.unbanning"))
 	_, err := m.Client.EditBanned(m.ChatID(), userId, &telegram.BannedOptions{Unban: true})
 	if err != nil {
-		_, err := msg.Edit(locales.Tr("admin.unban_error"))
+		_, err := msg.Edit(tr(m, "admin.unban_error"))
 		return err
 	}
-	_, err = msg.Edit(fmt.Sprintf(locales.Tr("admin.unbanned"), userId, userName))
+	auditAction(m, "unban", userId, userName, "")
+	_, err = msg.Edit(fmt.Sprintf(tr(m, "admin.unbanned"), userId, userName))
 	return err
 }
 
 func KickUser(m *telegram.NewMessage) error {
 	userId, userName, reason := ExtractUserMsg(m)
 	if userId == 0 {
-		_, err := eOR(m, locales.Tr("admin.usage_kick"))
+		_, err := eOR(m, tr(m, "admin.usage_kick"))
 		return err
 	}
 	if reason == "" {
-		reason = locales.Tr("common.no_reason")
+		reason = tr(m, "common.no_reason")
 	}
-	msg, _ := eOR(m, locales.Tr("admin.kicking"))
+	msg, _ := eOR(m, tr(m, "admin.kicking"))
 	_, err := m.Client.KickParticipant(m.ChatID(), userId)
 	if err != nil {
-		_, err := msg.Edit(locales.Tr("admin.kick_error"))
+		_, err := msg.Edit(tr(m, "admin.kick_error"))
 		return err
 	}
-	_, err = msg.Edit(fmt.Sprintf(locales.Tr("admin.kicked"), userId, userName, reason))
+	auditAction(m, "kick", userId, userName, reason)
+	_, err = msg.Edit(fmt.Sprintf(tr(m, "admin.kicked"), userId, userName, reason))
 	return err
 }
 
 func MuteUser(m *telegram.NewMessage) error {
-	userId, userName := ExtractUser(m)
+	userId, userName, reason := ExtractUserMsg(m)
 	if userId == 0 {
-		_, err := eOR(m, locales.Tr("admin.usage_mute"))
+		_, err := eOR(m, tr(m, "admin.usage_mute"))
 		return err
 	}
-	msg, _ := eOR(m, locales.Tr("admin.muting"))
+	msg, _ := eOR(m, tr(m, "admin.muting"))
 	_, err := m.Client.EditBanned(m.ChatID(), userId, &telegram.BannedOptions{Mute: true})
 	if err != nil {
-		_, err := msg.Edit(locales.Tr("admin.mute_error"))
+		_, err := msg.Edit(tr(m, "admin.mute_error"))
 		return err
 	}
-	_, err = msg.Edit(fmt.Sprintf(locales.Tr("admin.muted"), userId, userName))
+	auditAction(m, "mute", userId, userName, reason)
+	_, err = msg.Edit(fmt.Sprintf(tr(m, "admin.muted"), userId, userName))
 	return err
 }
 
 func UnmuteUser(m *telegram.NewMessage) error {
 	userId, userName := ExtractUser(m)
 	if userId == 0 {
-		_, err := eOR(m, locales.Tr("admin.usage_unmute"))
+		_, err := eOR(m, tr(m, "admin.usage_unmute"))
 		return err
 	}
-	msg, _ := eOR(m, locales.Tr("admin.unmuting"))
+	msg, _ := eOR(m, tr(m, "admin.unmuting"))
 	_, err := m.Client.EditBanned(m.ChatID(), userId, &telegram.BannedOptions{Unmute: true})
 	if err != nil {
-		_, err := msg.Edit(locales.Tr("admin.unmute_error"))
+		_, err := msg.Edit(tr(m, "admin.unmute_error"))
 		return err
 	}
-	_, err = msg.Edit(fmt.Sprintf(locales.Tr("admin.unmuted"), userId, userName))
+	auditAction(m, "unmute", userId, userName, "")
+	_, err = msg.Edit(fmt.Sprintf(tr(m, "admin.unmuted"), userId, userName))
 	return err
 }
 
 func DmuteUser(m *telegram.NewMessage) error {
-	userId, userName, _ := ExtractUserMsg(m)
+	userId, userName, reason := ExtractUserMsg(m)
 	if userId == 0 {
-		_, err := eOR(m, locales.Tr("admin.usage_dmute"))
+		_, err := eOR(m, tr(m, "admin.usage_dmute"))
 		return err
 	}
-	msg, _ := eOR(m, locales.Tr("admin.dmuting"))
+	msg, _ := eOR(m, tr(m, "admin.dmuting"))
 	_, err := m.Client.EditBanned(m.ChatID(), userId, &telegram.BannedOptions{Mute: true})
 	if err != nil {
-		_, err := msg.Edit(locales.Tr("admin.dmute_error"))
+		_, err := msg.Edit(tr(m, "admin.dmute_error"))
 		return err
 	}
 	if reply, _ := m.GetReplyMessage(); reply != nil {
 		reply.Delete()
 	}
-	_, err = msg.Edit(fmt.Sprintf(locales.Tr("admin.muted"), userId, userName))
+	auditAction(m, "mute", userId, userName, reason)
+	_, err = msg.Edit(fmt.Sprintf(tr(m, "admin.muted"), userId, userName))
 	return err
 }
 
 func DkickUser(m *telegram.NewMessage) error {
 	userId, userName, reason := ExtractUserMsg(m)
 	if userId == 0 {
-		_, err := eOR(m, locales.Tr("admin.usage_kick"))
+		_, err := eOR(m, tr(m, "admin.usage_kick"))
 		return err
 	}
 	if reason == "" {
-		reason = locales.Tr("common.no_reason")
+		reason = tr(m, "common.no_reason")
 	}
-	msg, _ := eOR(m, locales.Tr("admin.kicking"))
+	msg, _ := eOR(m, tr(m, "admin.kicking"))
 	_, err := m.Client.KickParticipant(m.ChatID(), userId)
 	if err != nil {
-		_, err := msg.Edit(locales.Tr("admin.kick_error"))
+		_, err := msg.Edit(tr(m, "admin.kick_error"))
 		return err
 	}
 	if reply, _ := m.GetReplyMessage(); reply != nil {
 		reply.Delete()
 	}
-	_, err = msg.Edit(fmt.Sprintf(locales.Tr("admin.kicked"), userId, userName, reason))
+	auditAction(m, "kick", userId, userName, reason)
+	_, err = msg.Edit(fmt.Sprintf(tr(m, "admin.kicked"), userId, userName, reason))
 	return err
 }
 
 func DbanUser(m *telegram.NewMessage) error {
 	userId, userName, reason := ExtractUserMsg(m)
 	if userId == 0 {
-		_, err := eOR(m, locales.Tr("admin.usage_ban"))
+		_, err := eOR(m, tr(m, "admin.usage_ban"))
 		return err
 	}
 	if reason == "" {
-		reason = locales.Tr("common.no_reason")
+		reason = tr(m, "common.no_reason")
 	}
-	msg, _ := eOR(m, locales.Tr("admin.banning"))
+	msg, _ := eOR(m, tr(m, "admin.banning"))
 	_, err := m.Client.EditBanned(m.ChatID(), userId, &telegram.BannedOptions{Ban: true})
 	if err != nil {
-		_, err := msg.Edit(locales.Tr("admin.ban_error"))
+		_, err := msg.Edit(tr(m, "admin.ban_error"))
 		return err
 	}
 	if reply, _ := m.GetReplyMessage(); reply != nil {
 		reply.Delete()
 	}
-	_, err = msg.Edit(fmt.Sprintf(locales.Tr("admin.banned"), userId, userName, reason))
+	auditAction(m, "ban", userId, userName, reason)
+	_, err = msg.Edit(fmt.Sprintf(tr(m, "admin.banned"), userId, userName, reason))
 	return err
 }
 
 func PromoteUser(m *telegram.NewMessage) error {
 	userId, userName, title := ExtractUserMsg(m)
 	if userId == 0 {
-		_, err := eOR(m, locales.Tr("admin.usage_promote"))
+		_, err := eOR(m, tr(m, "admin.usage_promote"))
 		return err
 	}
 	if title == "" {
 		title = "Λ∂мιи"
 	}
-	msg, _ := eOR(m, locales.Tr("admin.promoting"))
+	msg, _ := eOR(m, tr(m, "admin.promoting"))
 	_, err := m.Client.EditAdmin(m.ChatID(), userId, &telegram.AdminOptions{Rights: &telegram.ChatAdminRights{ChangeInfo: true, DeleteMessages: true, InviteUsers: true, BanUsers: true, PinMessages: true}, Rank: title, IsAdmin: true})
 	if err != nil {
-		_, err := msg.Edit(locales.Tr("admin.promote_error"))
+		_, err := msg.Edit(tr(m, "admin.promote_error"))
 		return err
 	}
-	_, err = msg.Edit(fmt.Sprintf(locales.Tr("admin.promoted"), userId, userName))
+	auditAction(m, "promote", userId, userName, title)
+	_, err = msg.Edit(fmt.Sprintf(tr(m, "admin.promoted"), userId, userName))
 	return err
 }
 
 func FullPromoteUser(m *telegram.NewMessage) error {
 	userId, userName, title := ExtractUserMsg(m)
 	if userId == 0 {
-		_, err := eOR(m, locales.Tr("admin.usage_promote"))
+		_, err := eOR(m, tr(m, "admin.usage_promote"))
 		return err
 	}
 	if title == "" {
 		title = "𝙎υρєя Λ∂мιи"
 	}
-	msg, _ := eOR(m, locales.Tr("admin.promoting"))
+	msg, _ := eOR(m, tr(m, "admin.promoting"))
 	_, err := m.Client.EditAdmin(m.ChatID(), userId, &telegram.AdminOptions{Rights: &telegram.ChatAdminRights{ChangeInfo: true, DeleteMessages: true, InviteUsers: true, BanUsers: true, PinMessages: true, AddAdmins: true, ManageCall: true, ManageTopics: true, PostStories: true, EditStories: true, DeleteStories: true}, Rank: title, IsAdmin: true})
 	if err != nil {
-		_, err := msg.Edit(locales.Tr("admin.promote_error"))
+		_, err := msg.Edit(tr(m, "admin.promote_error"))
 		return err
 	}
-	_, err = msg.Edit(fmt.Sprintf(locales.Tr("admin.promoted"), userId, userName))
+	auditAction(m, "promote", userId, userName, title)
+	_, err = msg.Edit(fmt.Sprintf(tr(m, "admin.promoted"), userId, userName))
 	return err
 }
 
 func DemoteUser(m *telegram.NewMessage) error {
 	userId, userName := ExtractUser(m)
 	if userId == 0 {
-		_, err := eOR(m, locales.Tr("admin.usage_demote"))
+		_, err := eOR(m, tr(m, "admin.usage_demote"))
 		return err
 	}
-	msg, _ := eOR(m, locales.Tr("admin.demoting"))
+	msg, _ := eOR(m, tr(m, "admin.demoting"))
 	_, err := m.Client.EditAdmin(m.ChatID(), userId, &telegram.AdminOptions{IsAdmin: false, Rights: &telegram.ChatAdminRights{}})
 	if err != nil {
-		_, err := msg.Edit(locales.Tr("admin.demote_error"))
+		_, err := msg.Edit(tr(m, "admin.demote_error"))
 		return err
 	}
-	_, err = msg.Edit(fmt.Sprintf(locales.Tr("admin.demoted"), userId, userName))
+	auditAction(m, "demote", userId, userName, "")
+	_, err = msg.Edit(fmt.Sprintf(tr(m, "admin.demoted"), userId, userName))
 	return err
 }
 
 func PinMessage(m *telegram.NewMessage) error {
 	if !m.IsGroup() {
-		_, err := eOR(m, locales.Tr("admin.groups_only"))
+		_, err := eOR(m, tr(m, "admin.groups_only"))
 		return err
 	}
 	reply, err := m.GetReplyMessage()
 	if err != nil || reply == nil {
-		_, err := eOR(m, locales.Tr("admin.usage_pin"))
+		_, err := eOR(m, tr(m, "admin.usage_pin"))
 		return err
 	}
 	var silent bool
@@ -228,52 +238,52 @@ func PinMessage(m *telegram.NewMessage) error {
 	}
 	err = reply.Pin(&telegram.PinOptions{Silent: silent})
 	if err != nil {
-		_, err := eOR(m, locales.Tr("admin.pin_error"))
+		_, err := eOR(m, tr(m, "admin.pin_error"))
 		return err
 	}
-	_, err = eOR(m, fmt.Sprintf(locales.Tr("admin.pinned"), msgLink(reply)))
+	_, err = eOR(m, fmt.Sprintf(tr(m, "admin.pinned"), msgLink(reply)))
 	return err
 }
 
 func UnpinMessage(m *telegram.NewMessage) error {
 	if !m.IsGroup() {
-		_, err := eOR(m, locales.Tr("admin.groups_only"))
+		_, err := eOR(m, tr(m, "admin.groups_only"))
 		return err
 	}
 	reply, err := m.GetReplyMessage()
 	if err != nil || reply == nil {
-		_, err := eOR(m, locales.Tr("admin.usage_unpin"))
+		_, err := eOR(m, tr(m, "admin.usage_unpin"))
 		return err
 	}
 	err = reply.Pin(&telegram.PinOptions{Unpin: true})
 	if err != nil {
-		_, err := eOR(m, locales.Tr("admin.unpin_error"))
+		_, err := eOR(m, tr(m, "admin.unpin_error"))
 		return err
 	}
-	_, err = eOR(m, fmt.Sprintf(locales.Tr("admin.unpinned"), msgLink(reply)))
+	_, err = eOR(m, fmt.Sprintf(tr(m, "admin.unpinned"), msgLink(reply)))
 	return err
 }
 
 func zombiesCmd(m *telegram.NewMessage) error {
 	args := m.Args()
 	if !m.IsGroup() {
-		_, err := m.Edit(locales.Tr("admin.groups_only"))
+		_, err := m.Edit(tr(m, "admin.groups_only"))
 		return err
 	}
 	perms, err := m.Client.GetChatMember(m.ChatID(), m.Sender.ID)
 	if err != nil {
-		_, err = m.Edit(locales.Tr("admin.get_permissions_error"))
+		_, err = m.Edit(tr(m, "admin.get_permissions_error"))
 		return err
 	}
 	if !perms.Rights.BanUsers {
-		_, err = m.Edit(locales.Tr("admin.no_permissions"))
+		_, err = m.Edit(tr(m, "admin.no_permissions"))
 		return err
 	}
 	deleted := []int64{}
-	msg, _ := m.Edit(locales.Tr("admin.zombies_searching"))
+	msg, _ := m.Edit(tr(m, "admin.zombies_searching"))
 	members, _, err := m.Client.GetChatMembers(m.ChatID(), &telegram.ParticipantOptions{Limit: 500000})
 	if err != nil {
-		_, err = msg.Edit(locales.Tr("admin.get_permissions_error"))
+		_, err = msg.Edit(tr(m, "admin.get_permissions_error"))
 		return err
 	}
 	for _, member := range members {
@@ -285,7 +295,7 @@ func zombiesCmd(m *telegram.NewMessage) error {
 		}
 	}
 	if len(deleted) == 0 {
-		_, err = msg.Edit(locales.Tr("admin.zombies_not_found"))
+		_, err = msg.Edit(tr(m, "admin.zombies_not_found"))
 		return err
 	}
 	if strings.Contains(args, "clean") && len(deleted) > 0 {
@@ -299,10 +309,10 @@ func zombiesCmd(m *telegram.NewMessage) error {
 				success++
 			}
 		}
-		_, err = msg.Edit(fmt.Sprintf(locales.Tr("admin.zombies_cleaned"), success, failed))
+		_, err = msg.Edit(fmt.Sprintf(tr(m, "admin.zombies_cleaned"), success, failed))
 		return err
 	}
-	_, err = msg.Edit(fmt.Sprintf(locales.Tr("admin.zombies_found"), len(deleted)))
+	_, err = msg.Edit(fmt.Sprintf(tr(m, "admin.zombies_found"), len(deleted)))
 	return err
 }
 
@@ -322,6 +332,12 @@ func LoadAdminModule(c *telegram.Client) {
 		{Command: "pin", Func: PinMessage, Description: "Pin a message in the chat", ModuleName: "Admin"},
 		{Command: "unpin", Func: UnpinMessage, Description: "Unpin a message", ModuleName: "Admin"},
 		{Command: "zombies", Func: zombiesCmd, Description: "Find and clean deleted accounts", ModuleName: "Admin"},
+		{Command: "purge", Func: PurgeCmd, Description: "Delete all messages from the replied one up to here", ModuleName: "Admin", DisAllowSudos: true},
+		{Command: "purgeme", Func: PurgeMeCmd, Description: "Delete your last N messages in this chat", ModuleName: "Admin", DisAllowSudos: true},
+		{Command: "del", Func: DelCmd, Description: "Delete the replied message", ModuleName: "Admin", DisAllowSudos: true},
+		{Command: "sd", Func: SelfDestructCmd, Description: "Send a message that deletes itself (<seconds|5m> <text>)", ModuleName: "Admin", DisAllowSudos: true},
 	}
 	AddHandlers(handlers, c)
+
+	resumeSelfDestructs()
 }
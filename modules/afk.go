@@ -3,8 +3,11 @@ package modules
 import (
 	"NovaUserbot/db"
 	"NovaUserbot/locales"
+	"NovaUserbot/logger"
+	"NovaUserbot/utils"
 	"encoding/json"
 	"fmt"
+	"html"
 	"strings"
 	"sync"
 	"time"
@@ -12,6 +15,22 @@ import (
 	"github.com/amarnathcjd/gogram/telegram"
 )
 
+const (
+	afkScopeAll   = "all"
+	afkScopePM    = "pm"
+	afkScopeChats = "chats"
+)
+
+type AFKMention struct {
+	ChatID     int64     `json:"chat_id"`
+	ChatTitle  string    `json:"chat_title"`
+	SenderID   int64     `json:"sender_id"`
+	SenderName string    `json:"sender_name"`
+	Text       string    `json:"text"`
+	Link       string    `json:"link,omitempty"`
+	Time       time.Time `json:"time"`
+}
+
 type AFKData struct {
 	IsAFK       bool                `json:"is_afk"`
 	Reason      string              `json:"reason"`
@@ -19,12 +38,25 @@ type AFKData struct {
 	MsgCount    int                 `json:"msg_count"`
 	LastNotify  map[int64]time.Time `json:"last_notify"`
 	MediaFileID string              `json:"media_file_id,omitempty"`
+	Scope       string              `json:"scope,omitempty"`
+	Chats       []int64             `json:"chats,omitempty"`
+	Until       time.Time           `json:"until,omitempty"`
+	Mentions    []AFKMention        `json:"mentions,omitempty"`
+	Auto        bool                `json:"auto,omitempty"`
 }
 
-const afkSpamBlock = 2 * time.Minute
+const (
+	afkSpamBlock      = 2 * time.Minute
+	afkMaxMentions    = 100
+	afkSnippetLength  = 120
+	afkDigestEntries  = 20
+	afkDigestButtons  = 8
+	afkMaxScheduleFor = 30 * 24 * time.Hour
+)
 
 var (
 	afkMutex sync.RWMutex
+	afkTimer *time.Timer
 )
 
 func getAFK() AFKData {
@@ -33,7 +65,9 @@ func getAFK() AFKData {
 		return AFKData{LastNotify: make(map[int64]time.Time)}
 	}
 	var d AFKData
-	_ = json.Unmarshal([]byte(raw), &d)
+	if err := json.Unmarshal([]byte(raw), &d); err != nil {
+		logger.Errorf("Corrupted AFK data: %v", err)
+	}
 	if d.LastNotify == nil {
 		d.LastNotify = make(map[int64]time.Time)
 	}
@@ -61,40 +95,218 @@ func fmtDur(d time.Duration) string {
 	return fmt.Sprintf("%ds", int(d.Seconds()))
 }
 
+// parseAFKArgs reads leading flags (-pm, -here, -chats <ids>, -for <duration>)
+// off the command arguments and returns the remaining text as the reason.
+func parseAFKArgs(m *telegram.NewMessage) (AFKData, error) {
+	d := AFKData{Scope: afkScopeAll}
+	fields := strings.Fields(m.Args())
+
+	i := 0
+	for ; i < len(fields); i++ {
+		switch strings.ToLower(fields[i]) {
+		case "-pm":
+			d.Scope = afkScopePM
+		case "-here":
+			d.Scope = afkScopeChats
+			d.Chats = append(d.Chats, m.ChatID())
+		case "-chats":
+			if i+1 >= len(fields) {
+				return d, fmt.Errorf("missing chat list")
+			}
+			i++
+			for _, id := range strings.Split(fields[i], ",") {
+				chatID := utils.StringToInt64(strings.TrimSpace(id))
+				if chatID == 0 {
+					return d, fmt.Errorf("invalid chat id: %s", id)
+				}
+				d.Chats = append(d.Chats, normalizeChatID(chatID))
+			}
+			d.Scope = afkScopeChats
+		case "-for":
+			if i+1 >= len(fields) {
+				return d, fmt.Errorf("missing duration")
+			}
+			i++
+			dur, err := parseDurationString(fields[i])
+			if err != nil || dur > afkMaxScheduleFor {
+				return d, fmt.Errorf("invalid duration: %s", fields[i])
+			}
+			d.Until = time.Now().Add(dur)
+		default:
+			d.Reason = strings.Join(fields[i:], " ")
+			return d, nil
+		}
+	}
+	return d, nil
+}
+
+// normalizeChatID strips the -100 / - prefixes from bot-API style chat IDs so
+// they can be compared against NewMessage.ChatID().
+func normalizeChatID(id int64) int64 {
+	if id < -1000000000000 {
+		return -id - 1000000000000
+	}
+	if id < 0 {
+		return -id
+	}
+	return id
+}
+
 func afkCommand(m *telegram.NewMessage) error {
-	reason := strings.TrimSpace(m.Args())
-	if reason == "" {
-		reason = "No reason specified"
+	d, err := parseAFKArgs(m)
+	if err != nil {
+		_, err := eOR(m, fmt.Sprintf(tr(m, "afk.invalid_args"), err.Error()))
+		return err
+	}
+	if d.Reason == "" {
+		d.Reason = "No reason specified"
 	}
 
-	var fileID string
 	if m.IsReply() {
 		if r, err := m.GetReplyMessage(); err == nil && r.File != nil {
-			fileID = r.File.FileID
+			d.MediaFileID = r.File.FileID
 		}
 	}
 
+	d.IsAFK = true
+	d.StartTime = time.Now()
+	d.LastNotify = map[int64]time.Time{}
+
 	afkMutex.Lock()
-	setAFK(AFKData{
-		IsAFK:       true,
-		Reason:      reason,
-		StartTime:   time.Now(),
-		MsgCount:    0,
-		LastNotify:  map[int64]time.Time{},
-		MediaFileID: fileID,
-	})
+	setAFK(d)
 	afkMutex.Unlock()
 
-	_, err := eOR(m, fmt.Sprintf(locales.Tr("afk.now_afk"), reason))
+	scheduleAFKEnd(d.Until)
+
+	text := fmt.Sprintf(tr(m, "afk.now_afk"), d.Reason)
+	if d.Scope != afkScopeAll {
+		text += fmt.Sprintf(tr(m, "afk.scope_line"), d.Scope)
+	}
+	if !d.Until.IsZero() {
+		text += fmt.Sprintf(tr(m, "afk.until_line"), fmtDur(time.Until(d.Until)))
+	}
+
+	_, err = eOR(m, text)
 	return err
 }
 
+// afkApplies reports whether an incoming message falls inside the AFK scope.
+func afkApplies(d AFKData, m *telegram.NewMessage) bool {
+	switch d.Scope {
+	case afkScopePM:
+		return m.IsPrivate()
+	case afkScopeChats:
+		return utils.IsIn64Array(d.Chats, m.ChatID())
+	}
+	return true
+}
+
+func newAFKMention(m *telegram.NewMessage) AFKMention {
+	text := m.Text()
+	if text == "" {
+		text = locales.Tr("afk.no_text")
+	}
+	if runes := []rune(text); len(runes) > afkSnippetLength {
+		text = string(runes[:afkSnippetLength]) + "…"
+	}
+
+	return AFKMention{
+		ChatID:     m.ChatID(),
+		ChatTitle:  chatTitle(m),
+		SenderID:   m.Sender.ID,
+		SenderName: strings.TrimSpace(m.Sender.FirstName + " " + m.Sender.LastName),
+		Text:       text,
+		Link:       msgLink(m),
+		Time:       time.Now(),
+	}
+}
+
+// endAFK clears the AFK state and posts the digest of missed mentions to the
+// log chat. It returns the state that was active, or ok=false if AFK had
+// already been cleared by someone else.
+func endAFK() (AFKData, bool) {
+	afkMutex.Lock()
+	old := getAFK()
+	if !old.IsAFK {
+		afkMutex.Unlock()
+		return old, false
+	}
+	setAFK(AFKData{IsAFK: false, LastNotify: map[int64]time.Time{}})
+	afkMutex.Unlock()
+
+	scheduleAFKEnd(time.Time{})
+
+	if err := sendAFKDigest(old); err != nil {
+		logger.Errorf("AFK digest error: %v", err)
+	}
+	return old, true
+}
+
+func sendAFKDigest(d AFKData) error {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(locales.Tr("afk.digest_header"), fmtDur(time.Since(d.StartTime)), d.MsgCount))
+
+	if len(d.Mentions) == 0 {
+		sb.WriteString(locales.Tr("afk.digest_empty"))
+		return logTo(logger.CategoryPM, sb.String())
+	}
+
+	btn := telegram.ButtonBuilder{}
+	var buttons []telegram.KeyboardButton
+
+	for i, mention := range d.Mentions {
+		if i >= afkDigestEntries {
+			sb.WriteString(fmt.Sprintf(locales.Tr("afk.digest_more"), len(d.Mentions)-afkDigestEntries))
+			break
+		}
+		sb.WriteString(fmt.Sprintf(locales.Tr("afk.digest_entry"),
+			i+1, mention.SenderID, html.EscapeString(mention.SenderName), html.EscapeString(mention.ChatTitle),
+			mention.Time.Format("02 Jan 15:04"), html.EscapeString(mention.Text)))
+
+		if mention.Link != "" && len(buttons) < afkDigestButtons {
+			buttons = append(buttons, btn.URL(fmt.Sprintf(locales.Tr("afk.digest_btn"), i+1), mention.Link))
+		}
+	}
+
+	if len(buttons) == 0 {
+		return logTo(logger.CategoryPM, sb.String())
+	}
+	return logTo(logger.CategoryPM, sb.String(), &telegram.SendOptions{
+		ParseMode:   "HTML",
+		ReplyMarkup: telegram.NewKeyboard().NewColumn(4, buttons...).Build(),
+	})
+}
+
+// scheduleAFKEnd (re)arms the timer that ends AFK at the scheduled time. A
+// zero time just cancels any pending timer.
+func scheduleAFKEnd(until time.Time) {
+	afkMutex.Lock()
+	defer afkMutex.Unlock()
+
+	if afkTimer != nil {
+		afkTimer.Stop()
+		afkTimer = nil
+	}
+	if until.IsZero() {
+		return
+	}
+
+	afkTimer = time.AfterFunc(max(time.Until(until), 0), func() {
+		if old, ok := endAFK(); ok {
+			logger.Infof("AFK ended as scheduled after %s", fmtDur(time.Since(old.StartTime)))
+		}
+	})
+}
+
 func afkHandler(m *telegram.NewMessage) error {
-	
-	if m == nil {
+	if m == nil || m.Sender == nil {
 		return nil
 	}
 
+	if m.Message.Out {
+		markActivity()
+	}
+
 	afkMutex.RLock()
 	d := getAFK()
 	afkMutex.RUnlock()
@@ -103,25 +315,27 @@ func afkHandler(m *telegram.NewMessage) error {
 		return nil
 	}
 
-	
 	if m.Message.Out {
-		afkMutex.Lock()
-		old := d
-		setAFK(AFKData{IsAFK: false, LastNotify: map[int64]time.Time{}})
-		afkMutex.Unlock()
+		old, ok := endAFK()
+		if !ok {
+			return nil
+		}
 
-		msg := fmt.Sprintf(locales.Tr("afk.auto_back"), fmtDur(time.Since(old.StartTime)), old.MsgCount)
+		msg := fmt.Sprintf(tr(m, "afk.auto_back"), fmtDur(time.Since(old.StartTime)), old.MsgCount)
 		m.Reply(msg, &telegram.SendOptions{ParseMode: "HTML"})
 		return nil
 	}
 
-	
-	if !m.Message.Mentioned || m.Sender.Bot {
+	if (!m.Message.Mentioned && !m.IsPrivate()) || m.Sender.Bot || !afkApplies(d, m) {
 		return nil
 	}
+
 	afkMutex.Lock()
 	d = getAFK()
 	d.MsgCount++
+	if len(d.Mentions) < afkMaxMentions {
+		d.Mentions = append(d.Mentions, newAFKMention(m))
+	}
 
 	last, ok := d.LastNotify[m.Sender.ID]
 	send := !ok || time.Since(last) > afkSpamBlock
@@ -135,7 +349,10 @@ func afkHandler(m *telegram.NewMessage) error {
 		return nil
 	}
 
-	msg := fmt.Sprintf(locales.Tr("afk.reply"), fmtDur(time.Since(d.StartTime)), d.Reason)
+	msg := fmt.Sprintf(tr(m, "afk.reply"), fmtDur(time.Since(d.StartTime)), d.Reason)
+	if !d.Until.IsZero() {
+		msg += fmt.Sprintf(tr(m, "afk.back_in"), fmtDur(time.Until(d.Until)))
+	}
 
 	opts := &telegram.SendOptions{ParseMode: "HTML"}
 	if d.MediaFileID != "" {
@@ -149,9 +366,14 @@ func afkHandler(m *telegram.NewMessage) error {
 
 func LoadAFKModule(c *telegram.Client) {
 	handlers := []*Handler{
-		{Command: "afk", Func: afkCommand, Description: "Set AFK", ModuleName: "AFK", DisAllowSudos: true},
+		{Command: "afk", Func: afkCommand, Description: "Set AFK (-pm, -here, -chats <ids>, -for <duration>)", ModuleName: "AFK", DisAllowSudos: true},
 	}
 	AddHandlers(handlers, c)
+	loadAutoAFK(c)
+
+	if d := getAFK(); d.IsAFK && !d.Until.IsZero() {
+		scheduleAFKEnd(d.Until)
+	}
 
 	c.On("message", afkHandler)
 }
@@ -0,0 +1,751 @@
+package modules
+
+import (
+	"NovaUserbot/db"
+	"NovaUserbot/locales"
+	"NovaUserbot/logger"
+	"NovaUserbot/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+const (
+	antiFloodPrefix = "ANTIFLOOD:"
+	antiRaidPrefix  = "ANTIRAID:"
+	captchaPrefix   = "CAPTCHA:"
+	antiRaidTick    = 30 * time.Second
+	maxFloodWindow  = 5 * time.Minute
+	maxRaidWindow   = time.Hour
+)
+
+// AntiFloodConfig punishes members who send Limit messages within Duration
+// seconds. ActionDuration applies to the tban and tmute actions.
+type AntiFloodConfig struct {
+	Limit          int           `json:"limit"`
+	Duration       int64         `json:"duration"`
+	Action         string        `json:"action"`
+	ActionDuration time.Duration `json:"action_duration,omitempty"`
+	Enabled        bool          `json:"enabled"`
+}
+
+// AntiRaidConfig turns on raid mode once Joins members join within Window
+// seconds. Raid mode restricts every new member and is lifted Cooldown
+// seconds after the last spike, at ActiveUntil.
+type AntiRaidConfig struct {
+	Joins       int       `json:"joins"`
+	Window      int64     `json:"window"`
+	Cooldown    int64     `json:"cooldown"`
+	Enabled     bool      `json:"enabled"`
+	ActiveUntil time.Time `json:"active_until,omitempty"`
+}
+
+// Captcha is a member muted during a raid until they press the button of
+// the captcha message, or removed once Deadline passes.
+type Captcha struct {
+	MsgID    int32     `json:"msg_id,omitempty"`
+	Deadline time.Time `json:"deadline"`
+}
+
+func defaultAntiFloodConfig() *AntiFloodConfig {
+	return &AntiFloodConfig{Limit: 6, Duration: 5, Action: "tmute", ActionDuration: time.Hour}
+}
+
+func defaultAntiRaidConfig() *AntiRaidConfig {
+	return &AntiRaidConfig{Joins: 10, Window: 60, Cooldown: 900}
+}
+
+func (c *AntiRaidConfig) active() bool {
+	return time.Now().Before(c.ActiveUntil)
+}
+
+type recentEvent struct {
+	id int64
+	at time.Time
+}
+
+// eventTracker counts recent messages per chat and sender, and joins per chat.
+type eventTracker struct {
+	sync.Mutex
+	events map[string][]recentEvent
+}
+
+var (
+	floodTracker = eventTracker{events: make(map[string][]recentEvent)}
+	joinTracker  = eventTracker{events: make(map[string][]recentEvent)}
+
+	// Flood configs are cached, as they are checked on every group message.
+	floodConfigs     = make(map[int64]*AntiFloodConfig)
+	floodConfigsLock sync.RWMutex
+
+	antiRaidMutex sync.Mutex
+)
+
+// add records an event and returns the events within window once there are
+// limit of them, forgetting them so they are only reported once.
+func (t *eventTracker) add(key string, id int64, limit int, window time.Duration) []recentEvent {
+	t.Lock()
+	defer t.Unlock()
+
+	now := time.Now()
+	events := t.events[key][:0]
+	for _, e := range t.events[key] {
+		if now.Sub(e.at) <= window {
+			events = append(events, e)
+		}
+	}
+	events = append(events, recentEvent{id: id, at: now})
+
+	if len(events) < limit {
+		t.events[key] = events
+		return nil
+	}
+	delete(t.events, key)
+	return events
+}
+
+// prune forgets keys without an event within window, so senders who never
+// reach the limit do not stay in memory.
+func (t *eventTracker) prune(window time.Duration) {
+	t.Lock()
+	defer t.Unlock()
+
+	for key, events := range t.events {
+		if len(events) == 0 || time.Since(events[len(events)-1].at) > window {
+			delete(t.events, key)
+		}
+	}
+}
+
+func getAntiFloodConfig(chatID int64) *AntiFloodConfig {
+	floodConfigsLock.RLock()
+	config, cached := floodConfigs[chatID]
+	floodConfigsLock.RUnlock()
+	if cached {
+		return config
+	}
+
+	if data := db.Get(antiFloodPrefix + strconv.FormatInt(chatID, 10)); data != "" {
+		config = &AntiFloodConfig{}
+		if err := json.Unmarshal([]byte(data), config); err != nil {
+			logger.Errorf("Corrupted anti-flood config for %d: %v", chatID, err)
+			config = nil
+		}
+	}
+
+	floodConfigsLock.Lock()
+	floodConfigs[chatID] = config
+	floodConfigsLock.Unlock()
+	return config
+}
+
+func setAntiFloodConfig(chatID int64, config *AntiFloodConfig) error {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	if err := db.Set(antiFloodPrefix+strconv.FormatInt(chatID, 10), string(data)); err != nil {
+		return err
+	}
+
+	floodConfigsLock.Lock()
+	floodConfigs[chatID] = config
+	floodConfigsLock.Unlock()
+	return nil
+}
+
+// resetFloodConfigs drops the cached anti-flood configs so they are read
+// again from the database, e.g. after a restore.
+func resetFloodConfigs() {
+	floodConfigsLock.Lock()
+	clear(floodConfigs)
+	floodConfigsLock.Unlock()
+}
+
+func getAntiRaidConfig(chatID int64) *AntiRaidConfig {
+	data := db.Get(antiRaidPrefix + strconv.FormatInt(chatID, 10))
+	if data == "" {
+		return nil
+	}
+
+	var config AntiRaidConfig
+	if err := json.Unmarshal([]byte(data), &config); err != nil {
+		logger.Errorf("Corrupted anti-raid config for %d: %v", chatID, err)
+		return nil
+	}
+	return &config
+}
+
+func setAntiRaidConfig(chatID int64, config *AntiRaidConfig) error {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	return db.Set(antiRaidPrefix+strconv.FormatInt(chatID, 10), string(data))
+}
+
+func getCaptchas(chatID int64) map[int64]Captcha {
+	captchas := make(map[int64]Captcha)
+	if data := db.Get(captchaPrefix + strconv.FormatInt(chatID, 10)); data != "" {
+		if err := json.Unmarshal([]byte(data), &captchas); err != nil {
+			logger.Errorf("Corrupted captchas of %d: %v", chatID, err)
+		}
+	}
+	return captchas
+}
+
+func saveCaptchas(chatID int64, captchas map[int64]Captcha) error {
+	key := captchaPrefix + strconv.FormatInt(chatID, 10)
+	if len(captchas) == 0 {
+		return db.Del(key)
+	}
+	data, err := json.Marshal(captchas)
+	if err != nil {
+		return err
+	}
+	return db.Set(key, string(data))
+}
+
+func sendToChat(chatID int64, text string) {
+	peer, err := client.GetSendablePeer(chatID)
+	if err != nil {
+		logger.Debugf("Anti-raid: cannot resolve chat %d: %v", chatID, err)
+		return
+	}
+	client.SendMessage(peer, text, &telegram.SendOptions{ParseMode: "HTML"})
+}
+
+// antiFloodHandler punishes members of supergroups with anti-flood enabled
+// who send too many messages too quickly, and deletes the flood.
+func antiFloodHandler(m *telegram.NewMessage) error {
+	if !isSupergroup(m) || m.Sender == nil || m.SenderChat != nil || m.Action != nil {
+		return nil
+	}
+	userID := m.SenderID()
+	if userID == ubId || utils.IsIn64Array(sudoers, userID) {
+		return nil
+	}
+
+	config := getAntiFloodConfig(m.ChatID())
+	if config == nil || !config.Enabled {
+		return nil
+	}
+
+	key := fmt.Sprintf("%d:%d", m.ChatID(), userID)
+	flood := floodTracker.add(key, int64(m.ID), config.Limit, time.Duration(config.Duration)*time.Second)
+	if flood == nil || IsAdmin(userID, m.ChatID()) {
+		return nil
+	}
+
+	if err := punish(m.ChatID(), userID, config.Action, config.ActionDuration); err != nil {
+		logger.Debugf("Anti-flood: failed to punish %d in %d: %v", userID, m.ChatID(), err)
+		return nil
+	}
+
+	ids := make([]int32, len(flood))
+	for i, e := range flood {
+		ids[i] = int32(e.id)
+	}
+	if _, err := deleteMessages(m.ChatID(), ids); err != nil {
+		logger.Debugf("Anti-flood: failed to delete flood in %d: %v", m.ChatID(), err)
+	}
+
+	name := html.EscapeString(senderName(m))
+	action := describeAction(config.Action, config.ActionDuration)
+	m.Respond(fmt.Sprintf(tr(m, "antiflood.flood_punished"), userID, name, action))
+	logTo(logger.CategoryModeration, fmt.Sprintf(locales.Tr("antiflood.log_flood"), html.EscapeString(chatTitle(m)), m.ChatID(), userID, name, len(flood), time.Duration(config.Duration)*time.Second, action))
+	return nil
+}
+
+// antiRaidJoinHandler turns on raid mode when joins spike and restricts
+// every member joining while it is on.
+func antiRaidJoinHandler(p *telegram.ParticipantUpdate) error {
+	if !p.IsJoined() && !p.IsAdded() {
+		return nil
+	}
+	chatID, userID := p.ChatID(), p.UserID()
+	if userID == 0 || userID == ubId {
+		return nil
+	}
+
+	antiRaidMutex.Lock()
+	config := getAntiRaidConfig(chatID)
+	if config == nil || (!config.Enabled && !config.active()) {
+		antiRaidMutex.Unlock()
+		return nil
+	}
+
+	wasActive := config.active()
+	var raiders []int64
+	if config.Enabled {
+		for _, e := range joinTracker.add(strconv.FormatInt(chatID, 10), userID, config.Joins, time.Duration(config.Window)*time.Second) {
+			raiders = append(raiders, e.id)
+		}
+	}
+	if raiders != nil {
+		config.ActiveUntil = time.Now().Add(time.Duration(config.Cooldown) * time.Second)
+		if err := setAntiRaidConfig(chatID, config); err != nil {
+			logger.Errorf("Anti-raid: failed to save raid mode of %d: %v", chatID, err)
+		}
+	} else if wasActive {
+		raiders = []int64{userID}
+	}
+	antiRaidMutex.Unlock()
+
+	if raiders == nil {
+		return nil
+	}
+
+	if !wasActive {
+		title := "Unknown"
+		if p.Channel != nil {
+			title = p.Channel.Title
+		}
+		notice := "antiflood.raid_started"
+		if tgbot == nil {
+			notice = "antiflood.raid_started_muted"
+		}
+		sendToChat(chatID, fmt.Sprintf(locales.TrFor(0, chatID, notice), formatDurationHuman(time.Duration(config.Cooldown)*time.Second)))
+		logTo(logger.CategorySecurity, fmt.Sprintf(locales.Tr("antiflood.log_raid_started"), html.EscapeString(title), chatID, config.Joins, time.Duration(config.Window)*time.Second))
+	}
+
+	for _, id := range raiders {
+		if err := restrictRaider(chatID, id, config.ActiveUntil); err != nil {
+			logger.Debugf("Anti-raid: failed to restrict %d in %d: %v", id, chatID, err)
+		}
+	}
+	return nil
+}
+
+// restrictRaider mutes a member who joined during a raid. With the assistant
+// bot they get a captcha to lift the mute, otherwise the mute simply ends
+// with raid mode.
+func restrictRaider(chatID, userID int64, until time.Time) error {
+	if tgbot == nil {
+		return restrictFor(chatID, userID, false, max(time.Until(until), minRestriction))
+	}
+
+	antiRaidMutex.Lock()
+	_, pending := getCaptchas(chatID)[userID]
+	antiRaidMutex.Unlock()
+	if pending || IsAdmin(userID, chatID) {
+		return nil
+	}
+
+	if _, err := client.EditBanned(chatID, userID, &telegram.BannedOptions{Mute: true}); err != nil {
+		return err
+	}
+
+	msgID, err := sendCaptcha(chatID, userID)
+	if err != nil {
+		logger.Warnf("Anti-raid: failed to send captcha in %d: %v", chatID, err)
+	}
+
+	antiRaidMutex.Lock()
+	defer antiRaidMutex.Unlock()
+	captchas := getCaptchas(chatID)
+	captchas[userID] = Captcha{MsgID: msgID, Deadline: time.Now().Add(minutesVar("CAPTCHA_TIMEOUT"))}
+	return saveCaptchas(chatID, captchas)
+}
+
+// sendCaptcha posts the assistant's captcha for a user to the chat and
+// returns the ID of the message.
+func sendCaptcha(chatID, userID int64) (int32, error) {
+	name := strconv.FormatInt(userID, 10)
+	if user, err := client.GetUser(userID); err == nil {
+		name = strings.TrimSpace(user.FirstName + " " + user.LastName)
+	}
+	if len(name) > 64 {
+		name = name[:64]
+	}
+
+	results, err := client.InlineQuery(tbotId, &telegram.InlineOptions{Query: fmt.Sprintf("captcha %d %d %s", chatID, userID, name)})
+	if err != nil {
+		return 0, err
+	}
+	if len(results.Results) == 0 {
+		return 0, fmt.Errorf("no captcha returned by the assistant")
+	}
+	res, ok := results.Results[0].(*telegram.BotInlineResultObj)
+	if !ok {
+		return 0, fmt.Errorf("unexpected captcha result %T", results.Results[0])
+	}
+
+	peer, err := client.GetSendablePeer(chatID)
+	if err != nil {
+		return 0, err
+	}
+	updates, err := client.MessagesSendInlineBotResult(&telegram.MessagesSendInlineBotResultParams{
+		QueryID: results.QueryID, Peer: peer, RandomID: results.QueryID, ID: res.ID,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if u, ok := updates.(*telegram.UpdatesObj); ok {
+		for _, update := range u.Updates {
+			if nm, ok := update.(*telegram.UpdateNewChannelMessage); ok {
+				if msg, ok := nm.Message.(*telegram.MessageObj); ok {
+					return msg.ID, nil
+				}
+			}
+		}
+	}
+	return 0, nil
+}
+
+// CaptchaInline builds the captcha message; only the userbot may ask for it.
+func CaptchaInline(i *telegram.InlineQuery) error {
+	b := i.Builder()
+	fields := strings.SplitN(strings.TrimSpace(strings.TrimPrefix(i.Query, "captcha")), " ", 3)
+	if i.Sender.ID != ubId || len(fields) < 2 {
+		i.Answer(b.Results())
+		return nil
+	}
+
+	chatID, _ := strconv.ParseInt(fields[0], 10, 64)
+	userID, _ := strconv.ParseInt(fields[1], 10, 64)
+	name := fields[1]
+	if len(fields) == 3 && fields[2] != "" {
+		name = fields[2]
+	}
+
+	text := fmt.Sprintf(locales.TrFor(0, chatID, "antiflood.captcha_text"), userID, html.EscapeString(name), formatDurationHuman(minutesVar("CAPTCHA_TIMEOUT")))
+	markup := telegram.NewKeyboard().NewRow(1,
+		telegram.ButtonBuilder{}.Data(locales.TrFor(0, chatID, "antiflood.captcha_btn"), fmt.Sprintf("captcha:%d:%d", chatID, userID)),
+	).Build()
+	b.Article("Captcha", "Raid captcha", text, &telegram.ArticleOptions{ReplyMarkup: markup, ID: fmt.Sprintf("captcha_%d_%d", chatID, userID)})
+	i.Answer(b.Results())
+	return nil
+}
+
+// CaptchaCbk lifts the mute of the member the captcha was sent for.
+func CaptchaCbk(cb *telegram.InlineCallbackQuery) error {
+	parts := strings.Split(string(cb.Data), ":")
+	if len(parts) != 3 {
+		return nil
+	}
+	chatID, _ := strconv.ParseInt(parts[1], 10, 64)
+	userID, _ := strconv.ParseInt(parts[2], 10, 64)
+
+	if cb.Sender.ID != userID {
+		cb.Answer(locales.TrUser(cb.SenderID, "antiflood.captcha_not_yours"), &telegram.CallbackOptions{Alert: true})
+		return nil
+	}
+
+	antiRaidMutex.Lock()
+	captchas := getCaptchas(chatID)
+	_, pending := captchas[userID]
+	delete(captchas, userID)
+	err := saveCaptchas(chatID, captchas)
+	antiRaidMutex.Unlock()
+	if !pending {
+		cb.Answer(locales.TrUser(cb.SenderID, "antiflood.captcha_expired"), &telegram.CallbackOptions{Alert: true})
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := client.EditBanned(chatID, userID, &telegram.BannedOptions{Unmute: true}); err != nil {
+		logger.Warnf("Anti-raid: failed to unmute %d in %d: %v", userID, chatID, err)
+	}
+	name := html.EscapeString(strings.TrimSpace(cb.Sender.FirstName + " " + cb.Sender.LastName))
+	cb.Answer(locales.TrUser(cb.SenderID, "antiflood.captcha_ok"))
+	cb.Edit(fmt.Sprintf(locales.TrFor(0, chatID, "antiflood.captcha_solved"), userID, name), &telegram.SendOptions{ParseMode: "HTML"})
+	return nil
+}
+
+// liftRaid turns off raid mode in a chat.
+func liftRaid(chatID int64, config *AntiRaidConfig) error {
+	config.ActiveUntil = time.Time{}
+	if err := setAntiRaidConfig(chatID, config); err != nil {
+		return err
+	}
+	sendToChat(chatID, locales.TrFor(0, chatID, "antiflood.raid_lifted"))
+	logTo(logger.CategorySecurity, fmt.Sprintf(locales.Tr("antiflood.log_raid_lifted"), chatID))
+	return nil
+}
+
+// expireCaptchas removes members who did not solve their captcha in time.
+func expireCaptchas(chatID int64) {
+	antiRaidMutex.Lock()
+	captchas := getCaptchas(chatID)
+	var expired []int64
+	var msgIDs []int32
+	for userID, c := range captchas {
+		if time.Now().After(c.Deadline) {
+			expired = append(expired, userID)
+			if c.MsgID != 0 {
+				msgIDs = append(msgIDs, c.MsgID)
+			}
+			delete(captchas, userID)
+		}
+	}
+	if len(expired) > 0 {
+		if err := saveCaptchas(chatID, captchas); err != nil {
+			logger.Errorf("Anti-raid: failed to save captchas of %d: %v", chatID, err)
+		}
+	}
+	antiRaidMutex.Unlock()
+
+	if len(expired) == 0 {
+		return
+	}
+	for _, userID := range expired {
+		if _, err := client.KickParticipant(chatID, userID); err != nil {
+			logger.Debugf("Anti-raid: failed to remove %d from %d: %v", userID, chatID, err)
+		}
+	}
+	if len(msgIDs) > 0 {
+		deleteMessages(chatID, msgIDs)
+	}
+	logTo(logger.CategoryModeration, fmt.Sprintf(locales.Tr("antiflood.log_captcha_kicked"), len(expired), chatID))
+}
+
+func runAntiRaid(ctx context.Context) {
+	ticker := time.NewTicker(antiRaidTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		floodTracker.prune(maxFloodWindow)
+		joinTracker.prune(maxRaidWindow)
+
+		keys, err := db.Scan(antiRaidPrefix + "*")
+		if err != nil {
+			logger.Errorf("Anti-raid: failed to list configs: %v", err)
+			continue
+		}
+		for _, key := range keys {
+			chatID, _ := strconv.ParseInt(strings.TrimPrefix(key, antiRaidPrefix), 10, 64)
+
+			antiRaidMutex.Lock()
+			config := getAntiRaidConfig(chatID)
+			if config != nil && !config.ActiveUntil.IsZero() && !config.active() {
+				if err := liftRaid(chatID, config); err != nil {
+					logger.Errorf("Anti-raid: failed to lift raid mode of %d: %v", chatID, err)
+				}
+			}
+			antiRaidMutex.Unlock()
+		}
+
+		keys, err = db.Scan(captchaPrefix + "*")
+		if err != nil {
+			logger.Errorf("Anti-raid: failed to list captchas: %v", err)
+			continue
+		}
+		for _, key := range keys {
+			chatID, _ := strconv.ParseInt(strings.TrimPrefix(key, captchaPrefix), 10, 64)
+			expireCaptchas(chatID)
+		}
+	}
+}
+
+// AntiFloodCmd shows, toggles or configures anti-flood in the chat.
+func AntiFloodCmd(m *telegram.NewMessage) error {
+	if !isSupergroup(m) {
+		_, err := eOR(m, tr(m, "antiflood.supergroups_only"))
+		return err
+	}
+
+	args := strings.Fields(strings.ToLower(m.Args()))
+	config := getAntiFloodConfig(m.ChatID())
+	if config == nil {
+		config = defaultAntiFloodConfig()
+	} else {
+		copied := *config
+		config = &copied
+	}
+
+	switch {
+	case len(args) == 0:
+	case args[0] == "on" || args[0] == "off":
+		config.Enabled = args[0] == "on"
+	default:
+		if len(args) < 2 {
+			_, err := eOR(m, tr(m, "antiflood.usage_antiflood"))
+			return err
+		}
+		window, err := parseDurationString(args[0])
+		limit, limitErr := strconv.Atoi(args[1])
+		if err != nil || limitErr != nil || window < time.Second || window > maxFloodWindow || limit < 2 || limit > 100 {
+			_, err := eOR(m, tr(m, "antiflood.usage_antiflood"))
+			return err
+		}
+		config.Limit, config.Duration, config.Enabled = limit, int64(window.Seconds()), true
+		if len(args) > 2 {
+			action, d, err := parseAction(args[2:])
+			if errors.Is(err, errBadDuration) {
+				_, err := eOR(m, tr(m, "admin.invalid_duration"))
+				return err
+			}
+			if err != nil {
+				_, err := eOR(m, tr(m, "antiflood.usage_antiflood"))
+				return err
+			}
+			config.Action, config.ActionDuration = action, d
+		}
+	}
+
+	if len(args) > 0 {
+		if err := setAntiFloodConfig(m.ChatID(), config); err != nil {
+			_, err := eOR(m, fmt.Sprintf(tr(m, "antiflood.config_error"), html.EscapeString(err.Error())))
+			return err
+		}
+	}
+
+	if !config.Enabled {
+		_, err := eOR(m, tr(m, "antiflood.flood_disabled"))
+		return err
+	}
+	_, err := eOR(m, fmt.Sprintf(tr(m, "antiflood.flood_status"), config.Limit, time.Duration(config.Duration)*time.Second, describeAction(config.Action, config.ActionDuration)))
+	return err
+}
+
+// AntiRaidCmd shows, toggles or configures raid detection in the chat.
+func AntiRaidCmd(m *telegram.NewMessage) error {
+	if !isSupergroup(m) {
+		_, err := eOR(m, tr(m, "antiflood.supergroups_only"))
+		return err
+	}
+
+	antiRaidMutex.Lock()
+	defer antiRaidMutex.Unlock()
+
+	args := strings.Fields(strings.ToLower(m.Args()))
+	config := getAntiRaidConfig(m.ChatID())
+	if config == nil {
+		config = defaultAntiRaidConfig()
+	}
+
+	switch {
+	case len(args) == 0:
+	case args[0] == "on" || args[0] == "off":
+		config.Enabled = args[0] == "on"
+	default:
+		if len(args) < 2 {
+			_, err := eOR(m, tr(m, "antiflood.usage_antiraid"))
+			return err
+		}
+		window, err := parseDurationString(args[0])
+		joins, joinsErr := strconv.Atoi(args[1])
+		if err != nil || joinsErr != nil || window < 10*time.Second || window > maxRaidWindow || joins < 2 || joins > 1000 {
+			_, err := eOR(m, tr(m, "antiflood.usage_antiraid"))
+			return err
+		}
+		config.Joins, config.Window, config.Enabled = joins, int64(window.Seconds()), true
+		if len(args) > 2 {
+			cooldown, err := parseDurationString(args[2])
+			if err != nil || cooldown < time.Minute || cooldown > 24*time.Hour {
+				_, err := eOR(m, tr(m, "antiflood.usage_antiraid"))
+				return err
+			}
+			config.Cooldown = int64(cooldown.Seconds())
+		}
+	}
+
+	if len(args) > 0 {
+		if err := setAntiRaidConfig(m.ChatID(), config); err != nil {
+			_, err := eOR(m, fmt.Sprintf(tr(m, "antiflood.config_error"), html.EscapeString(err.Error())))
+			return err
+		}
+	}
+
+	text := tr(m, "antiflood.raid_disabled")
+	if config.Enabled {
+		text = fmt.Sprintf(tr(m, "antiflood.raid_status"), config.Joins, time.Duration(config.Window)*time.Second, formatDurationHuman(time.Duration(config.Cooldown)*time.Second))
+	}
+	if config.active() {
+		text += fmt.Sprintf(tr(m, "antiflood.raid_active"), config.ActiveUntil.Format("2006-01-02 15:04"), len(getCaptchas(m.ChatID())))
+	}
+	_, err := eOR(m, text)
+	return err
+}
+
+// RaidModeCmd turns raid mode on or off by hand.
+func RaidModeCmd(m *telegram.NewMessage) error {
+	if !isSupergroup(m) {
+		_, err := eOR(m, tr(m, "antiflood.supergroups_only"))
+		return err
+	}
+
+	args := strings.Fields(strings.ToLower(m.Args()))
+	if len(args) == 0 || (args[0] != "on" && args[0] != "off") {
+		_, err := eOR(m, tr(m, "antiflood.usage_raidmode"))
+		return err
+	}
+
+	antiRaidMutex.Lock()
+	defer antiRaidMutex.Unlock()
+
+	config := getAntiRaidConfig(m.ChatID())
+	if config == nil {
+		config = defaultAntiRaidConfig()
+	}
+
+	if args[0] == "off" {
+		if !config.active() {
+			_, err := eOR(m, tr(m, "antiflood.raid_not_active"))
+			return err
+		}
+		if err := liftRaid(m.ChatID(), config); err != nil {
+			_, err := eOR(m, fmt.Sprintf(tr(m, "antiflood.config_error"), html.EscapeString(err.Error())))
+			return err
+		}
+		_, err := eOR(m, tr(m, "antiflood.raid_mode_off"))
+		return err
+	}
+
+	d := time.Duration(config.Cooldown) * time.Second
+	if len(args) > 1 {
+		var err error
+		if d, err = parseDurationString(args[1]); err != nil || d < time.Minute || d > 7*24*time.Hour {
+			_, err := eOR(m, tr(m, "antiflood.usage_raidmode"))
+			return err
+		}
+	}
+	config.ActiveUntil = time.Now().Add(d)
+	if err := setAntiRaidConfig(m.ChatID(), config); err != nil {
+		_, err := eOR(m, fmt.Sprintf(tr(m, "antiflood.config_error"), html.EscapeString(err.Error())))
+		return err
+	}
+	auditAction(m, "raidmode", 0, "", formatDurationHuman(d))
+	_, err := eOR(m, fmt.Sprintf(tr(m, "antiflood.raid_mode_on"), formatDurationHuman(d)))
+	return err
+}
+
+func LoadAntiFloodModule(c *telegram.Client) {
+	RegisterVars([]*ConfigVar{
+		{Key: "CAPTCHA_TIMEOUT", Module: "AntiFlood", Type: VarInt, Default: "5", Description: "Minutes members joining during a raid have to solve the captcha before they are removed", Validate: intRange(1, 60)},
+	})
+
+	handlers := []*Handler{
+		{Command: "antiflood", Func: AntiFloodCmd, Description: "Show or set anti-flood (on, off, <duration> <limit> [action])", ModuleName: "AntiFlood", DisAllowSudos: true},
+		{Command: "antiraid", Func: AntiRaidCmd, Description: "Show or set raid detection (on, off, <window> <joins> [cooldown])", ModuleName: "AntiFlood", DisAllowSudos: true},
+		{Command: "raidmode", Func: RaidModeCmd, Description: "Turn raid mode on or off by hand (on [duration], off)", ModuleName: "AntiFlood", DisAllowSudos: true},
+	}
+	AddHandlers(handlers, c)
+
+	c.On("message", antiFloodHandler)
+	c.On(telegram.OnParticipant, antiRaidJoinHandler)
+	if tgbot != nil {
+		tgbot.AddInlineCallbackHandler("captcha", CaptchaCbk)
+		tgbot.On("inline:captcha", CaptchaInline)
+	}
+
+	startWorker("anti-raid", runAntiRaid)
+}
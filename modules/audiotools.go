@@ -1,7 +1,6 @@
 package modules
 
 import (
-	"NovaUserbot/locales"
 	"NovaUserbot/utils"
 	"fmt"
 	"os"
@@ -44,19 +43,19 @@ func isValidTimeFormat(t string) bool {
 
 func audioTrimCommand(m *telegram.NewMessage) error {
 	if !m.IsReply() {
-		_, err := eOR(m, locales.Tr("audiotools.reply_required"))
+		_, err := eOR(m, tr(m, "audiotools.reply_required"))
 		return err
 	}
 
 	args := strings.TrimSpace(m.Args())
 	if args == "" {
-		_, err := eOR(m, locales.Tr("audiotools.trim_usage"))
+		_, err := eOR(m, tr(m, "audiotools.trim_usage"))
 		return err
 	}
 
 	parts := strings.Fields(args)
 	if len(parts) < 2 {
-		_, err := eOR(m, locales.Tr("audiotools.trim_usage"))
+		_, err := eOR(m, tr(m, "audiotools.trim_usage"))
 		return err
 	}
 
@@ -64,36 +63,36 @@ func audioTrimCommand(m *telegram.NewMessage) error {
 	endTime := parts[1]
 
 	if !isValidTimeFormat(startTime) || !isValidTimeFormat(endTime) {
-		_, err := eOR(m, locales.Tr("audiotools.invalid_time_format"))
+		_, err := eOR(m, tr(m, "audiotools.invalid_time_format"))
 		return err
 	}
 
 	if !checkFFmpeg() {
-		_, err := eOR(m, locales.Tr("audiotools.ffmpeg_missing"))
+		_, err := eOR(m, tr(m, "audiotools.ffmpeg_missing"))
 		return err
 	}
 
 	reply, err := m.GetReplyMessage()
 	if err != nil {
-		_, err := eOR(m, locales.Tr("audiotools.fetch_error"))
+		_, err := eOR(m, tr(m, "audiotools.fetch_error"))
 		return err
 	}
 
 	if reply.Audio() == nil && reply.Voice() == nil && reply.Video() == nil {
-		_, err := eOR(m, locales.Tr("audiotools.no_audio"))
+		_, err := eOR(m, tr(m, "audiotools.no_audio"))
 		return err
 	}
 
-	msg, _ := eOR(m, locales.Tr("audiotools.downloading"))
+	msg, _ := eOR(m, tr(m, "audiotools.downloading"))
 
 	inputPath, err := reply.Download()
 	if err != nil {
-		_, err := msg.Edit(locales.Tr("audiotools.download_error"))
+		_, err := msg.Edit(tr(m, "audiotools.download_error"))
 		return err
 	}
 	defer os.Remove(inputPath)
 
-	msg.Edit(locales.Tr("audiotools.processing"))
+	msg.Edit(tr(m, "audiotools.processing"))
 
 	ext := filepath.Ext(inputPath)
 	if ext == "" {
@@ -105,15 +104,15 @@ func audioTrimCommand(m *telegram.NewMessage) error {
 	cmd := fmt.Sprintf("ffmpeg -y -i %q -ss %s -to %s -c copy %q", inputPath, startTime, endTime, outputPath)
 	output, err := utils.RunCommand(cmd)
 	if err != nil {
-		_, err := msg.Edit(fmt.Sprintf(locales.Tr("audiotools.process_error"), output))
+		_, err := msg.Edit(fmt.Sprintf(tr(m, "audiotools.process_error"), output))
 		return err
 	}
 
-	msg.Edit(locales.Tr("audiotools.uploading"))
+	msg.Edit(tr(m, "audiotools.uploading"))
 
 	_, err = m.Respond("", &telegram.SendOptions{Media: outputPath})
 	if err != nil {
-		_, err := msg.Edit(locales.Tr("audiotools.upload_error"))
+		_, err := msg.Edit(tr(m, "audiotools.upload_error"))
 		return err
 	}
 
@@ -123,43 +122,43 @@ func audioTrimCommand(m *telegram.NewMessage) error {
 
 func audioConvertCommand(m *telegram.NewMessage) error {
 	if !m.IsReply() {
-		_, err := eOR(m, locales.Tr("audiotools.reply_required"))
+		_, err := eOR(m, tr(m, "audiotools.reply_required"))
 		return err
 	}
 
 	targetFormat := strings.ToLower(strings.TrimSpace(m.Args()))
 	validFormats := map[string]bool{"mp3": true, "wav": true, "ogg": true, "flac": true, "aac": true, "m4a": true, "opus": true}
 	if targetFormat == "" || !validFormats[targetFormat] {
-		_, err := eOR(m, locales.Tr("audiotools.convert_usage"))
+		_, err := eOR(m, tr(m, "audiotools.convert_usage"))
 		return err
 	}
 
 	if !checkFFmpeg() {
-		_, err := eOR(m, locales.Tr("audiotools.ffmpeg_missing"))
+		_, err := eOR(m, tr(m, "audiotools.ffmpeg_missing"))
 		return err
 	}
 
 	reply, err := m.GetReplyMessage()
 	if err != nil {
-		_, err := eOR(m, locales.Tr("audiotools.fetch_error"))
+		_, err := eOR(m, tr(m, "audiotools.fetch_error"))
 		return err
 	}
 
 	if reply.Audio() == nil && reply.Voice() == nil && reply.Video() == nil {
-		_, err := eOR(m, locales.Tr("audiotools.no_audio"))
+		_, err := eOR(m, tr(m, "audiotools.no_audio"))
 		return err
 	}
 
-	msg, _ := eOR(m, locales.Tr("audiotools.downloading"))
+	msg, _ := eOR(m, tr(m, "audiotools.downloading"))
 
 	inputPath, err := reply.Download()
 	if err != nil {
-		_, err := msg.Edit(locales.Tr("audiotools.download_error"))
+		_, err := msg.Edit(tr(m, "audiotools.download_error"))
 		return err
 	}
 	defer os.Remove(inputPath)
 
-	msg.Edit(locales.Tr("audiotools.converting"))
+	msg.Edit(tr(m, "audiotools.converting"))
 
 	outputPath := filepath.Join(os.TempDir(), fmt.Sprintf("convert_%d.%s", m.ID, targetFormat))
 	defer os.Remove(outputPath)
@@ -167,15 +166,15 @@ func audioConvertCommand(m *telegram.NewMessage) error {
 	cmd := fmt.Sprintf("ffmpeg -y -i %q %q", inputPath, outputPath)
 	output, err := utils.RunCommand(cmd)
 	if err != nil {
-		_, err := msg.Edit(fmt.Sprintf(locales.Tr("audiotools.process_error"), output))
+		_, err := msg.Edit(fmt.Sprintf(tr(m, "audiotools.process_error"), output))
 		return err
 	}
 
-	msg.Edit(locales.Tr("audiotools.uploading"))
+	msg.Edit(tr(m, "audiotools.uploading"))
 
 	_, err = m.Respond("", &telegram.SendOptions{Media: outputPath})
 	if err != nil {
-		_, err := msg.Edit(locales.Tr("audiotools.upload_error"))
+		_, err := msg.Edit(tr(m, "audiotools.upload_error"))
 		return err
 	}
 
@@ -185,36 +184,36 @@ func audioConvertCommand(m *telegram.NewMessage) error {
 
 func extractAudioCommand(m *telegram.NewMessage) error {
 	if !m.IsReply() {
-		_, err := eOR(m, locales.Tr("audiotools.reply_required"))
+		_, err := eOR(m, tr(m, "audiotools.reply_required"))
 		return err
 	}
 
 	if !checkFFmpeg() {
-		_, err := eOR(m, locales.Tr("audiotools.ffmpeg_missing"))
+		_, err := eOR(m, tr(m, "audiotools.ffmpeg_missing"))
 		return err
 	}
 
 	reply, err := m.GetReplyMessage()
 	if err != nil {
-		_, err := eOR(m, locales.Tr("audiotools.fetch_error"))
+		_, err := eOR(m, tr(m, "audiotools.fetch_error"))
 		return err
 	}
 
 	if reply.Video() == nil {
-		_, err := eOR(m, locales.Tr("audiotools.no_video"))
+		_, err := eOR(m, tr(m, "audiotools.no_video"))
 		return err
 	}
 
-	msg, _ := eOR(m, locales.Tr("audiotools.downloading"))
+	msg, _ := eOR(m, tr(m, "audiotools.downloading"))
 
 	inputPath, err := reply.Download()
 	if err != nil {
-		_, err := msg.Edit(locales.Tr("audiotools.download_error"))
+		_, err := msg.Edit(tr(m, "audiotools.download_error"))
 		return err
 	}
 	defer os.Remove(inputPath)
 
-	msg.Edit(locales.Tr("audiotools.extracting"))
+	msg.Edit(tr(m, "audiotools.extracting"))
 
 	format := strings.ToLower(strings.TrimSpace(m.Args()))
 	if format == "" {
@@ -235,15 +234,15 @@ func extractAudioCommand(m *telegram.NewMessage) error {
 
 	output, err := utils.RunCommand(cmd)
 	if err != nil {
-		_, err := msg.Edit(fmt.Sprintf(locales.Tr("audiotools.process_error"), output))
+		_, err := msg.Edit(fmt.Sprintf(tr(m, "audiotools.process_error"), output))
 		return err
 	}
 
-	msg.Edit(locales.Tr("audiotools.uploading"))
+	msg.Edit(tr(m, "audiotools.uploading"))
 
 	_, err = m.Respond("", &telegram.SendOptions{Media: outputPath})
 	if err != nil {
-		_, err := msg.Edit(locales.Tr("audiotools.upload_error"))
+		_, err := msg.Edit(tr(m, "audiotools.upload_error"))
 		return err
 	}
 
@@ -253,7 +252,7 @@ func extractAudioCommand(m *telegram.NewMessage) error {
 
 func audioBitrateCommand(m *telegram.NewMessage) error {
 	if !m.IsReply() {
-		_, err := eOR(m, locales.Tr("audiotools.reply_required"))
+		_, err := eOR(m, tr(m, "audiotools.reply_required"))
 		return err
 	}
 
@@ -264,36 +263,36 @@ func audioBitrateCommand(m *telegram.NewMessage) error {
 
 	bitrateNum, err := strconv.Atoi(strings.TrimSuffix(bitrate, "k"))
 	if err != nil || bitrateNum < 32 || bitrateNum > 320 {
-		_, err := eOR(m, locales.Tr("audiotools.bitrate_usage"))
+		_, err := eOR(m, tr(m, "audiotools.bitrate_usage"))
 		return err
 	}
 
 	if !checkFFmpeg() {
-		_, err := eOR(m, locales.Tr("audiotools.ffmpeg_missing"))
+		_, err := eOR(m, tr(m, "audiotools.ffmpeg_missing"))
 		return err
 	}
 
 	reply, err := m.GetReplyMessage()
 	if err != nil {
-		_, err := eOR(m, locales.Tr("audiotools.fetch_error"))
+		_, err := eOR(m, tr(m, "audiotools.fetch_error"))
 		return err
 	}
 
 	if reply.Audio() == nil && reply.Voice() == nil {
-		_, err := eOR(m, locales.Tr("audiotools.no_audio"))
+		_, err := eOR(m, tr(m, "audiotools.no_audio"))
 		return err
 	}
 
-	msg, _ := eOR(m, locales.Tr("audiotools.downloading"))
+	msg, _ := eOR(m, tr(m, "audiotools.downloading"))
 
 	inputPath, err := reply.Download()
 	if err != nil {
-		_, err := msg.Edit(locales.Tr("audiotools.download_error"))
+		_, err := msg.Edit(tr(m, "audiotools.download_error"))
 		return err
 	}
 	defer os.Remove(inputPath)
 
-	msg.Edit(locales.Tr("audiotools.processing"))
+	msg.Edit(tr(m, "audiotools.processing"))
 
 	ext := filepath.Ext(inputPath)
 	if ext == "" {
@@ -305,15 +304,15 @@ func audioBitrateCommand(m *telegram.NewMessage) error {
 	cmd := fmt.Sprintf("ffmpeg -y -i %q -b:a %dk %q", inputPath, bitrateNum, outputPath)
 	output, err := utils.RunCommand(cmd)
 	if err != nil {
-		_, err := msg.Edit(fmt.Sprintf(locales.Tr("audiotools.process_error"), output))
+		_, err := msg.Edit(fmt.Sprintf(tr(m, "audiotools.process_error"), output))
 		return err
 	}
 
-	msg.Edit(locales.Tr("audiotools.uploading"))
+	msg.Edit(tr(m, "audiotools.uploading"))
 
 	_, err = m.Respond("", &telegram.SendOptions{Media: outputPath})
 	if err != nil {
-		_, err := msg.Edit(locales.Tr("audiotools.upload_error"))
+		_, err := msg.Edit(tr(m, "audiotools.upload_error"))
 		return err
 	}
 
@@ -323,36 +322,36 @@ func audioBitrateCommand(m *telegram.NewMessage) error {
 
 func voiceToMp3Command(m *telegram.NewMessage) error {
 	if !m.IsReply() {
-		_, err := eOR(m, locales.Tr("audiotools.reply_required"))
+		_, err := eOR(m, tr(m, "audiotools.reply_required"))
 		return err
 	}
 
 	if !checkFFmpeg() {
-		_, err := eOR(m, locales.Tr("audiotools.ffmpeg_missing"))
+		_, err := eOR(m, tr(m, "audiotools.ffmpeg_missing"))
 		return err
 	}
 
 	reply, err := m.GetReplyMessage()
 	if err != nil {
-		_, err := eOR(m, locales.Tr("audiotools.fetch_error"))
+		_, err := eOR(m, tr(m, "audiotools.fetch_error"))
 		return err
 	}
 
 	if reply.Voice() == nil {
-		_, err := eOR(m, locales.Tr("audiotools.no_voice"))
+		_, err := eOR(m, tr(m, "audiotools.no_voice"))
 		return err
 	}
 
-	msg, _ := eOR(m, locales.Tr("audiotools.downloading"))
+	msg, _ := eOR(m, tr(m, "audiotools.downloading"))
 
 	inputPath, err := reply.Download()
 	if err != nil {
-		_, err := msg.Edit(locales.Tr("audiotools.download_error"))
+		_, err := msg.Edit(tr(m, "audiotools.download_error"))
 		return err
 	}
 	defer os.Remove(inputPath)
 
-	msg.Edit(locales.Tr("audiotools.converting"))
+	msg.Edit(tr(m, "audiotools.converting"))
 
 	outputPath := filepath.Join(os.TempDir(), fmt.Sprintf("voice_%d.mp3", m.ID))
 	defer os.Remove(outputPath)
@@ -360,15 +359,15 @@ func voiceToMp3Command(m *telegram.NewMessage) error {
 	cmd := fmt.Sprintf("ffmpeg -y -i %q -acodec libmp3lame -q:a 2 %q", inputPath, outputPath)
 	output, err := utils.RunCommand(cmd)
 	if err != nil {
-		_, err := msg.Edit(fmt.Sprintf(locales.Tr("audiotools.process_error"), output))
+		_, err := msg.Edit(fmt.Sprintf(tr(m, "audiotools.process_error"), output))
 		return err
 	}
 
-	msg.Edit(locales.Tr("audiotools.uploading"))
+	msg.Edit(tr(m, "audiotools.uploading"))
 
 	_, err = m.Respond("", &telegram.SendOptions{Media: outputPath})
 	if err != nil {
-		_, err := msg.Edit(locales.Tr("audiotools.upload_error"))
+		_, err := msg.Edit(tr(m, "audiotools.upload_error"))
 		return err
 	}
 
@@ -378,36 +377,36 @@ func voiceToMp3Command(m *telegram.NewMessage) error {
 
 func mp3ToVoiceCommand(m *telegram.NewMessage) error {
 	if !m.IsReply() {
-		_, err := eOR(m, locales.Tr("audiotools.reply_required"))
+		_, err := eOR(m, tr(m, "audiotools.reply_required"))
 		return err
 	}
 
 	if !checkFFmpeg() {
-		_, err := eOR(m, locales.Tr("audiotools.ffmpeg_missing"))
+		_, err := eOR(m, tr(m, "audiotools.ffmpeg_missing"))
 		return err
 	}
 
 	reply, err := m.GetReplyMessage()
 	if err != nil {
-		_, err := eOR(m, locales.Tr("audiotools.fetch_error"))
+		_, err := eOR(m, tr(m, "audiotools.fetch_error"))
 		return err
 	}
 
 	if reply.Audio() == nil {
-		_, err := eOR(m, locales.Tr("audiotools.no_audio"))
+		_, err := eOR(m, tr(m, "audiotools.no_audio"))
 		return err
 	}
 
-	msg, _ := eOR(m, locales.Tr("audiotools.downloading"))
+	msg, _ := eOR(m, tr(m, "audiotools.downloading"))
 
 	inputPath, err := reply.Download()
 	if err != nil {
-		_, err := msg.Edit(locales.Tr("audiotools.download_error"))
+		_, err := msg.Edit(tr(m, "audiotools.download_error"))
 		return err
 	}
 	defer os.Remove(inputPath)
 
-	msg.Edit(locales.Tr("audiotools.converting"))
+	msg.Edit(tr(m, "audiotools.converting"))
 
 	outputPath := filepath.Join(os.TempDir(), fmt.Sprintf("tovoice_%d.ogg", m.ID))
 	defer os.Remove(outputPath)
@@ -415,15 +414,15 @@ func mp3ToVoiceCommand(m *telegram.NewMessage) error {
 	cmd := fmt.Sprintf("ffmpeg -y -i %q -acodec libopus -b:a 64k %q", inputPath, outputPath)
 	output, err := utils.RunCommand(cmd)
 	if err != nil {
-		_, err := msg.Edit(fmt.Sprintf(locales.Tr("audiotools.process_error"), output))
+		_, err := msg.Edit(fmt.Sprintf(tr(m, "audiotools.process_error"), output))
 		return err
 	}
 
-	msg.Edit(locales.Tr("audiotools.uploading"))
+	msg.Edit(tr(m, "audiotools.uploading"))
 
 	uploaded, err := m.Client.UploadFile(outputPath)
 	if err != nil {
-		_, err := msg.Edit(locales.Tr("audiotools.upload_error"))
+		_, err := msg.Edit(tr(m, "audiotools.upload_error"))
 		return err
 	}
 
@@ -438,7 +437,7 @@ func mp3ToVoiceCommand(m *telegram.NewMessage) error {
 	})
 
 	if err != nil {
-		_, err := msg.Edit(locales.Tr("audiotools.upload_error"))
+		_, err := msg.Edit(tr(m, "audiotools.upload_error"))
 		return err
 	}
 
@@ -448,7 +447,7 @@ func mp3ToVoiceCommand(m *telegram.NewMessage) error {
 
 func audioSpeedCommand(m *telegram.NewMessage) error {
 	if !m.IsReply() {
-		_, err := eOR(m, locales.Tr("audiotools.reply_required"))
+		_, err := eOR(m, tr(m, "audiotools.reply_required"))
 		return err
 	}
 
@@ -459,36 +458,36 @@ func audioSpeedCommand(m *telegram.NewMessage) error {
 
 	speed, err := strconv.ParseFloat(speedStr, 64)
 	if err != nil || speed < 0.5 || speed > 2.0 {
-		_, err := eOR(m, locales.Tr("audiotools.speed_usage"))
+		_, err := eOR(m, tr(m, "audiotools.speed_usage"))
 		return err
 	}
 
 	if !checkFFmpeg() {
-		_, err := eOR(m, locales.Tr("audiotools.ffmpeg_missing"))
+		_, err := eOR(m, tr(m, "audiotools.ffmpeg_missing"))
 		return err
 	}
 
 	reply, err := m.GetReplyMessage()
 	if err != nil {
-		_, err := eOR(m, locales.Tr("audiotools.fetch_error"))
+		_, err := eOR(m, tr(m, "audiotools.fetch_error"))
 		return err
 	}
 
 	if reply.Audio() == nil && reply.Voice() == nil {
-		_, err := eOR(m, locales.Tr("audiotools.no_audio"))
+		_, err := eOR(m, tr(m, "audiotools.no_audio"))
 		return err
 	}
 
-	msg, _ := eOR(m, locales.Tr("audiotools.downloading"))
+	msg, _ := eOR(m, tr(m, "audiotools.downloading"))
 
 	inputPath, err := reply.Download()
 	if err != nil {
-		_, err := msg.Edit(locales.Tr("audiotools.download_error"))
+		_, err := msg.Edit(tr(m, "audiotools.download_error"))
 		return err
 	}
 	defer os.Remove(inputPath)
 
-	msg.Edit(locales.Tr("audiotools.processing"))
+	msg.Edit(tr(m, "audiotools.processing"))
 
 	ext := filepath.Ext(inputPath)
 	if ext == "" {
@@ -501,15 +500,15 @@ func audioSpeedCommand(m *telegram.NewMessage) error {
 	cmd := fmt.Sprintf("ffmpeg -y -i %q -filter:a 'atempo=%f' %q", inputPath, atempo, outputPath)
 	output, err := utils.RunCommand(cmd)
 	if err != nil {
-		_, err := msg.Edit(fmt.Sprintf(locales.Tr("audiotools.process_error"), output))
+		_, err := msg.Edit(fmt.Sprintf(tr(m, "audiotools.process_error"), output))
 		return err
 	}
 
-	msg.Edit(locales.Tr("audiotools.uploading"))
+	msg.Edit(tr(m, "audiotools.uploading"))
 
 	_, err = m.Respond("", &telegram.SendOptions{Media: outputPath})
 	if err != nil {
-		_, err := msg.Edit(locales.Tr("audiotools.upload_error"))
+		_, err := msg.Edit(tr(m, "audiotools.upload_error"))
 		return err
 	}
 
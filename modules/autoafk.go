@@ -0,0 +1,250 @@
+package modules
+
+import (
+	"NovaUserbot/db"
+	"NovaUserbot/locales"
+	"NovaUserbot/logger"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+type AutoAFKConfig struct {
+	Enabled   bool   `json:"enabled"`
+	After     int64  `json:"after"`
+	Reason    string `json:"reason"`
+	OnOffline bool   `json:"on_offline"`
+}
+
+const (
+	autoAFKMinIdle      = time.Minute
+	autoAFKCheckEvery   = time.Minute
+	autoAFKDefaultAfter = 30 * time.Minute
+)
+
+var (
+	lastActivity     = time.Now()
+	lastActivityLock sync.RWMutex
+
+	autoAFKMutex          sync.Mutex
+	autoAFKCheckerRunning = false
+	autoAFKCheckerStop    chan bool
+)
+
+func getAutoAFKConfig() *AutoAFKConfig {
+	data := db.Get("AUTO_AFK")
+	if data == "" {
+		return nil
+	}
+
+	var config AutoAFKConfig
+	if err := json.Unmarshal([]byte(data), &config); err != nil {
+		logger.Errorf("Corrupted auto-AFK config: %v", err)
+		return nil
+	}
+	return &config
+}
+
+func setAutoAFKConfig(config *AutoAFKConfig) error {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	return db.Set("AUTO_AFK", string(data))
+}
+
+func markActivity() {
+	lastActivityLock.Lock()
+	lastActivity = time.Now()
+	lastActivityLock.Unlock()
+}
+
+func getLastActivity() time.Time {
+	lastActivityLock.RLock()
+	defer lastActivityLock.RUnlock()
+	return lastActivity
+}
+
+// enterAutoAFK switches on AFK with the configured preset reason unless AFK is
+// already active. since is used as the AFK start time.
+func enterAutoAFK(config *AutoAFKConfig, since time.Time, trigger string) {
+	afkMutex.Lock()
+	if getAFK().IsAFK {
+		afkMutex.Unlock()
+		return
+	}
+
+	reason := config.Reason
+	if reason == "" {
+		reason = locales.Tr("afk.auto_reason")
+	}
+
+	setAFK(AFKData{
+		IsAFK:      true,
+		Reason:     reason,
+		StartTime:  since,
+		LastNotify: map[int64]time.Time{},
+		Scope:      afkScopeAll,
+		Auto:       true,
+	})
+	afkMutex.Unlock()
+
+	logger.Infof("Auto-AFK enabled (%s)", trigger)
+	logTo(logger.CategorySystem, fmt.Sprintf(locales.Tr("afk.auto_entered_log"), trigger, reason))
+}
+
+func checkAutoAFK() {
+	config := getAutoAFKConfig()
+	if config == nil || !config.Enabled || config.After <= 0 {
+		return
+	}
+
+	last := getLastActivity()
+	if time.Since(last) >= time.Duration(config.After)*time.Second {
+		enterAutoAFK(config, last, locales.Tr("afk.auto_trigger_idle"))
+	}
+}
+
+func StartAutoAFKChecker() {
+	autoAFKMutex.Lock()
+	if autoAFKCheckerRunning {
+		autoAFKMutex.Unlock()
+		return
+	}
+	autoAFKCheckerRunning = true
+	autoAFKCheckerStop = make(chan bool)
+	autoAFKMutex.Unlock()
+
+	stop := autoAFKCheckerStop
+	startWorker("auto-AFK", func(ctx context.Context) {
+		ticker := time.NewTicker(autoAFKCheckEvery)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				checkAutoAFK()
+			case <-stop:
+				logger.Info("Auto-AFK checker stopped")
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	})
+}
+
+func StopAutoAFKChecker() {
+	autoAFKMutex.Lock()
+	defer autoAFKMutex.Unlock()
+
+	if !autoAFKCheckerRunning {
+		return
+	}
+
+	autoAFKCheckerRunning = false
+	close(autoAFKCheckerStop)
+}
+
+// autoAFKStatusHandler enters AFK as soon as the account goes offline on all
+// other sessions, when the offline trigger is enabled.
+func autoAFKStatusHandler(u telegram.Update, c *telegram.Client) error {
+	upd, ok := u.(*telegram.UpdateUserStatus)
+	if !ok || upd.UserID != ubId {
+		return nil
+	}
+
+	if _, offline := upd.Status.(*telegram.UserStatusOffline); !offline {
+		return nil
+	}
+
+	config := getAutoAFKConfig()
+	if config == nil || !config.Enabled || !config.OnOffline {
+		return nil
+	}
+
+	enterAutoAFK(config, getLastActivity(), locales.Tr("afk.auto_trigger_offline"))
+	return nil
+}
+
+func autoAFKCommand(m *telegram.NewMessage) error {
+	args := strings.Fields(m.Args())
+	config := getAutoAFKConfig()
+
+	if len(args) == 0 {
+		if config == nil || !config.Enabled {
+			_, err := eOR(m, tr(m, "afk.auto_status_off"))
+			return err
+		}
+		idle := tr(m, "afk.auto_idle_disabled")
+		if config.After > 0 {
+			idle = fmtDur(time.Duration(config.After) * time.Second)
+		}
+		_, err := eOR(m, fmt.Sprintf(tr(m, "afk.auto_status_on"), idle, config.OnOffline, config.Reason))
+		return err
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "off":
+		if err := db.Del("AUTO_AFK"); err != nil {
+			_, err := eOR(m, tr(m, "afk.set_error"))
+			return err
+		}
+		_, err := eOR(m, tr(m, "afk.auto_disabled"))
+		return err
+
+	case "offline":
+		if len(args) < 2 || (args[1] != "on" && args[1] != "off") {
+			_, err := eOR(m, tr(m, "afk.auto_usage"))
+			return err
+		}
+		if config == nil {
+			config = &AutoAFKConfig{After: int64(autoAFKDefaultAfter.Seconds())}
+		}
+		config.Enabled = true
+		config.OnOffline = args[1] == "on"
+		if err := setAutoAFKConfig(config); err != nil {
+			_, err := eOR(m, tr(m, "afk.set_error"))
+			return err
+		}
+		_, err := eOR(m, fmt.Sprintf(tr(m, "afk.auto_offline_set"), args[1]))
+		return err
+	}
+
+	after, err := parseDurationString(args[0])
+	if err != nil || after < autoAFKMinIdle {
+		_, err := eOR(m, tr(m, "afk.auto_usage"))
+		return err
+	}
+
+	if config == nil {
+		config = &AutoAFKConfig{}
+	}
+	config.Enabled = true
+	config.After = int64(after.Seconds())
+	config.Reason = strings.Join(args[1:], " ")
+
+	if err := setAutoAFKConfig(config); err != nil {
+		_, err := eOR(m, tr(m, "afk.set_error"))
+		return err
+	}
+
+	markActivity()
+	_, err = eOR(m, fmt.Sprintf(tr(m, "afk.auto_enabled"), fmtDur(after)))
+	return err
+}
+
+func loadAutoAFK(c *telegram.Client) {
+	handlers := []*Handler{
+		{Command: "autoafk", Func: autoAFKCommand, Description: "Auto-AFK after inactivity (<duration> [reason] | offline on/off | off)", ModuleName: "AFK", DisAllowSudos: true},
+	}
+	AddHandlers(handlers, c)
+
+	c.On(&telegram.UpdateUserStatus{}, autoAFKStatusHandler)
+	StartAutoAFKChecker()
+}
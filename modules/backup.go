@@ -0,0 +1,366 @@
+package modules
+
+import (
+	"NovaUserbot/db"
+	"NovaUserbot/locales"
+	"NovaUserbot/logger"
+	"NovaUserbot/utils"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// Archive layout: "NOVABAK" magic, one flags byte, then either the gzipped
+// JSON document or, when encrypted, salt + nonce + AES-GCM ciphertext of it.
+const (
+	backupMagic       = "NOVABAK"
+	backupFormat      = 1
+	backupFlagCrypted = 1 << 0
+	backupExt         = ".nbak"
+	backupMaxSize     = 20 * 1024 * 1024
+	backupSaltSize    = 16
+	backupKDFRounds   = 200000
+	backupCheckEvery  = 10 * time.Minute
+	backupLastKey     = "LAST_BACKUP"
+)
+
+var errBackupPassword = errors.New("wrong password or corrupted archive")
+
+type backupDocument struct {
+	Format  int                 `json:"format"`
+	Created time.Time           `json:"created"`
+	Prefix  string              `json:"prefix"`
+	Keys    map[string]db.Entry `json:"keys"`
+}
+
+func backupKey(password string, salt []byte) ([]byte, error) {
+	return pbkdf2.Key(sha256.New, password, salt, backupKDFRounds, 32)
+}
+
+func encodeBackup(doc backupDocument, password string) ([]byte, error) {
+	var payload bytes.Buffer
+	gz := gzip.NewWriter(&payload)
+	if err := json.NewEncoder(gz).Encode(doc); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+
+	out := bytes.NewBufferString(backupMagic)
+	if password == "" {
+		out.WriteByte(0)
+		out.Write(payload.Bytes())
+		return out.Bytes(), nil
+	}
+
+	salt := make([]byte, backupSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	key, err := backupKey(password, salt)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	out.WriteByte(backupFlagCrypted)
+	out.Write(salt)
+	out.Write(nonce)
+	out.Write(gcm.Seal(nil, nonce, payload.Bytes(), []byte(backupMagic)))
+	return out.Bytes(), nil
+}
+
+func decodeBackup(data []byte, password string) (*backupDocument, error) {
+	if len(data) < len(backupMagic)+1 || string(data[:len(backupMagic)]) != backupMagic {
+		return nil, errors.New("not a Nova backup")
+	}
+	flags := data[len(backupMagic)]
+	payload := data[len(backupMagic)+1:]
+
+	if flags&backupFlagCrypted != 0 {
+		if password == "" {
+			return nil, errors.New("archive is encrypted, a password is required")
+		}
+		if len(payload) < backupSaltSize {
+			return nil, errBackupPassword
+		}
+		key, err := backupKey(password, payload[:backupSaltSize])
+		if err != nil {
+			return nil, err
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		rest := payload[backupSaltSize:]
+		if len(rest) < gcm.NonceSize() {
+			return nil, errBackupPassword
+		}
+		payload, err = gcm.Open(nil, rest[:gcm.NonceSize()], rest[gcm.NonceSize():], []byte(backupMagic))
+		if err != nil {
+			return nil, errBackupPassword
+		}
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("corrupted archive: %w", err)
+	}
+	defer gz.Close()
+
+	var doc backupDocument
+	if err := json.NewDecoder(io.LimitReader(gz, 8*backupMaxSize)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("corrupted archive: %w", err)
+	}
+	if doc.Keys == nil {
+		doc.Keys = make(map[string]db.Entry)
+	}
+	if doc.Format > backupFormat {
+		return nil, fmt.Errorf("archive format %d is newer than this version supports (%d)", doc.Format, backupFormat)
+	}
+	return &doc, nil
+}
+
+// writeBackupArchive exports the whole namespace to a file in the temp
+// directory and returns its path and the number of keys.
+func writeBackupArchive(password string) (string, int, error) {
+	entries, err := db.Dump()
+	if err != nil {
+		return "", 0, err
+	}
+	delete(entries, backupLastKey)
+	delete(entries, restartNoticeKey)
+
+	data, err := encodeBackup(backupDocument{
+		Format:  backupFormat,
+		Created: time.Now().UTC(),
+		Prefix:  db.Prefix(),
+		Keys:    entries,
+	}, password)
+	if err != nil {
+		return "", 0, err
+	}
+
+	path := filepath.Join(os.TempDir(), "nova_backup_"+time.Now().Format("20060102_150405")+backupExt)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return "", 0, err
+	}
+	return path, len(entries), nil
+}
+
+// sendBackup creates an archive and sends it through the assistant bot to the
+// chat the system log category is routed to.
+func sendBackup(password, caption string) (int, error) {
+	path, count, err := writeBackupArchive(password)
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(path)
+
+	route := logRouteFor(logger.CategorySystem)
+	peer, err := logClient().GetSendablePeer(route.ChatID)
+	if err != nil {
+		return 0, err
+	}
+
+	encrypted := locales.Tr("backup.plain")
+	if password != "" {
+		encrypted = locales.Tr("backup.encrypted")
+	}
+	caption += fmt.Sprintf(locales.Tr("backup.caption"), count, html.EscapeString(db.Prefix()), encrypted)
+
+	_, err = logClient().SendMedia(peer, path, &telegram.MediaOptions{Caption: caption, ForceDocument: true, FileName: filepath.Base(path), TopicID: route.TopicID})
+	if err != nil {
+		return 0, err
+	}
+	db.Set(backupLastKey, strconv.FormatInt(time.Now().Unix(), 10))
+	return count, nil
+}
+
+// Backup sends an archive of all persisted state to the log chat. A password
+// argument, or BACKUP_PASSWORD, encrypts it.
+func Backup(m *telegram.NewMessage) error {
+	password := strings.TrimSpace(m.Args())
+	if password == "" {
+		password = getVar("BACKUP_PASSWORD")
+	}
+
+	msg, _ := eOR(m, tr(m, "backup.creating"))
+	count, err := sendBackup(password, tr(m, "backup.manual_title"))
+	if err != nil {
+		_, err = msg.Edit(fmt.Sprintf(tr(m, "backup.error"), html.EscapeString(err.Error())))
+		return err
+	}
+
+	_, err = msg.Edit(fmt.Sprintf(tr(m, "backup.sent"), count))
+	return err
+}
+
+// Restore writes the keys of a replied archive back into the database. Keys
+// that are not in the archive are kept.
+func Restore(m *telegram.NewMessage) error {
+	reply, err := m.GetReplyMessage()
+	if err != nil || reply.File == nil || !strings.HasSuffix(reply.File.Name, backupExt) {
+		_, err := eOR(m, tr(m, "backup.restore_usage"))
+		return err
+	}
+	if reply.File.Size > backupMaxSize {
+		_, err := eOR(m, tr(m, "backup.too_big"))
+		return err
+	}
+
+	password := strings.TrimSpace(m.Args())
+	if password == "" {
+		password = getVar("BACKUP_PASSWORD")
+	}
+
+	msg, _ := eOR(m, tr(m, "backup.restoring"))
+
+	path, err := reply.Download(&telegram.DownloadOptions{FileName: filepath.Join(os.TempDir(), "restore_"+reply.File.Name)})
+	if err != nil {
+		_, err = msg.Edit(fmt.Sprintf(tr(m, "backup.error"), html.EscapeString(err.Error())))
+		return err
+	}
+	defer os.Remove(path)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		_, err = msg.Edit(fmt.Sprintf(tr(m, "backup.error"), html.EscapeString(err.Error())))
+		return err
+	}
+
+	doc, err := decodeBackup(data, password)
+	if err != nil {
+		_, err = msg.Edit(fmt.Sprintf(tr(m, "backup.error"), html.EscapeString(err.Error())))
+		return err
+	}
+
+	// Archives made before schema versioning hold version 0 data, so every
+	// migration has to run again after restoring them.
+	if _, ok := doc.Keys[db.SchemaVersionKey]; !ok {
+		doc.Keys[db.SchemaVersionKey] = db.Entry{Type: "string", Value: "0"}
+	}
+	if v, _ := strconv.Atoi(doc.Keys[db.SchemaVersionKey].Value); v > db.LatestSchema() {
+		_, err = msg.Edit(fmt.Sprintf(tr(m, "backup.schema_too_new"), v, db.LatestSchema()))
+		return err
+	}
+
+	restored, err := db.Restore(doc.Keys)
+	if err == nil {
+		err = db.Migrate()
+	}
+	if err != nil {
+		_, err = msg.Edit(fmt.Sprintf(tr(m, "backup.error"), html.EscapeString(err.Error())))
+		return err
+	}
+	reloadStoredState()
+
+	warning := ""
+	if err := db.Verify(storedSchemas); err != nil {
+		warning = fmt.Sprintf(tr(m, "backup.corrupt_warning"), html.EscapeString(err.Error()))
+	}
+	logger.LogEventf(logger.CategorySystem, "restore", "Restored %d keys from a backup made %s", restored, doc.Created.Format(time.RFC3339))
+
+	_, err = msg.Edit(fmt.Sprintf(tr(m, "backup.restored"), restored, doc.Created.Format("2006-01-02 15:04 MST")) + warning)
+	return err
+}
+
+// reloadStoredState refreshes everything that is cached in memory from the
+// database, so a restore or wipe takes effect without a restart.
+func reloadStoredState() {
+	locales.GetInstance().ReloadPreferences()
+	loadLogRoutes()
+	loadMsgLoggerConfig()
+	loadTagKeywords()
+	resetFloodConfigs()
+
+	d := getAFK()
+	if d.IsAFK {
+		scheduleAFKEnd(d.Until)
+	} else {
+		scheduleAFKEnd(time.Time{})
+	}
+}
+
+func getBackupInterval() time.Duration {
+	hours, err := strconv.Atoi(getVar("BACKUP_INTERVAL"))
+	if err != nil || hours <= 0 {
+		return 0
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+func runBackupScheduler(ctx context.Context) {
+	ticker := time.NewTicker(backupCheckEvery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		interval := getBackupInterval()
+		if interval == 0 {
+			continue
+		}
+
+		last := time.Unix(utils.StringToInt64(db.Get(backupLastKey)), 0)
+		if time.Since(last) < interval {
+			continue
+		}
+
+		if _, err := sendBackup(getVar("BACKUP_PASSWORD"), locales.Tr("backup.scheduled_title")); err != nil {
+			logger.Errorf("Scheduled backup failed: %v", err)
+		}
+	}
+}
+
+func LoadBackupModule(c *telegram.Client) {
+	RegisterVars([]*ConfigVar{
+		{Key: "BACKUP_INTERVAL", Module: "Backup", Type: VarInt, Default: "0", Description: "Hours between automatic backups to the log chat, 0 disables them", Validate: intRange(0, 24*30)},
+		{Key: "BACKUP_PASSWORD", Module: "Backup", Description: "Password used to encrypt backups when none is given", Secret: true},
+	})
+
+	handlers := []*Handler{
+		{ModuleName: "Backup", Command: "backup", Description: "Send a backup of all data to the log chat [password]", Func: Backup, DisAllowSudos: true},
+		{ModuleName: "Backup", Command: "restore", Description: "Restore a replied .nbak backup [password]", Func: Restore, DisAllowSudos: true},
+	}
+	AddHandlers(handlers, c)
+
+	startWorker("backup scheduler", runBackupScheduler)
+}
@@ -6,6 +6,8 @@ import (
 	"NovaUserbot/logger"
 	"encoding/json"
 	"fmt"
+	"html"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -14,54 +16,83 @@ import (
 	"github.com/amarnathcjd/gogram/telegram"
 )
 
+const (
+	banGuardLogPrefix = "BANGUARD_LOG:"
+	banGuardDemote    = "demote"
+	banGuardDemoteBan = "demote_ban"
+	banGuardNotify    = "notify"
+)
+
+// BanGuardConfig stops admins who ban, kick or demote Limit members within
+// Duration seconds. Action is demote, demote_ban or notify, demote when
+// unset, and unless NoRestore is set the victims are restored afterwards.
 type BanGuardConfig struct {
-	Limit    int   `json:"limit"`
-	Duration int64 `json:"duration"`
-	Enabled  bool  `json:"enabled"`
+	Limit     int    `json:"limit"`
+	Duration  int64  `json:"duration"`
+	Enabled   bool   `json:"enabled"`
+	Action    string `json:"action,omitempty"`
+	NoRestore bool   `json:"no_restore,omitempty"`
 }
 
-type BanCache struct {
-	sync.Mutex
-	cache     map[int64]map[int64]int
-	timestamps map[int64]map[int64]time.Time
+func (c *BanGuardConfig) action() string {
+	if c.Action == "" {
+		return banGuardDemote
+	}
+	return c.Action
 }
 
-var banCache = BanCache{
-	cache:     make(map[int64]map[int64]int),
-	timestamps: make(map[int64]map[int64]time.Time),
+// GuardedAction is a ban, kick or demotion done by an admin. They are kept
+// for the BanGuard window so they can be counted and reverted; Rights and
+// Rank are what a demoted admin had.
+type GuardedAction struct {
+	Kind   string                    `json:"kind"`
+	UserID int64                     `json:"user_id"`
+	Name   string                    `json:"name,omitempty"`
+	Time   time.Time                 `json:"time"`
+	Rights *telegram.ChatAdminRights `json:"rights,omitempty"`
+	Rank   string                    `json:"rank,omitempty"`
 }
 
-func addUserToCache(chatID int64, adminID int64, banLimit int, duration int64) bool {
-	banCache.Lock()
-	defer banCache.Unlock()
+var banGuardMutex sync.Mutex
 
-	now := time.Now()
+func banGuardLogKey(chatID, adminID int64) string {
+	return fmt.Sprintf("%s%d:%d", banGuardLogPrefix, chatID, adminID)
+}
 
-	if banCache.cache[chatID] == nil {
-		banCache.cache[chatID] = make(map[int64]int)
-	}
-	if banCache.timestamps[chatID] == nil {
-		banCache.timestamps[chatID] = make(map[int64]time.Time)
+// recordGuardedAction adds an admin's action to the ones within the window
+// and returns them. They are stored with the window as expiry, so the count
+// survives restarts without piling up.
+func recordGuardedAction(chatID, adminID int64, action GuardedAction, window time.Duration) []GuardedAction {
+	banGuardMutex.Lock()
+	defer banGuardMutex.Unlock()
+
+	key := banGuardLogKey(chatID, adminID)
+	var stored []GuardedAction
+	if data := db.Get(key); data != "" {
+		if err := json.Unmarshal([]byte(data), &stored); err != nil {
+			logger.Errorf("Corrupted BanGuard log of %d in %d: %v", adminID, chatID, err)
+		}
 	}
 
-	if lastTime, exists := banCache.timestamps[chatID][adminID]; exists {
-		if now.Sub(lastTime) > time.Duration(duration)*time.Second {
-			banCache.cache[chatID][adminID] = 0
+	var actions []GuardedAction
+	for _, a := range stored {
+		if time.Since(a.Time) <= window {
+			actions = append(actions, a)
 		}
 	}
+	actions = append(actions, action)
 
-	banCache.cache[chatID][adminID]++
-	banCache.timestamps[chatID][adminID] = now
-	return banCache.cache[chatID][adminID] >= banLimit
+	data, _ := json.Marshal(actions)
+	if err := db.SetEx(key, string(data), window); err != nil {
+		logger.Errorf("Failed to save BanGuard log of %d in %d: %v", adminID, chatID, err)
+	}
+	return actions
 }
 
-func resetUserBanCount(chatID int64, adminID int64) {
-	banCache.Lock()
-	defer banCache.Unlock()
-
-	if banCache.cache[chatID] != nil {
-		banCache.cache[chatID][adminID] = 0
-	}
+func clearGuardedActions(chatID, adminID int64) {
+	banGuardMutex.Lock()
+	defer banGuardMutex.Unlock()
+	db.Del(banGuardLogKey(chatID, adminID))
 }
 
 func getBanGuardConfig(chatID int64) *BanGuardConfig {
@@ -72,6 +103,7 @@ func getBanGuardConfig(chatID int64) *BanGuardConfig {
 
 	var config BanGuardConfig
 	if err := json.Unmarshal([]byte(data), &config); err != nil {
+		logger.Errorf("Corrupted BanGuard config for %d: %v", chatID, err)
 		return nil
 	}
 	return &config
@@ -91,52 +123,52 @@ func deleteBanGuardConfig(chatID int64) error {
 
 func setBanGuardLimit(m *telegram.NewMessage) error {
 	if !m.IsGroup() {
-		_, err := eOR(m, locales.Tr("banguard.groups_only"))
+		_, err := eOR(m, tr(m, "banguard.groups_only"))
 		return err
 	}
 
 	args := strings.Fields(m.Args())
 	if len(args) < 2 {
-		_, err := eOR(m, locales.Tr("banguard.usage_gconfig"))
+		_, err := eOR(m, tr(m, "banguard.usage_gconfig"))
 		return err
 	}
 
 	duration, err := time.ParseDuration(args[0])
-	if err != nil {
-		_, err := eOR(m, locales.Tr("banguard.invalid_duration"))
+	if err != nil || duration < time.Second {
+		_, err := eOR(m, tr(m, "banguard.invalid_duration"))
 		return err
 	}
 
 	userLimit, err := strconv.Atoi(args[1])
 	if err != nil || userLimit <= 0 {
-		_, err := eOR(m, locales.Tr("banguard.invalid_limit"))
+		_, err := eOR(m, tr(m, "banguard.invalid_limit"))
 		return err
 	}
 
-	config := &BanGuardConfig{
-		Limit:    userLimit,
-		Duration: int64(duration.Seconds()),
-		Enabled:  true,
+	config := getBanGuardConfig(m.ChatID())
+	if config == nil {
+		config = &BanGuardConfig{}
 	}
+	config.Limit, config.Duration, config.Enabled = userLimit, int64(duration.Seconds()), true
 
 	if err := setBanGuardConfig(m.ChatID(), config); err != nil {
-		_, err := eOR(m, locales.Tr("banguard.config_error"))
+		_, err := eOR(m, tr(m, "banguard.config_error"))
 		return err
 	}
 
-	_, err = eOR(m, fmt.Sprintf(locales.Tr("banguard.limits_set"), duration, userLimit))
+	_, err = eOR(m, fmt.Sprintf(tr(m, "banguard.limits_set"), duration, userLimit))
 	return err
 }
 
 func toggleBanGuard(m *telegram.NewMessage) error {
 	if !m.IsGroup() {
-		_, err := eOR(m, locales.Tr("banguard.groups_only"))
+		_, err := eOR(m, tr(m, "banguard.groups_only"))
 		return err
 	}
 
 	args := strings.ToLower(strings.TrimSpace(m.Args()))
 	if args != "on" && args != "off" {
-		_, err := eOR(m, locales.Tr("banguard.usage_gtoggle"))
+		_, err := eOR(m, tr(m, "banguard.usage_gtoggle"))
 		return err
 	}
 
@@ -154,51 +186,192 @@ func toggleBanGuard(m *telegram.NewMessage) error {
 		}
 
 		if err := setBanGuardConfig(m.ChatID(), config); err != nil {
-			_, err := eOR(m, locales.Tr("banguard.config_error"))
+			_, err := eOR(m, tr(m, "banguard.config_error"))
 			return err
 		}
 
 		if config.Limit == 5 && config.Duration == 10 {
-			_, err := eOR(m, locales.Tr("banguard.enabled_default"))
+			_, err := eOR(m, tr(m, "banguard.enabled_default"))
 			return err
 		}
-		_, err := eOR(m, locales.Tr("banguard.enabled"))
+		_, err := eOR(m, tr(m, "banguard.enabled"))
 		return err
 	}
 
 	if config == nil {
-		_, err := eOR(m, locales.Tr("banguard.not_configured"))
+		_, err := eOR(m, tr(m, "banguard.not_configured"))
 		return err
 	}
 
 	if err := deleteBanGuardConfig(m.ChatID()); err != nil {
-		_, err := eOR(m, locales.Tr("banguard.config_error"))
+		_, err := eOR(m, tr(m, "banguard.config_error"))
 		return err
 	}
 
-	_, err := eOR(m, locales.Tr("banguard.disabled"))
+	_, err := eOR(m, tr(m, "banguard.disabled"))
 	return err
 }
 
 func banGuardStatus(m *telegram.NewMessage) error {
 	if !m.IsGroup() {
-		_, err := eOR(m, locales.Tr("banguard.groups_only"))
+		_, err := eOR(m, tr(m, "banguard.groups_only"))
 		return err
 	}
 
 	config := getBanGuardConfig(m.ChatID())
 	if config == nil || !config.Enabled {
-		_, err := eOR(m, locales.Tr("banguard.status_disabled"))
+		_, err := eOR(m, tr(m, "banguard.status_disabled"))
 		return err
 	}
 
+	restore := tr(m, "banguard.restore_on")
+	if config.NoRestore {
+		restore = tr(m, "banguard.restore_off")
+	}
 	duration := time.Duration(config.Duration) * time.Second
-	_, err := eOR(m, fmt.Sprintf(locales.Tr("banguard.status_enabled"), config.Limit, duration))
+	_, err := eOR(m, fmt.Sprintf(tr(m, "banguard.status_enabled"), config.Limit, duration, config.action(), restore))
+	return err
+}
+
+func setBanGuardAction(m *telegram.NewMessage) error {
+	if !m.IsGroup() {
+		_, err := eOR(m, tr(m, "banguard.groups_only"))
+		return err
+	}
+
+	action := strings.ToLower(strings.TrimSpace(m.Args()))
+	if !slices.Contains([]string{banGuardDemote, banGuardDemoteBan, banGuardNotify}, action) {
+		_, err := eOR(m, tr(m, "banguard.usage_gaction"))
+		return err
+	}
+
+	config := getBanGuardConfig(m.ChatID())
+	if config == nil {
+		_, err := eOR(m, tr(m, "banguard.not_configured"))
+		return err
+	}
+
+	config.Action = action
+	if err := setBanGuardConfig(m.ChatID(), config); err != nil {
+		_, err := eOR(m, tr(m, "banguard.config_error"))
+		return err
+	}
+
+	_, err := eOR(m, fmt.Sprintf(tr(m, "banguard.action_set"), action))
+	return err
+}
+
+func toggleBanGuardRestore(m *telegram.NewMessage) error {
+	if !m.IsGroup() {
+		_, err := eOR(m, tr(m, "banguard.groups_only"))
+		return err
+	}
+
+	args := strings.ToLower(strings.TrimSpace(m.Args()))
+	if args != "on" && args != "off" {
+		_, err := eOR(m, tr(m, "banguard.usage_grestore"))
+		return err
+	}
+
+	config := getBanGuardConfig(m.ChatID())
+	if config == nil {
+		_, err := eOR(m, tr(m, "banguard.not_configured"))
+		return err
+	}
+
+	config.NoRestore = args == "off"
+	if err := setBanGuardConfig(m.ChatID(), config); err != nil {
+		_, err := eOR(m, tr(m, "banguard.config_error"))
+		return err
+	}
+
+	if config.NoRestore {
+		_, err := eOR(m, tr(m, "banguard.restore_disabled"))
+		return err
+	}
+	_, err := eOR(m, tr(m, "banguard.restore_enabled"))
 	return err
 }
 
+// guardedAction tells which of the actions BanGuard watches an update is. An
+// admin who is banned or kicked counts as that, keeping their rights so they
+// can be promoted again. Restrictions that still let the member read the chat
+// are not bans.
+func guardedAction(p *telegram.ParticipantUpdate) (GuardedAction, bool) {
+	action := GuardedAction{UserID: p.UserID(), Time: time.Now()}
+	if p.User != nil {
+		action.Name = strings.TrimSpace(p.User.FirstName + " " + p.User.LastName)
+	}
+	if p.Old == nil || p.ActorID() == p.UserID() {
+		return action, false
+	}
+
+	switch old := p.Old.(type) {
+	case *telegram.ChannelParticipantAdmin:
+		action.Rights, action.Rank = old.AdminRights, old.Rank
+	case *telegram.ChannelParticipantObj, *telegram.ChannelParticipantSelf:
+	default:
+		return action, false
+	}
+
+	switch n := p.New.(type) {
+	case *telegram.ChannelParticipantBanned:
+		if n.BannedRights == nil || !n.BannedRights.ViewMessages {
+			return action, false
+		}
+		action.Kind = "ban"
+	case *telegram.ChannelParticipantLeft, nil:
+		action.Kind = "kick"
+	case *telegram.ChannelParticipantAdmin, *telegram.ChannelParticipantCreator:
+		return action, false
+	default:
+		if action.Rights == nil {
+			return action, false
+		}
+		action.Kind = "demote"
+	}
+	return action, true
+}
+
+// restoreGuardedActions reverts the actions of a rogue admin: banned and
+// kicked members are unbanned, and admins among them or demoted admins get
+// their rights back. It returns a report line per action.
+func restoreGuardedActions(channel *telegram.Channel, actions []GuardedAction) string {
+	var report strings.Builder
+	for _, a := range actions {
+		var err error
+		key := "banguard.reverted_ban"
+		switch a.Kind {
+		case "demote":
+			key = "banguard.reverted_demote"
+		case "kick":
+			key = "banguard.reverted_kick"
+		}
+
+		if a.Kind != "demote" {
+			_, err = client.EditBanned(channel, a.UserID, &telegram.BannedOptions{Unban: true})
+		}
+		if err == nil && (a.Kind == "demote" || a.Rights != nil) {
+			if a.Rights == nil {
+				err = fmt.Errorf("admin rights unknown")
+			} else {
+				_, err = client.EditAdmin(channel, a.UserID, &telegram.AdminOptions{IsAdmin: true, Rights: a.Rights, Rank: a.Rank})
+			}
+		}
+
+		name := html.EscapeString(orNone(a.Name))
+		if err != nil {
+			fmt.Fprintf(&report, locales.Tr("banguard.revert_failed"), a.Kind, a.UserID, name, html.EscapeString(err.Error()))
+			continue
+		}
+		fmt.Fprintf(&report, locales.Tr(key), a.UserID, name)
+	}
+	return report.String()
+}
+
 func UserJoinHandle(p *telegram.ParticipantUpdate) error {
-	if !p.IsBanned() && !p.IsKicked() {
+	action, ok := guardedAction(p)
+	if !ok || p.Channel == nil {
 		return nil
 	}
 
@@ -214,41 +387,51 @@ func UserJoinHandle(p *telegram.ParticipantUpdate) error {
 		return nil
 	}
 
-	exceeded := addUserToCache(chatID, actorID, config.Limit, config.Duration)
-	if !exceeded {
-		return nil
-	}
-
-	_, err := p.Demote()
-	if err != nil {
-		logger.Errorf("BanGuard: Failed to demote admin %d in chat %d: %v", actorID, chatID, err)
+	window := time.Duration(config.Duration) * time.Second
+	actions := recordGuardedAction(chatID, actorID, action, window)
+	if len(actions) < config.Limit {
 		return nil
 	}
-
-	banCount := config.Limit
-	resetUserBanCount(chatID, actorID)
+	clearGuardedActions(chatID, actorID)
 
 	actorName := "Unknown"
 	if p.Actor != nil {
-		actorName = p.Actor.FirstName
-		if p.Actor.LastName != "" {
-			actorName += " " + p.Actor.LastName
+		actorName = strings.TrimSpace(p.Actor.FirstName + " " + p.Actor.LastName)
+	}
+	chatTitle := p.Channel.Title
+
+	mode := config.action()
+	if mode != banGuardNotify {
+		if _, err := client.EditAdmin(p.Channel, actorID, &telegram.AdminOptions{IsAdmin: false}); err != nil {
+			logger.Errorf("BanGuard: Failed to demote admin %d in chat %d: %v", actorID, chatID, err)
+			logTo(logger.CategorySecurity, fmt.Sprintf(locales.Tr("banguard.demote_failed"), actorID))
+			return nil
+		}
+	}
+	notice := "banguard.user_demoted"
+	if mode == banGuardDemoteBan {
+		notice = "banguard.user_banned"
+		if _, err := client.EditBanned(p.Channel, actorID, &telegram.BannedOptions{Ban: true}); err != nil {
+			logger.Errorf("BanGuard: Failed to ban admin %d in chat %d: %v", actorID, chatID, err)
+			notice = "banguard.user_demoted"
 		}
 	}
 
-	chatTitle := "Unknown"
-	if p.Channel != nil {
-		chatTitle = p.Channel.Title
+	var report string
+	if mode != banGuardNotify && !config.NoRestore {
+		report = locales.Tr("banguard.log_reverted") + restoreGuardedActions(p.Channel, actions)
 	}
 
-	peer, err := client.GetSendablePeer(chatID)
-	if err == nil {
-		client.SendMessage(peer, fmt.Sprintf(locales.Tr("banguard.user_demoted"), actorID, actorName), &telegram.SendOptions{ParseMode: "HTML"})
+	if mode != banGuardNotify {
+		peer, err := client.GetSendablePeer(chatID)
+		if err == nil {
+			client.SendMessage(peer, fmt.Sprintf(locales.Tr(notice), actorID, html.EscapeString(actorName)), &telegram.SendOptions{ParseMode: "HTML"})
+		}
 	}
 
-	logMessage(fmt.Sprintf(locales.Tr("banguard.log_demoted"), chatTitle, chatID, actorID, actorName, actorID, banCount))
+	logTo(logger.CategorySecurity, fmt.Sprintf(locales.Tr("banguard.log_action"), html.EscapeString(chatTitle), chatID, actorID, html.EscapeString(actorName), len(actions), window, mode)+report)
 
-	logger.Infof("BanGuard: Demoted admin %d (%s) in chat %d for exceeding ban limit", actorID, actorName, chatID)
+	logger.Infof("BanGuard: %s for admin %d (%s) in chat %d after %d actions", mode, actorID, actorName, chatID, len(actions))
 	return nil
 }
 
@@ -257,6 +440,8 @@ func LoadBanGuardModule(c *telegram.Client) {
 		{Command: "gconfig", Func: setBanGuardLimit, Description: "Set BanGuard limits (duration limit)", ModuleName: "BanGuard", DisAllowSudos: true},
 		{Command: "gtoggle", Func: toggleBanGuard, Description: "Toggle BanGuard on/off", ModuleName: "BanGuard", DisAllowSudos: true},
 		{Command: "gstatus", Func: banGuardStatus, Description: "Check BanGuard status", ModuleName: "BanGuard", DisAllowSudos: true},
+		{Command: "gaction", Func: setBanGuardAction, Description: "Set what BanGuard does to rogue admins (demote, demote_ban, notify)", ModuleName: "BanGuard", DisAllowSudos: true},
+		{Command: "grestore", Func: toggleBanGuardRestore, Description: "Toggle restoring the victims of rogue admins on/off", ModuleName: "BanGuard", DisAllowSudos: true},
 	}
 	AddHandlers(handlers, c)
 
@@ -1,7 +1,6 @@
 package modules
 
 import (
-	"NovaUserbot/locales"
 	"NovaUserbot/logger"
 	"NovaUserbot/utils"
 	"fmt"
@@ -60,7 +59,7 @@ func geminiAi(m *telegram.NewMessage) error {
 	var image string
 	args := m.Args()
 
-	msg, _ := eOR(m, locales.Tr("chatbot.fetching"))
+	msg, _ := eOR(m, tr(m, "chatbot.fetching"))
 
 	if m.IsReply() {
 		reply, _ := m.GetReplyMessage()
@@ -74,21 +73,25 @@ func geminiAi(m *telegram.NewMessage) error {
 	}
 
 	if args == "" {
-		_, err := msg.Edit(locales.Tr("chatbot.no_query"))
+		_, err := msg.Edit(tr(m, "chatbot.no_query"))
 		return err
 	}
 
 	result, err := utils.ProcessGemini(image, args)
 	if err != nil {
-		_, err = msg.Edit(locales.Tr("chatbot.error"))
+		_, err = msg.Edit(tr(m, "chatbot.error"))
 		return err
 	}
 
-	_, err = msg.Edit(fmt.Sprintf(locales.Tr("chatbot.result"), args, result), &telegram.SendOptions{ParseMode: "Markdown"})
+	_, err = msg.Edit(fmt.Sprintf(tr(m, "chatbot.result"), args, result), &telegram.SendOptions{ParseMode: "Markdown"})
 	return err
 }
 
 func LoadChatBotHandler(c *telegram.Client) {
+	RegisterVars([]*ConfigVar{
+		{Key: "GEMINI_API_KEY", Module: "ChatBot", Description: "Google Gemini API key for .ai and the PM assistant", Secret: true},
+	})
+
 	handlers := []*Handler{
 		{ModuleName: "ChatBot", Command: "ai", Description: "Query Gemini AI", Func: geminiAi},
 	}
@@ -0,0 +1,493 @@
+package modules
+
+import (
+	"NovaUserbot/db"
+	"NovaUserbot/locales"
+	"NovaUserbot/logger"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+const (
+	cloneJobsKey   = "CLONE_JOBS"
+	cloneMapPrefix = "CLONE_MAP:"
+	// cloneNextIDKey counts up job IDs, so an ID is never reused and a new
+	// job can't pick up the reply map of an old one.
+	cloneNextIDKey = "CLONE_NEXT_ID"
+	// cloneMaxErrors is how many fetches in a row may fail before a job is
+	// given up, so an unreachable source doesn't block the queue.
+	cloneMaxErrors = 5
+	// cloneWindow is how many message IDs are fetched per request; the source
+	// is walked oldest first in windows of IDs.
+	cloneWindow = 100
+)
+
+// CloneJob replays a chat's messages into a channel. LastID is the last
+// source message handled, so a restart resumes right after it.
+type CloneJob struct {
+	ID         int       `json:"id"`
+	Source     int64     `json:"source"`
+	SourceName string    `json:"source_name"`
+	Dest       int64     `json:"dest"`
+	DestName   string    `json:"dest_name"`
+	LastID     int32     `json:"last_id"`
+	EndID      int32     `json:"end_id"`
+	Copied     int       `json:"copied"`
+	Failed     int       `json:"failed"`
+	Errors     int       `json:"errors,omitempty"`
+	Started    time.Time `json:"started"`
+}
+
+var cloneMutex sync.Mutex
+
+func getCloneJobs() []CloneJob {
+	data := db.Get(cloneJobsKey)
+	if data == "" {
+		return nil
+	}
+	var jobs []CloneJob
+	if err := json.Unmarshal([]byte(data), &jobs); err != nil {
+		logger.Errorf("Failed to parse clone jobs: %v", err)
+		return nil
+	}
+	return jobs
+}
+
+func saveCloneJobs(jobs []CloneJob) error {
+	if len(jobs) == 0 {
+		return db.Del(cloneJobsKey)
+	}
+	data, err := json.Marshal(jobs)
+	if err != nil {
+		return err
+	}
+	return db.Set(cloneJobsKey, string(data))
+}
+
+// updateCloneJob stores the progress of job and adds copied to its reply map.
+// It returns false when the job was cancelled in the meantime, leaving the
+// map of a cancelled job deleted.
+func updateCloneJob(job CloneJob, copied map[int32]int32) bool {
+	cloneMutex.Lock()
+	defer cloneMutex.Unlock()
+
+	jobs := getCloneJobs()
+	i := slices.IndexFunc(jobs, func(j CloneJob) bool { return j.ID == job.ID })
+	if i < 0 {
+		return false
+	}
+	jobs[i] = job
+	saveCloneJobs(jobs)
+	addCloneMap(job.ID, copied)
+	return true
+}
+
+func cloneJobExists(id int) bool {
+	cloneMutex.Lock()
+	defer cloneMutex.Unlock()
+	return slices.ContainsFunc(getCloneJobs(), func(j CloneJob) bool { return j.ID == id })
+}
+
+// nextCloneJobID returns an ID no job had before. Jobs queued before the
+// counter existed are skipped over.
+func nextCloneJobID(jobs []CloneJob) (int, error) {
+	id, err := db.Incr(cloneNextIDKey)
+	if err != nil {
+		return 0, err
+	}
+	highest := 0
+	for _, j := range jobs {
+		highest = max(highest, j.ID)
+	}
+	if int(id) <= highest {
+		id = int64(highest + 1)
+		if err := db.Set(cloneNextIDKey, strconv.FormatInt(id, 10)); err != nil {
+			return 0, err
+		}
+	}
+	return int(id), nil
+}
+
+func removeCloneJob(id int) (CloneJob, bool) {
+	cloneMutex.Lock()
+	defer cloneMutex.Unlock()
+
+	jobs := getCloneJobs()
+	i := slices.IndexFunc(jobs, func(j CloneJob) bool { return j.ID == id })
+	if i < 0 {
+		return CloneJob{}, false
+	}
+	job := jobs[i]
+	saveCloneJobs(slices.Delete(jobs, i, i+1))
+	db.Del(cloneMapPrefix + strconv.Itoa(id))
+	return job, true
+}
+
+// cloneMap maps source message IDs to their copies, so replies can point at
+// the copied message. It is a hash, so every copy adds one field.
+func getCloneMap(id int) map[int32]int32 {
+	ids := make(map[int32]int32)
+	fields, _ := db.HGetAll(cloneMapPrefix + strconv.Itoa(id))
+	for src, dst := range fields {
+		srcID, err1 := strconv.ParseInt(src, 10, 32)
+		dstID, err2 := strconv.ParseInt(dst, 10, 32)
+		if err1 == nil && err2 == nil {
+			ids[int32(srcID)] = int32(dstID)
+		}
+	}
+	return ids
+}
+
+func addCloneMap(id int, copied map[int32]int32) {
+	fields := make(map[string]string, len(copied))
+	for src, dst := range copied {
+		fields[strconv.Itoa(int(src))] = strconv.Itoa(int(dst))
+	}
+	db.HSet(cloneMapPrefix+strconv.Itoa(id), fields)
+}
+
+// resolveClonePeer resolves a username, link or ID to a chat ID ResolvePeer
+// accepts later, and the chat's name.
+func resolveClonePeer(arg string) (int64, string, telegram.InputPeer, error) {
+	arg = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(arg), "https://t.me/"), "@")
+	var ref any = arg
+	if id, err := strconv.ParseInt(arg, 10, 64); err == nil {
+		ref = id
+	}
+
+	peer, err := client.ResolvePeer(ref)
+	if err != nil {
+		return 0, "", nil, err
+	}
+	switch p := peer.(type) {
+	case *telegram.InputPeerChannel:
+		if ch, err := client.GetChannel(p.ChannelID); err == nil {
+			return p.ChannelID, ch.Title, peer, nil
+		}
+		return p.ChannelID, arg, peer, nil
+	case *telegram.InputPeerChat:
+		if chat, err := client.GetChat(p.ChatID); err == nil {
+			return p.ChatID, chat.Title, peer, nil
+		}
+		return p.ChatID, arg, peer, nil
+	case *telegram.InputPeerUser:
+		if user, err := client.GetUser(p.UserID); err == nil {
+			return p.UserID, strings.TrimSpace(user.FirstName + " " + user.LastName), peer, nil
+		}
+		return p.UserID, arg, peer, nil
+	}
+	return 0, "", nil, fmt.Errorf("unsupported chat")
+}
+
+func getCloneDelay() time.Duration {
+	seconds, err := strconv.Atoi(getVar("CLONE_DELAY"))
+	if err != nil || seconds <= 0 {
+		seconds = 3
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// fetchCloneWindow returns the messages with IDs in
+// (LastID, LastID+cloneWindow] oldest first, leaving out service messages.
+func fetchCloneWindow(job CloneJob) ([]telegram.NewMessage, error) {
+	msgs, err := client.GetMessages(job.Source, &telegram.SearchOption{
+		MinID: job.LastID,
+		MaxID: job.LastID + cloneWindow + 1,
+		Limit: cloneWindow,
+	})
+	if err != nil {
+		return nil, err
+	}
+	msgs = slices.DeleteFunc(msgs, func(msg telegram.NewMessage) bool { return msg.Action != nil })
+	slices.SortFunc(msgs, func(a, b telegram.NewMessage) int { return int(a.ID - b.ID) })
+	return msgs, nil
+}
+
+// groupAlbums splits msgs into sends, keeping the items of an album together.
+func groupAlbums(msgs []telegram.NewMessage) [][]telegram.NewMessage {
+	var groups [][]telegram.NewMessage
+	for _, msg := range msgs {
+		if n := len(groups); n > 0 && msg.Message.GroupedID != 0 && groups[n-1][0].Message.GroupedID == msg.Message.GroupedID {
+			groups[n-1] = append(groups[n-1], msg)
+			continue
+		}
+		groups = append(groups, []telegram.NewMessage{msg})
+	}
+	return groups
+}
+
+// reuploadMessage copies a message from a chat that forbids forwarding by
+// sending its text again and uploading its media.
+func reuploadMessage(dest int64, msg *telegram.NewMessage, replyID int32) (int32, error) {
+	if !msg.IsMedia() || msg.MediaType() == "web_page" {
+		sent, err := client.SendMessage(dest, msg.Text(), &telegram.SendOptions{Entities: msg.Message.Entities, ReplyID: replyID})
+		if err != nil {
+			return 0, err
+		}
+		return sent.ID, nil
+	}
+
+	name := fmt.Sprintf("clone_%d", msg.ID)
+	if msg.File != nil {
+		name += "_" + filepath.Base(msg.File.Name) + msg.File.Ext
+	}
+	file, err := msg.Download(&telegram.DownloadOptions{FileName: filepath.Join(os.TempDir(), name)})
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(file)
+
+	sent, err := client.SendMedia(dest, file, &telegram.MediaOptions{Caption: msg.Text(), Entities: msg.Message.Entities, ReplyID: replyID})
+	if err != nil {
+		return 0, err
+	}
+	return sent.ID, nil
+}
+
+// copyGroup sends one message or album to the destination without the
+// forward header and records the IDs of the copies.
+func copyGroup(job *CloneJob, group []telegram.NewMessage, ids map[int32]int32) error {
+	replyID := ids[group[0].ReplyToMsgID()]
+	srcIDs := make([]int32, len(group))
+	for i, msg := range group {
+		srcIDs[i] = msg.ID
+	}
+
+	sent, err := client.Forward(job.Dest, job.Source, srcIDs, &telegram.ForwardOptions{HideAuthor: true, ReplyID: replyID})
+	if telegram.MatchError(err, "FORWARDS_RESTRICTED") {
+		for i := range group {
+			id, err := reuploadMessage(job.Dest, &group[i], replyID)
+			if err != nil {
+				return err
+			}
+			ids[group[i].ID] = id
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for i := range min(len(sent), len(group)) {
+		ids[group[i].ID] = sent[i].ID
+	}
+	return nil
+}
+
+// failCloneJob counts a failed fetch and gives the job up, reporting it,
+// once cloneMaxErrors fetches in a row failed.
+func failCloneJob(job CloneJob, err error) {
+	job.Errors++
+	if job.Errors < cloneMaxErrors {
+		updateCloneJob(job, nil)
+		return
+	}
+	if failed, ok := removeCloneJob(job.ID); ok {
+		logTo(logger.CategoryError, fmt.Sprintf(locales.Tr("export.clone_failed"), failed.ID, html.EscapeString(failed.SourceName), html.EscapeString(failed.DestName), failed.Copied, html.EscapeString(err.Error())))
+	}
+}
+
+// runCloneJob copies the source of job from LastID up to EndID. It returns
+// false when it stopped early, because of ctx, a cancel or an error.
+func runCloneJob(ctx context.Context, job CloneJob) bool {
+	ids := getCloneMap(job.ID)
+	for job.LastID < job.EndID {
+		windowEnd := min(job.LastID+cloneWindow, job.EndID)
+		msgs, err := fetchCloneWindow(job)
+		if wait := telegram.GetFloodWait(err); wait > 0 {
+			if !sleepCtx(ctx, time.Duration(wait)*time.Second) {
+				return false
+			}
+			continue
+		}
+		if err != nil {
+			logger.Warnf("Clone #%d: could not fetch messages: %v", job.ID, err)
+			failCloneJob(job, err)
+			return false
+		}
+		job.Errors = 0
+
+		for _, group := range groupAlbums(msgs) {
+			if !sleepCtx(ctx, getCloneDelay()) || !cloneJobExists(job.ID) {
+				return false
+			}
+
+			err := copyGroup(&job, group, ids)
+			for wait := telegram.GetFloodWait(err); wait > 0; wait = telegram.GetFloodWait(err) {
+				logger.Warnf("Clone #%d: flood wait of %ds", job.ID, wait)
+				if !sleepCtx(ctx, time.Duration(wait)*time.Second) || !cloneJobExists(job.ID) {
+					return false
+				}
+				err = copyGroup(&job, group, ids)
+			}
+			if err != nil {
+				logger.Warnf("Clone #%d: could not copy message %d: %v", job.ID, group[0].ID, err)
+				job.Failed += len(group)
+			} else {
+				job.Copied += len(group)
+			}
+
+			copied := make(map[int32]int32, len(group))
+			for _, msg := range group {
+				if id, ok := ids[msg.ID]; ok {
+					copied[msg.ID] = id
+				}
+			}
+			job.LastID = group[len(group)-1].ID
+			if !updateCloneJob(job, copied) {
+				return false
+			}
+		}
+
+		job.LastID = windowEnd
+		if !updateCloneJob(job, nil) {
+			return false
+		}
+	}
+	return true
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// runCloneJobs works through the stored jobs one at a time, picking up where
+// they stopped before a restart.
+func runCloneJobs(ctx context.Context) {
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		jobs := getCloneJobs()
+		if len(jobs) == 0 {
+			continue
+		}
+		job := jobs[0]
+		if !runCloneJob(ctx, job) {
+			continue
+		}
+
+		if done, ok := removeCloneJob(job.ID); ok {
+			logTo(logger.CategorySystem, fmt.Sprintf(locales.Tr("export.clone_done"), done.ID, html.EscapeString(done.SourceName), html.EscapeString(done.DestName), done.Copied, done.Failed))
+		}
+	}
+}
+
+func CloneCmd(m *telegram.NewMessage) error {
+	args := strings.Fields(m.Args())
+	if len(args) == 0 {
+		_, err := eOR(m, tr(m, "export.clone_usage"))
+		return err
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "list":
+		jobs := getCloneJobs()
+		if len(jobs) == 0 {
+			_, err := eOR(m, tr(m, "export.clone_empty"))
+			return err
+		}
+		text := tr(m, "export.clone_list_header")
+		for _, job := range jobs {
+			text += fmt.Sprintf(tr(m, "export.clone_list_entry"), job.ID, html.EscapeString(job.SourceName), html.EscapeString(job.DestName), job.LastID, job.EndID, job.Copied, job.Failed)
+		}
+		_, err := eOR(m, text)
+		return err
+
+	case "cancel", "stop":
+		id := 0
+		if len(args) > 1 {
+			id, _ = strconv.Atoi(args[1])
+		}
+		job, ok := removeCloneJob(id)
+		if !ok {
+			_, err := eOR(m, tr(m, "export.clone_not_found"))
+			return err
+		}
+		_, err := eOR(m, fmt.Sprintf(tr(m, "export.clone_cancelled"), job.ID, job.Copied))
+		return err
+	}
+
+	if len(args) < 2 {
+		_, err := eOR(m, tr(m, "export.clone_usage"))
+		return err
+	}
+
+	source, sourceName, sourcePeer, err := resolveClonePeer(args[0])
+	if err != nil {
+		_, err := eOR(m, fmt.Sprintf(tr(m, "export.clone_resolve_error"), html.EscapeString(args[0])))
+		return err
+	}
+	dest, destName, destPeer, err := resolveClonePeer(args[1])
+	if err != nil {
+		_, err := eOR(m, fmt.Sprintf(tr(m, "export.clone_resolve_error"), html.EscapeString(args[1])))
+		return err
+	}
+
+	channel, ok := destPeer.(*telegram.InputPeerChannel)
+	if !ok {
+		_, err := eOR(m, tr(m, "export.clone_not_owner"))
+		return err
+	}
+	if ch, err := client.GetChannel(channel.ChannelID); err != nil || !(ch.Creator || (ch.AdminRights != nil && ch.AdminRights.PostMessages)) {
+		_, err := eOR(m, tr(m, "export.clone_not_owner"))
+		return err
+	}
+
+	latest, err := client.GetHistory(sourcePeer, &telegram.HistoryOption{Limit: 1})
+	if err != nil || len(latest) == 0 {
+		_, err := eOR(m, tr(m, "export.clone_source_empty"))
+		return err
+	}
+
+	cloneMutex.Lock()
+	jobs := getCloneJobs()
+	job := CloneJob{Source: source, SourceName: sourceName, Dest: dest, DestName: destName, EndID: latest[0].ID, Started: time.Now()}
+	job.ID, err = nextCloneJobID(jobs)
+	if err == nil {
+		jobs = append(jobs, job)
+		err = saveCloneJobs(jobs)
+	}
+	cloneMutex.Unlock()
+	if err != nil {
+		_, err := eOR(m, fmt.Sprintf(tr(m, "export.error"), html.EscapeString(err.Error())))
+		return err
+	}
+
+	_, err = eOR(m, fmt.Sprintf(tr(m, "export.clone_queued"), job.ID, html.EscapeString(sourceName), html.EscapeString(destName), job.EndID, int(getCloneDelay().Seconds()), len(jobs)-1))
+	return err
+}
+
+func LoadCloneModule(c *telegram.Client) {
+	RegisterVars([]*ConfigVar{
+		{Key: "CLONE_DELAY", Module: "Export", Type: VarInt, Default: "3", Description: "Seconds between messages sent by .clone", Validate: intRange(1, 300)},
+	})
+
+	handlers := []*Handler{
+		{Command: "clone", Func: CloneCmd, Description: "Copy a chat's messages into a channel you own (<source> <dest>, list, cancel <id>)", ModuleName: "Export", DisAllowSudos: true},
+	}
+	AddHandlers(handlers, c)
+
+	startWorker("clone", runCloneJobs)
+}
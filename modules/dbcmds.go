@@ -4,15 +4,26 @@ import (
 	"NovaUserbot/db"
 	"NovaUserbot/locales"
 	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/amarnathcjd/gogram/telegram"
 )
 
 func SetVar(m *telegram.NewMessage) error {
-	args := strings.SplitN(m.Args(), " ", 2)
+	text := strings.TrimSpace(m.Args())
+	force := false
+	if rest, ok := strings.CutPrefix(text, "-f "); ok {
+		force, text = true, strings.TrimSpace(rest)
+	}
+
+	args := strings.SplitN(text, " ", 2)
 	if len(args) < 2 {
-		_, err := eOR(m, locales.Tr("database.usage_setvar"))
+		_, err := eOR(m, tr(m, "database.usage_setvar"))
 		return err
 	}
 
@@ -20,102 +31,250 @@ func SetVar(m *telegram.NewMessage) error {
 	value := strings.TrimSpace(args[1])
 
 	if key == "" || value == "" {
-		_, err := eOR(m, locales.Tr("database.key_value_required"))
+		_, err := eOR(m, tr(m, "database.key_value_required"))
 		return err
 	}
 
+	v, known := lookupVar(key)
+	if !known && !force {
+		_, err := eOR(m, fmt.Sprintf(tr(m, "database.unknown_key"), html.EscapeString(key)))
+		return err
+	}
+
+	display := value
+	if known {
+		parsed, err := v.parse(value)
+		if err != nil {
+			_, err = eOR(m, fmt.Sprintf(tr(m, "database.invalid_value"), key, v.Type, html.EscapeString(err.Error())))
+			return err
+		}
+		value, display = parsed, v.displayValue(parsed)
+	}
+
 	if err := db.Set(key, value); err != nil {
-		_, err = eOR(m, fmt.Sprintf(locales.Tr("database.set_error"), err.Error()))
+		_, err = eOR(m, fmt.Sprintf(tr(m, "database.set_error"), err.Error()))
 		return err
 	}
+	if known && v.OnChange != nil {
+		v.OnChange(value)
+	}
+	syncLanguageCache(key)
 
-	_, err := eOR(m, fmt.Sprintf(locales.Tr("database.set_success"), key, value))
+	msg := fmt.Sprintf(tr(m, "database.set_success"), key, html.EscapeString(display))
+	if known && v.Restart {
+		msg += tr(m, "database.restart_note")
+	}
+	_, err := eOR(m, msg)
 	return err
 }
 
 func GetVar(m *telegram.NewMessage) error {
-	key := strings.TrimSpace(m.Args())
+	key := strings.ToUpper(strings.TrimSpace(m.Args()))
 	if key == "" {
-		_, err := eOR(m, locales.Tr("database.usage_getvar"))
+		_, err := eOR(m, tr(m, "database.usage_getvar"))
 		return err
 	}
 
-	value := db.Get(strings.ToUpper(key))
-	if value == "" {
-		_, err := eOR(m, locales.Tr("database.get_not_found"))
+	value := db.Get(key)
+	v, known := lookupVar(key)
+	if !known {
+		if value == "" {
+			_, err := eOR(m, tr(m, "database.get_not_found"))
+			return err
+		}
+		// Unregistered keys hold internal state, some of it secret such as
+		// the Drive tokens, so only the owner may read them raw.
+		if m.SenderID() != ubId {
+			_, err := eOR(m, fmt.Sprintf(tr(m, "database.get_internal"), key, len(value)))
+			return err
+		}
+		_, err := eOR(m, fmt.Sprintf(tr(m, "database.get_result"), key, html.EscapeString(value)))
 		return err
 	}
 
-	_, err := eOR(m, fmt.Sprintf(locales.Tr("database.get_result"), strings.ToUpper(key), value))
+	msg := fmt.Sprintf(tr(m, "database.get_result"), key, html.EscapeString(v.displayValue(getVar(key))))
+	if value == "" {
+		msg += tr(m, "database.get_using_default")
+	}
+	msg += fmt.Sprintf(tr(m, "database.get_details"), v.Module, v.Type, html.EscapeString(formatVarDefault(m, v)), html.EscapeString(v.Description))
+	_, err := eOR(m, msg)
 	return err
 }
 
 func DelVar(m *telegram.NewMessage) error {
 	key := strings.TrimSpace(m.Args())
 	if key == "" {
-		_, err := eOR(m, locales.Tr("database.usage_delvar"))
+		_, err := eOR(m, tr(m, "database.usage_delvar"))
 		return err
 	}
 
 	upperKey := strings.ToUpper(key)
 	if !db.Exists(upperKey) {
-		_, err := eOR(m, locales.Tr("database.del_not_found"))
+		_, err := eOR(m, tr(m, "database.del_not_found"))
 		return err
 	}
 
 	if err := db.Del(upperKey); err != nil {
-		_, err = eOR(m, fmt.Sprintf(locales.Tr("database.del_error"), err.Error()))
+		_, err = eOR(m, fmt.Sprintf(tr(m, "database.del_error"), err.Error()))
 		return err
 	}
+	if v, ok := lookupVar(upperKey); ok && v.OnChange != nil {
+		v.OnChange(v.Default)
+	}
+	syncLanguageCache(upperKey)
 
-	_, err := eOR(m, fmt.Sprintf(locales.Tr("database.del_success"), upperKey))
+	_, err := eOR(m, fmt.Sprintf(tr(m, "database.del_success"), upperKey))
 	return err
 }
 
+func formatVarDefault(m *telegram.NewMessage, v *ConfigVar) string {
+	if v.Default == "" {
+		return tr(m, "database.unset")
+	}
+	return v.Default
+}
+
+// ListVars shows every registered variable with its effective value, grouped
+// by module. Internal state such as gbans or AFK data is not registered and
+// stays hidden; ".vars all" lists the names of every key in the namespace.
 func ListVars(m *telegram.NewMessage) error {
-	keys, err := db.Keys("*")
-	if err != nil {
-		_, err = eOR(m, locales.Tr("database.fetch_error"))
+	if strings.TrimSpace(m.Args()) == "all" {
+		return listNamespaceKeys(m)
+	}
+
+	vars := registeredVars()
+	if len(vars) == 0 {
+		_, err := eOR(m, tr(m, "database.list_empty"))
 		return err
 	}
 
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(tr(m, "database.list_header"), len(vars)))
+
+	module := ""
+	for _, v := range vars {
+		if v.Module != module {
+			module = v.Module
+			sb.WriteString(fmt.Sprintf(tr(m, "database.list_module"), html.EscapeString(module)))
+		}
+
+		value := getVar(v.Key)
+		shown := html.EscapeString(v.displayValue(value))
+		if value == "" {
+			shown = tr(m, "database.unset")
+		}
+		marker := ""
+		if !db.Exists(v.Key) {
+			marker = tr(m, "database.list_default_marker")
+		}
+		sb.WriteString(fmt.Sprintf(tr(m, "database.list_var_entry"), v.Key, shown, marker, html.EscapeString(v.Description)))
+	}
+
+	return sendListing(m, sb.String(), "vars.txt")
+}
+
+func listNamespaceKeys(m *telegram.NewMessage) error {
+	keys, err := db.Scan("*")
+	if err != nil {
+		_, err = eOR(m, tr(m, "database.fetch_error"))
+		return err
+	}
 	if len(keys) == 0 {
-		_, err = eOR(m, locales.Tr("database.list_empty"))
+		_, err = eOR(m, tr(m, "database.list_empty"))
 		return err
 	}
 
-	var entries []string
+	slices.Sort(keys)
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(tr(m, "database.list_keys_header"), html.EscapeString(db.Prefix()), len(keys)))
 	for _, key := range keys {
-		entries = append(entries, fmt.Sprintf(locales.Tr("database.list_entry"), key))
+		sb.WriteString(fmt.Sprintf(tr(m, "database.list_entry"), html.EscapeString(key)) + "\n")
 	}
+	return sendListing(m, sb.String(), "keys.txt")
+}
 
-	msg := fmt.Sprintf(locales.Tr("database.list_header"), len(keys)) + "\n\n" + strings.Join(entries, "\n")
-	_, err = eOR(m, msg, telegram.SendOptions{ParseMode: "HTML"})
-	return err
+// listingLimit keeps variable listings below Telegram's 4096 character
+// message limit; anything longer is sent as a plain text file instead.
+const listingLimit = 4000
+
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+func sendListing(m *telegram.NewMessage, text, fileName string) error {
+	if len([]rune(text)) <= listingLimit {
+		_, err := eOR(m, text, telegram.SendOptions{ParseMode: "HTML"})
+		return err
+	}
+
+	path := filepath.Join(os.TempDir(), fmt.Sprintf("%d_%s", m.ID, fileName))
+	plain := html.UnescapeString(htmlTag.ReplaceAllString(text, ""))
+	if err := os.WriteFile(path, []byte(plain), 0o600); err != nil {
+		_, err = eOR(m, tr(m, "database.fetch_error"))
+		return err
+	}
+	defer os.Remove(path)
+
+	if _, err := m.RespondMedia(path, &telegram.MediaOptions{
+		Caption:       tr(m, "database.list_file_caption"),
+		ForceDocument: true,
+		FileName:      fileName,
+	}); err != nil {
+		_, err = eOR(m, fmt.Sprintf(tr(m, "database.list_file_error"), html.EscapeString(err.Error())))
+		return err
+	}
+	if m.Sender.ID == ubId {
+		m.Delete()
+	}
+	return nil
 }
 
+// DelAllVars deletes every key in Nova's namespace after sending a backup of
+// it to Saved Messages. Other data on the same Redis server is left alone.
 func DelAllVars(m *telegram.NewMessage) error {
 	if m.Args() != "confirm" {
-		_, err := eOR(m, locales.Tr("database.del_all_warning"))
+		_, err := eOR(m, fmt.Sprintf(tr(m, "database.del_all_warning"), html.EscapeString(db.Prefix())))
 		return err
 	}
 
-	if err := db.FlushAll(); err != nil {
-		_, err = eOR(m, locales.Tr("database.del_all_error"))
+	path, count, err := writeBackupArchive(getVar("BACKUP_PASSWORD"))
+	if err != nil {
+		_, err = eOR(m, fmt.Sprintf(tr(m, "database.backup_error"), html.EscapeString(err.Error())))
 		return err
 	}
+	defer os.Remove(path)
 
-	_, err := eOR(m, locales.Tr("database.del_all_success"))
+	caption := fmt.Sprintf(tr(m, "database.backup_caption"), count, html.EscapeString(db.Prefix()))
+	if _, err := m.Client.SendMedia(ubId, path, &telegram.MediaOptions{Caption: caption, ForceDocument: true}); err != nil {
+		_, err = eOR(m, fmt.Sprintf(tr(m, "database.backup_error"), html.EscapeString(err.Error())))
+		return err
+	}
+
+	deleted, err := db.DeleteNamespace()
+	if err != nil {
+		_, err = eOR(m, tr(m, "database.del_all_error"))
+		return err
+	}
+	reloadStoredState()
+
+	_, err = eOR(m, fmt.Sprintf(tr(m, "database.del_all_success"), deleted))
 	return err
 }
 
+// syncLanguageCache drops the cached language preferences when a per-user or
+// per-chat override is edited directly. BOT_LANGUAGE is handled by its
+// registered OnChange.
+func syncLanguageCache(key string) {
+	if strings.HasPrefix(key, "USER_LANG_") || strings.HasPrefix(key, "CHAT_LANG_") {
+		locales.GetInstance().ReloadPreferences()
+	}
+}
+
 func LoadDbCmds(c *telegram.Client) {
 	handlers := []*Handler{
-		{Func: SetVar, Command: "setvar", Description: "Set a database variable", ModuleName: "Database"},
-		{Func: GetVar, Command: "getvar", Description: "Get a database variable", ModuleName: "Database"},
+		{Func: SetVar, Command: "setvar", Description: "Set a database variable ([-f] <key> <value>)", ModuleName: "Database"},
+		{Func: GetVar, Command: "getvar", Description: "Show a variable with its default and description", ModuleName: "Database"},
 		{Func: DelVar, Command: "delvar", Description: "Delete a database variable", ModuleName: "Database"},
-		{Func: ListVars, Command: "vars", Description: "List all database variables", ModuleName: "Database"},
-		{Func: DelAllVars, Command: "delallvars", Description: "Delete all variables (requires confirm)", ModuleName: "Database"},
+		{Func: ListVars, Command: "vars", Description: "List variables by module with their current values [all]", ModuleName: "Database"},
+		{Func: DelAllVars, Command: "delallvars", Description: "Back up and delete all of Nova's keys (requires confirm)", ModuleName: "Database", DisAllowSudos: true},
 	}
 	AddHandlers(handlers, c)
 }
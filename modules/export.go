@@ -0,0 +1,310 @@
+package modules
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+const (
+	exportDefaultCount = 500
+	exportMaxCount     = 10000
+	// exportMediaLimit keeps archives with --media under the upload limit.
+	exportMediaLimit = 1500 << 20
+)
+
+type chatExport struct {
+	ChatID   int64             `json:"chat_id"`
+	Title    string            `json:"title"`
+	Exported time.Time         `json:"exported"`
+	Messages []exportedMessage `json:"messages"`
+}
+
+type exportedMessage struct {
+	ID        int32            `json:"id"`
+	Date      time.Time        `json:"date"`
+	SenderID  int64            `json:"sender_id,omitempty"`
+	Sender    string           `json:"sender,omitempty"`
+	Text      string           `json:"text,omitempty"`
+	Entities  []exportedEntity `json:"entities,omitempty"`
+	ReplyTo   int32            `json:"reply_to,omitempty"`
+	Forwarded bool             `json:"forwarded,omitempty"`
+	Action    string           `json:"action,omitempty"`
+	Media     *exportedMedia   `json:"media,omitempty"`
+}
+
+// exportedEntity is a formatting entity; offsets count UTF-16 code units as
+// in the Bot API.
+type exportedEntity struct {
+	Type   string            `json:"type"`
+	Offset int32             `json:"offset"`
+	Length int32             `json:"length"`
+	Attrs  map[string]string `json:"attrs,omitempty"`
+}
+
+type exportedMedia struct {
+	Type    string `json:"type"`
+	FileID  string `json:"file_id,omitempty"`
+	Name    string `json:"name,omitempty"`
+	Size    int64  `json:"size,omitempty"`
+	Path    string `json:"path,omitempty"`
+	Skipped bool   `json:"skipped,omitempty"`
+}
+
+func parseExportArgs(args string) (count int, withMedia bool) {
+	count = exportDefaultCount
+	for _, arg := range strings.Fields(args) {
+		if arg == "--media" || arg == "-m" {
+			withMedia = true
+		} else if n, err := strconv.Atoi(arg); err == nil && n > 0 {
+			count = min(n, exportMaxCount)
+		}
+	}
+	return count, withMedia
+}
+
+func senderName(msg *telegram.NewMessage) string {
+	if msg.SenderChat != nil && msg.SenderChat.ID != 0 {
+		return msg.SenderChat.Title
+	}
+	if msg.Sender != nil {
+		return strings.TrimSpace(msg.Sender.FirstName + " " + msg.Sender.LastName)
+	}
+	if msg.Channel != nil {
+		return msg.Channel.Title
+	}
+	return ""
+}
+
+func exportMessage(msg *telegram.NewMessage) exportedMessage {
+	e := exportedMessage{
+		ID:        msg.ID,
+		Date:      time.Unix(int64(msg.Date()), 0),
+		SenderID:  msg.SenderID(),
+		Sender:    senderName(msg),
+		Text:      msg.Text(),
+		ReplyTo:   msg.ReplyToMsgID(),
+		Forwarded: msg.IsForward(),
+	}
+	if msg.Action != nil {
+		e.Action = strings.TrimPrefix(fmt.Sprintf("%T", msg.Action), "*telegram.MessageAction")
+	}
+	for _, tag := range telegram.ParseEntitiesToTags(msg.Message.Entities) {
+		e.Entities = append(e.Entities, exportedEntity{Type: tag.Type, Offset: tag.Offset, Length: tag.Length, Attrs: tag.Attrs})
+	}
+	if msg.IsMedia() {
+		e.Media = &exportedMedia{Type: msg.MediaType()}
+		if msg.File != nil {
+			e.Media.FileID, e.Media.Name, e.Media.Size = msg.File.FileID, msg.File.Name, msg.File.Size
+		}
+	}
+	return e
+}
+
+// downloadExportMedia saves the photos and documents of the export into
+// dir/media, skipping files once exportMediaLimit is reached.
+func downloadExportMedia(status *telegram.NewMessage, m *telegram.NewMessage, dir string, msgs []telegram.NewMessage, export *chatExport) {
+	var total int64
+	var done int
+	for i := range msgs {
+		media := export.Messages[i].Media
+		if media == nil || media.FileID == "" || (media.Type != "photo" && media.Type != "document") {
+			continue
+		}
+		if total+media.Size > exportMediaLimit {
+			media.Skipped = true
+			continue
+		}
+
+		name := media.Name
+		if name == "" {
+			name = fmt.Sprintf("%d%s", msgs[i].ID, msgs[i].File.Ext)
+		}
+		rel := filepath.Join("media", fmt.Sprintf("%d_%s", msgs[i].ID, filepath.Base(name)))
+		if _, err := msgs[i].Download(&telegram.DownloadOptions{FileName: filepath.Join(dir, rel)}); err != nil {
+			media.Skipped = true
+			continue
+		}
+		media.Path = filepath.ToSlash(rel)
+		total += media.Size
+
+		if done++; done%25 == 0 {
+			status.Edit(fmt.Sprintf(tr(m, "export.downloading"), done))
+		}
+	}
+}
+
+func renderExportHTML(export *chatExport) string {
+	var b strings.Builder
+	title := html.EscapeString(export.Title)
+	fmt.Fprintf(&b, `<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>%s</title>
+<style>
+body{font-family:sans-serif;background:#f4f4f5;margin:0 auto;max-width:760px;padding:16px}
+.msg{background:#fff;border-radius:8px;margin:8px 0;padding:8px 12px}
+.meta{color:#71717a;font-size:13px;margin-bottom:4px}.meta b{color:#2563eb}
+.reply{border-left:3px solid #2563eb;color:#52525b;display:block;font-size:13px;margin-bottom:4px;padding-left:6px;text-decoration:none}
+.service{color:#71717a;font-style:italic;text-align:center}
+.media{color:#52525b;font-size:13px;margin-top:4px}
+img{border-radius:6px;display:block;max-width:100%%}
+</style></head><body>
+<h1>%s</h1><p>%d messages, exported %s</p>
+`, title, title, len(export.Messages), export.Exported.Format("2006-01-02 15:04"))
+
+	for _, msg := range export.Messages {
+		if msg.Action != "" {
+			fmt.Fprintf(&b, "<div class=\"msg service\" id=\"m%d\">%s · %s</div>\n", msg.ID, html.EscapeString(msg.Action), msg.Date.Format("2006-01-02 15:04"))
+			continue
+		}
+
+		fmt.Fprintf(&b, "<div class=\"msg\" id=\"m%d\"><div class=\"meta\"><b>%s</b> %s <a href=\"#m%d\">#%d</a>", msg.ID, html.EscapeString(msg.Sender), msg.Date.Format("2006-01-02 15:04"), msg.ID, msg.ID)
+		if msg.Forwarded {
+			b.WriteString(" · forwarded")
+		}
+		b.WriteString("</div>")
+		if msg.ReplyTo != 0 {
+			fmt.Fprintf(&b, "<a class=\"reply\" href=\"#m%d\">↩ #%d</a>", msg.ReplyTo, msg.ReplyTo)
+		}
+		if msg.Text != "" {
+			b.WriteString("<div>" + strings.ReplaceAll(html.EscapeString(msg.Text), "\n", "<br>") + "</div>")
+		}
+		if media := msg.Media; media != nil {
+			switch {
+			case media.Path != "" && media.Type == "photo":
+				fmt.Fprintf(&b, "<img src=\"%s\" loading=\"lazy\">", html.EscapeString(media.Path))
+			case media.Path != "":
+				fmt.Fprintf(&b, "<div class=\"media\">📎 <a href=\"%s\">%s</a></div>", html.EscapeString(media.Path), html.EscapeString(orNone(media.Name)))
+			default:
+				fmt.Fprintf(&b, "<div class=\"media\">📎 %s %s</div>", html.EscapeString(media.Type), html.EscapeString(media.Name))
+			}
+		}
+		b.WriteString("</div>\n")
+	}
+	b.WriteString("</body></html>\n")
+	return b.String()
+}
+
+// writeExportArchive zips everything under dir into path.
+func writeExportArchive(dir, path string) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	zw := zip.NewWriter(out)
+	err = filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		w, err := zw.Create(filepath.ToSlash(rel))
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(w, f)
+		return err
+	})
+	if err != nil {
+		zw.Close()
+		return err
+	}
+	return zw.Close()
+}
+
+// ExportCmd archives the last messages of the current chat as JSON and HTML
+// and uploads the zip back to the chat.
+func ExportCmd(m *telegram.NewMessage) error {
+	count, withMedia := parseExportArgs(m.Args())
+	status, _ := eOR(m, fmt.Sprintf(tr(m, "export.fetching"), count))
+
+	msgs, err := client.GetHistory(m.ChatID(), &telegram.HistoryOption{Limit: int32(count) + 1})
+	if err != nil {
+		_, err := status.Edit(fmt.Sprintf(tr(m, "export.error"), html.EscapeString(err.Error())))
+		return err
+	}
+	msgs = slices.DeleteFunc(msgs, func(msg telegram.NewMessage) bool { return msg.ID == m.ID })
+	if len(msgs) > count {
+		msgs = msgs[:count]
+	}
+	slices.Reverse(msgs)
+	if len(msgs) == 0 {
+		_, err := status.Edit(tr(m, "export.empty"))
+		return err
+	}
+
+	export := &chatExport{ChatID: m.ChatID(), Exported: time.Now()}
+	if m.Channel != nil {
+		export.Title = m.Channel.Title
+	} else if m.Chat != nil {
+		export.Title = m.Chat.Title
+	}
+	if export.Title == "" {
+		export.Title = strconv.FormatInt(export.ChatID, 10)
+	}
+	for i := range msgs {
+		export.Messages = append(export.Messages, exportMessage(&msgs[i]))
+	}
+
+	dir, err := os.MkdirTemp("", "export_")
+	if err != nil {
+		_, err := status.Edit(fmt.Sprintf(tr(m, "export.error"), html.EscapeString(err.Error())))
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	if withMedia {
+		downloadExportMedia(status, m, dir, msgs, export)
+	}
+
+	data, _ := json.MarshalIndent(export, "", "  ")
+	if err := os.WriteFile(filepath.Join(dir, "messages.json"), data, 0644); err != nil {
+		_, err := status.Edit(fmt.Sprintf(tr(m, "export.error"), html.EscapeString(err.Error())))
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "messages.html"), []byte(renderExportHTML(export)), 0644); err != nil {
+		_, err := status.Edit(fmt.Sprintf(tr(m, "export.error"), html.EscapeString(err.Error())))
+		return err
+	}
+
+	archive := filepath.Join(os.TempDir(), fmt.Sprintf("export_%d_%s.zip", export.ChatID, export.Exported.Format("20060102_150405")))
+	if err := writeExportArchive(dir, archive); err != nil {
+		_, err := status.Edit(fmt.Sprintf(tr(m, "export.error"), html.EscapeString(err.Error())))
+		return err
+	}
+	defer os.Remove(archive)
+
+	status.Edit(tr(m, "export.uploading"))
+	caption := fmt.Sprintf(tr(m, "export.caption"), html.EscapeString(export.Title), len(export.Messages), export.Messages[0].Date.Format("2006-01-02"), export.Messages[len(export.Messages)-1].Date.Format("2006-01-02"))
+	if _, err := m.RespondMedia(archive, &telegram.MediaOptions{Caption: caption, ForceDocument: true, FileName: filepath.Base(archive)}); err != nil {
+		_, err := status.Edit(fmt.Sprintf(tr(m, "export.error"), html.EscapeString(err.Error())))
+		return err
+	}
+	_, err = status.Delete()
+	return err
+}
+
+func LoadExportModule(c *telegram.Client) {
+	handlers := []*Handler{
+		{Command: "export", Func: ExportCmd, Description: "Export the chat's last messages as a JSON and HTML archive ([count] [--media])", ModuleName: "Export", DisAllowSudos: true},
+	}
+	AddHandlers(handlers, c)
+}
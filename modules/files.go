@@ -1,7 +1,6 @@
 package modules
 
 import (
-	"NovaUserbot/locales"
 	"NovaUserbot/utils"
 	"context"
 	"fmt"
@@ -36,13 +35,13 @@ func humanBytes(b uint64) string {
 func sendFileByIDCommand(m *telegram.NewMessage) error {
 	fileId := strings.TrimSpace(m.Args())
 	if fileId == "" {
-		_, err := eOR(m, locales.Tr("files.no_fileid"))
+		_, err := eOR(m, tr(m, "files.no_fileid"))
 		return err
 	}
 
 	file, err := telegram.ResolveBotFileID(fileId)
 	if err != nil {
-		_, err := eOR(m, fmt.Sprintf(locales.Tr("files.resolve_error"), err.Error()))
+		_, err := eOR(m, fmt.Sprintf(tr(m, "files.resolve_error"), err.Error()))
 		return err
 	}
 
@@ -52,29 +51,29 @@ func sendFileByIDCommand(m *telegram.NewMessage) error {
 
 func getFileIDCommand(m *telegram.NewMessage) error {
 	if !m.IsReply() {
-		_, err := eOR(m, locales.Tr("files.reply_required"))
+		_, err := eOR(m, tr(m, "files.reply_required"))
 		return err
 	}
 
 	reply, err := m.GetReplyMessage()
 	if err != nil {
-		_, err := eOR(m, locales.Tr("files.fetch_error"))
+		_, err := eOR(m, tr(m, "files.fetch_error"))
 		return err
 	}
 
 	if reply.File == nil {
-		_, err := eOR(m, locales.Tr("files.no_file"))
+		_, err := eOR(m, tr(m, "files.no_file"))
 		return err
 	}
 
-	_, err = eOR(m, fmt.Sprintf(locales.Tr("files.fileid_result"), reply.File.FileID))
+	_, err = eOR(m, fmt.Sprintf(tr(m, "files.fileid_result"), reply.File.FileID))
 	return err
 }
 
 func uploadCommand(m *telegram.NewMessage) error {
 	filename := strings.TrimSpace(m.Args())
 	if filename == "" {
-		_, err := eOR(m, locales.Tr("files.no_filename"))
+		_, err := eOR(m, tr(m, "files.no_filename"))
 		return err
 	}
 
@@ -93,11 +92,11 @@ func uploadCommand(m *telegram.NewMessage) error {
 	}
 
 	if _, err := os.Stat(filename); os.IsNotExist(err) {
-		_, err := eOR(m, locales.Tr("files.file_not_found"))
+		_, err := eOR(m, tr(m, "files.file_not_found"))
 		return err
 	}
 
-	msg, _ := eOR(m, locales.Tr("files.uploading"))
+	msg, _ := eOR(m, tr(m, "files.uploading"))
 	uploadStartTimestamp := time.Now()
 
 	opts := &telegram.SendOptions{
@@ -107,17 +106,17 @@ func uploadCommand(m *telegram.NewMessage) error {
 	}
 
 	if _, err := m.Respond("", opts); err != nil {
-		_, err := msg.Edit(fmt.Sprintf(locales.Tr("files.upload_error"), err.Error()))
+		_, err := msg.Edit(fmt.Sprintf(tr(m, "files.upload_error"), err.Error()))
 		return err
 	}
 
-	_, err := msg.Edit(fmt.Sprintf(locales.Tr("files.upload_success"), filename, time.Since(uploadStartTimestamp).String()))
+	_, err := msg.Edit(fmt.Sprintf(tr(m, "files.upload_success"), filename, time.Since(uploadStartTimestamp).String()))
 	return err
 }
 
 func downloadCommand(m *telegram.NewMessage) error {
 	if !m.IsReply() && m.Args() == "" {
-		_, err := eOR(m, locales.Tr("files.reply_or_link"))
+		_, err := eOR(m, tr(m, "files.reply_or_link"))
 		return err
 	}
 
@@ -129,11 +128,11 @@ func downloadCommand(m *telegram.NewMessage) error {
 	if m.IsReply() {
 		r, err := m.GetReplyMessage()
 		if err != nil {
-			_, err := eOR(m, locales.Tr("files.fetch_error"))
+			_, err := eOR(m, tr(m, "files.fetch_error"))
 			return err
 		}
 		reply = r
-		msg, _ = eOR(m, locales.Tr("files.downloading"))
+		msg, _ = eOR(m, tr(m, "files.downloading"))
 	} else {
 
 		reg := regexp.MustCompile(`t\.me/(\w+)/(\d+)`)
@@ -143,55 +142,55 @@ func downloadCommand(m *telegram.NewMessage) error {
 			reg = regexp.MustCompile(`t\.me/c/(\d+)/(\d+)`)
 			match = reg.FindStringSubmatch(m.Args())
 			if len(match) != 3 {
-				_, err := eOR(m, locales.Tr("files.invalid_link"))
+				_, err := eOR(m, tr(m, "files.invalid_link"))
 				return err
 			}
 
 			msgId, err := strconv.Atoi(match[2])
 			if err != nil {
-				_, err := eOR(m, locales.Tr("files.invalid_link"))
+				_, err := eOR(m, tr(m, "files.invalid_link"))
 				return err
 			}
 
 			chatID, err := strconv.Atoi(match[1])
 			if err != nil {
-				_, err := eOR(m, locales.Tr("files.invalid_link"))
+				_, err := eOR(m, tr(m, "files.invalid_link"))
 				return err
 			}
 
 			msgX, err := m.Client.GetMessageByID(chatID, int32(msgId))
 			if err != nil {
-				_, err := eOR(m, fmt.Sprintf(locales.Tr("files.fetch_error")+": %s", err.Error()))
+				_, err := eOR(m, fmt.Sprintf(tr(m, "files.fetch_error")+": %s", err.Error()))
 				return err
 			}
 			reply = msgX
 			if reply.File != nil {
 				fn = reply.File.Name
 			}
-			msg, _ = eOR(m, fmt.Sprintf(locales.Tr("files.downloading_from"), "private", msgId))
+			msg, _ = eOR(m, fmt.Sprintf(tr(m, "files.downloading_from"), "private", msgId))
 		} else {
 			username := match[1]
 			msgId, err := strconv.Atoi(match[2])
 			if err != nil {
-				_, err := eOR(m, locales.Tr("files.invalid_link"))
+				_, err := eOR(m, tr(m, "files.invalid_link"))
 				return err
 			}
 
 			msgX, err := m.Client.GetMessageByID(username, int32(msgId))
 			if err != nil {
-				_, err := eOR(m, fmt.Sprintf(locales.Tr("files.fetch_error")+": %s", err.Error()))
+				_, err := eOR(m, fmt.Sprintf(tr(m, "files.fetch_error")+": %s", err.Error()))
 				return err
 			}
 			reply = msgX
 			if reply.File != nil {
 				fn = reply.File.Name
 			}
-			msg, _ = eOR(m, fmt.Sprintf(locales.Tr("files.downloading_from"), username, msgId))
+			msg, _ = eOR(m, fmt.Sprintf(tr(m, "files.downloading_from"), username, msgId))
 		}
 	}
 
 	if reply.File == nil {
-		_, err := msg.Edit(locales.Tr("files.no_file"))
+		_, err := msg.Edit(tr(m, "files.no_file"))
 		return err
 	}
 
@@ -219,26 +218,26 @@ func downloadCommand(m *telegram.NewMessage) error {
 	filePath, err := reply.Download(opts)
 	if err != nil {
 		if err == context.Canceled {
-			msg.Edit(locales.Tr("files.download_cancelled"))
+			msg.Edit(tr(m, "files.download_cancelled"))
 		} else {
-			msg.Edit(fmt.Sprintf(locales.Tr("files.download_error"), err.Error()))
+			msg.Edit(fmt.Sprintf(tr(m, "files.download_error"), err.Error()))
 		}
 		return err
 	}
 
-	_, err = msg.Edit(fmt.Sprintf(locales.Tr("files.download_success"), filePath, time.Since(uploadStartTimestamp).String()))
+	_, err = msg.Edit(fmt.Sprintf(tr(m, "files.download_success"), filePath, time.Since(uploadStartTimestamp).String()))
 	return err
 }
 
 func cancelDownloadCommand(m *telegram.NewMessage) error {
 	if !m.IsReply() {
-		_, err := eOR(m, locales.Tr("files.reply_to_download"))
+		_, err := eOR(m, tr(m, "files.reply_to_download"))
 		return err
 	}
 
 	reply, err := m.GetReplyMessage()
 	if err != nil {
-		_, err := eOR(m, locales.Tr("files.fetch_error"))
+		_, err := eOR(m, tr(m, "files.fetch_error"))
 		return err
 	}
 
@@ -247,24 +246,24 @@ func cancelDownloadCommand(m *telegram.NewMessage) error {
 	cancelMutex.RUnlock()
 
 	if !exists {
-		_, err := eOR(m, locales.Tr("files.no_active_download"))
+		_, err := eOR(m, tr(m, "files.no_active_download"))
 		return err
 	}
 
 	cancel()
-	_, err = eOR(m, locales.Tr("files.cancelled"))
+	_, err = eOR(m, tr(m, "files.cancelled"))
 	return err
 }
 
 func fileInfoCommand(m *telegram.NewMessage) error {
 	if !m.IsReply() {
-		_, err := eOR(m, locales.Tr("files.reply_required"))
+		_, err := eOR(m, tr(m, "files.reply_required"))
 		return err
 	}
 
 	reply, err := m.GetReplyMessage()
 	if err != nil {
-		_, err := eOR(m, locales.Tr("files.fetch_error"))
+		_, err := eOR(m, tr(m, "files.fetch_error"))
 		return err
 	}
 
@@ -358,31 +357,31 @@ func fileInfoCommand(m *telegram.NewMessage) error {
 
 func genLinkCommand(m *telegram.NewMessage) error {
 	if !m.IsReply() {
-		_, err := eOR(m, locales.Tr("misc.reply_to_media"))
+		_, err := eOR(m, tr(m, "misc.reply_to_media"))
 		return err
 	}
 
 	reply, err := m.GetReplyMessage()
 	if err != nil {
-		_, err := eOR(m, locales.Tr("misc.error_fetching_reply"))
+		_, err := eOR(m, tr(m, "misc.error_fetching_reply"))
 		return err
 	}
 
 	if reply.Media() == nil {
-		_, err := eOR(m, locales.Tr("misc.no_media"))
+		_, err := eOR(m, tr(m, "misc.no_media"))
 		return err
 	}
 
-	msg, _ := eOR(m, locales.Tr("misc.downloading"))
+	msg, _ := eOR(m, tr(m, "misc.downloading"))
 
 	file, err := reply.Download()
 	if err != nil {
-		_, err := eOR(m, locales.Tr("misc.error_downloading"))
+		_, err := eOR(m, tr(m, "misc.error_downloading"))
 		return err
 	}
 	defer os.Remove(file)
 
-	msg.Edit(locales.Tr("misc.uploading"))
+	msg.Edit(tr(m, "misc.uploading"))
 
 	link, err := utils.UploadFileToEnvsSh(file)
 	if err != nil {
@@ -390,7 +389,7 @@ func genLinkCommand(m *telegram.NewMessage) error {
 		return err
 	}
 
-	_, err = msg.Edit(fmt.Sprintf(locales.Tr("misc.upload_success"), link))
+	_, err = msg.Edit(fmt.Sprintf(tr(m, "misc.upload_success"), link))
 	return err
 }
 
@@ -1,7 +1,6 @@
 package modules
 
 import (
-	"NovaUserbot/locales"
 	"bytes"
 	"encoding/json"
 	"fmt"
@@ -227,31 +226,31 @@ func uploadTo0x0(filePath string) (string, error) {
 
 func shareFileCommand(m *telegram.NewMessage) error {
 	if !m.IsReply() {
-		_, err := eOR(m, locales.Tr("fileshare.reply_required"))
+		_, err := eOR(m, tr(m, "fileshare.reply_required"))
 		return err
 	}
 
 	reply, err := m.GetReplyMessage()
 	if err != nil {
-		_, err := eOR(m, locales.Tr("fileshare.fetch_error"))
+		_, err := eOR(m, tr(m, "fileshare.fetch_error"))
 		return err
 	}
 
 	if reply.Media() == nil {
-		_, err := eOR(m, locales.Tr("fileshare.no_media"))
+		_, err := eOR(m, tr(m, "fileshare.no_media"))
 		return err
 	}
 
-	msg, _ := eOR(m, locales.Tr("fileshare.downloading"))
+	msg, _ := eOR(m, tr(m, "fileshare.downloading"))
 
 	filePath, err := reply.Download()
 	if err != nil {
-		_, err := msg.Edit(locales.Tr("fileshare.download_error"))
+		_, err := msg.Edit(tr(m, "fileshare.download_error"))
 		return err
 	}
 	defer os.Remove(filePath)
 
-	msg.Edit(locales.Tr("fileshare.uploading"))
+	msg.Edit(tr(m, "fileshare.uploading"))
 
 	service := strings.ToLower(strings.TrimSpace(m.Args()))
 	var link string
@@ -271,125 +270,125 @@ func shareFileCommand(m *telegram.NewMessage) error {
 	}
 
 	if err != nil {
-		_, err := msg.Edit(fmt.Sprintf(locales.Tr("fileshare.upload_error"), err.Error()))
+		_, err := msg.Edit(fmt.Sprintf(tr(m, "fileshare.upload_error"), err.Error()))
 		return err
 	}
 
-	_, err = msg.Edit(fmt.Sprintf(locales.Tr("fileshare.upload_success"), service, link))
+	_, err = msg.Edit(fmt.Sprintf(tr(m, "fileshare.upload_success"), service, link))
 	return err
 }
 
 func catboxCommand(m *telegram.NewMessage) error {
 	if !m.IsReply() {
-		_, err := eOR(m, locales.Tr("fileshare.reply_required"))
+		_, err := eOR(m, tr(m, "fileshare.reply_required"))
 		return err
 	}
 
 	reply, err := m.GetReplyMessage()
 	if err != nil {
-		_, err := eOR(m, locales.Tr("fileshare.fetch_error"))
+		_, err := eOR(m, tr(m, "fileshare.fetch_error"))
 		return err
 	}
 
 	if reply.Media() == nil {
-		_, err := eOR(m, locales.Tr("fileshare.no_media"))
+		_, err := eOR(m, tr(m, "fileshare.no_media"))
 		return err
 	}
 
-	msg, _ := eOR(m, locales.Tr("fileshare.downloading"))
+	msg, _ := eOR(m, tr(m, "fileshare.downloading"))
 
 	filePath, err := reply.Download()
 	if err != nil {
-		_, err := msg.Edit(locales.Tr("fileshare.download_error"))
+		_, err := msg.Edit(tr(m, "fileshare.download_error"))
 		return err
 	}
 	defer os.Remove(filePath)
 
-	msg.Edit(locales.Tr("fileshare.uploading"))
+	msg.Edit(tr(m, "fileshare.uploading"))
 
 	link, err := uploadToCatbox(filePath)
 	if err != nil {
-		_, err := msg.Edit(fmt.Sprintf(locales.Tr("fileshare.upload_error"), err.Error()))
+		_, err := msg.Edit(fmt.Sprintf(tr(m, "fileshare.upload_error"), err.Error()))
 		return err
 	}
 
-	_, err = msg.Edit(fmt.Sprintf(locales.Tr("fileshare.upload_success"), "Catbox", link))
+	_, err = msg.Edit(fmt.Sprintf(tr(m, "fileshare.upload_success"), "Catbox", link))
 	return err
 }
 
 func gofileCommand(m *telegram.NewMessage) error {
 	if !m.IsReply() {
-		_, err := eOR(m, locales.Tr("fileshare.reply_required"))
+		_, err := eOR(m, tr(m, "fileshare.reply_required"))
 		return err
 	}
 
 	reply, err := m.GetReplyMessage()
 	if err != nil {
-		_, err := eOR(m, locales.Tr("fileshare.fetch_error"))
+		_, err := eOR(m, tr(m, "fileshare.fetch_error"))
 		return err
 	}
 
 	if reply.Media() == nil {
-		_, err := eOR(m, locales.Tr("fileshare.no_media"))
+		_, err := eOR(m, tr(m, "fileshare.no_media"))
 		return err
 	}
 
-	msg, _ := eOR(m, locales.Tr("fileshare.downloading"))
+	msg, _ := eOR(m, tr(m, "fileshare.downloading"))
 
 	filePath, err := reply.Download()
 	if err != nil {
-		_, err := msg.Edit(locales.Tr("fileshare.download_error"))
+		_, err := msg.Edit(tr(m, "fileshare.download_error"))
 		return err
 	}
 	defer os.Remove(filePath)
 
-	msg.Edit(locales.Tr("fileshare.uploading"))
+	msg.Edit(tr(m, "fileshare.uploading"))
 
 	link, err := uploadToGoFile(filePath)
 	if err != nil {
-		_, err := msg.Edit(fmt.Sprintf(locales.Tr("fileshare.upload_error"), err.Error()))
+		_, err := msg.Edit(fmt.Sprintf(tr(m, "fileshare.upload_error"), err.Error()))
 		return err
 	}
 
-	_, err = msg.Edit(fmt.Sprintf(locales.Tr("fileshare.upload_success"), "GoFile", link))
+	_, err = msg.Edit(fmt.Sprintf(tr(m, "fileshare.upload_success"), "GoFile", link))
 	return err
 }
 
 func fileioCommand(m *telegram.NewMessage) error {
 	if !m.IsReply() {
-		_, err := eOR(m, locales.Tr("fileshare.reply_required"))
+		_, err := eOR(m, tr(m, "fileshare.reply_required"))
 		return err
 	}
 
 	reply, err := m.GetReplyMessage()
 	if err != nil {
-		_, err := eOR(m, locales.Tr("fileshare.fetch_error"))
+		_, err := eOR(m, tr(m, "fileshare.fetch_error"))
 		return err
 	}
 
 	if reply.Media() == nil {
-		_, err := eOR(m, locales.Tr("fileshare.no_media"))
+		_, err := eOR(m, tr(m, "fileshare.no_media"))
 		return err
 	}
 
-	msg, _ := eOR(m, locales.Tr("fileshare.downloading"))
+	msg, _ := eOR(m, tr(m, "fileshare.downloading"))
 
 	filePath, err := reply.Download()
 	if err != nil {
-		_, err := msg.Edit(locales.Tr("fileshare.download_error"))
+		_, err := msg.Edit(tr(m, "fileshare.download_error"))
 		return err
 	}
 	defer os.Remove(filePath)
 
-	msg.Edit(locales.Tr("fileshare.uploading"))
+	msg.Edit(tr(m, "fileshare.uploading"))
 
 	link, err := uploadToFileIO(filePath)
 	if err != nil {
-		_, err := msg.Edit(fmt.Sprintf(locales.Tr("fileshare.upload_error"), err.Error()))
+		_, err := msg.Edit(fmt.Sprintf(tr(m, "fileshare.upload_error"), err.Error()))
 		return err
 	}
 
-	_, err = msg.Edit(fmt.Sprintf(locales.Tr("fileshare.upload_success"), "File.io", link))
+	_, err = msg.Edit(fmt.Sprintf(tr(m, "fileshare.upload_success"), "File.io", link))
 	return err
 }
 
@@ -3,8 +3,10 @@ package modules
 import (
 	"NovaUserbot/db"
 	"NovaUserbot/locales"
+	"NovaUserbot/logger"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,37 +18,78 @@ type BanInfo struct {
 	Time   string
 }
 
+func gbanKey(userID int64) string {
+	return fmt.Sprintf("GBAN:%d", userID)
+}
+
+func getGban(userID int64) (BanInfo, bool) {
+	data := db.Get(gbanKey(userID))
+	if data == "" {
+		return BanInfo{}, false
+	}
+
+	var info BanInfo
+	if err := json.Unmarshal([]byte(data), &info); err != nil {
+		logger.Errorf("Corrupted gban entry for %d: %v", userID, err)
+		return BanInfo{}, false
+	}
+	return info, true
+}
+
+func setGban(userID int64, info BanInfo) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	return db.Set(gbanKey(userID), string(data))
+}
+
+func listGbans() (map[int64]BanInfo, error) {
+	keys, err := db.Scan("GBAN:*")
+	if err != nil {
+		return nil, err
+	}
+
+	bans := make(map[int64]BanInfo, len(keys))
+	for _, key := range keys {
+		userID, err := strconv.ParseInt(strings.TrimPrefix(key, "GBAN:"), 10, 64)
+		if err != nil {
+			continue
+		}
+		if info, ok := getGban(userID); ok {
+			bans[userID] = info
+		}
+	}
+	return bans, nil
+}
+
 func gbanUser(m *telegram.NewMessage) error {
 	userID, Name, reason := ExtractUserMsg(m)
 	if userID == 0 {
-		_, err := eOR(m, locales.Tr("gban.usage_gban"))
+		_, err := eOR(m, tr(m, "gban.usage_gban"))
 		return err
 	}
 
 	if userID == ubId {
-		_, err := eOR(m, locales.Tr("gban.cant_ban_self"))
+		_, err := eOR(m, tr(m, "gban.cant_ban_self"))
 		return err
 	}
 
 	if reason == "" {
-		reason = locales.Tr("common.no_reason")
+		reason = tr(m, "common.no_reason")
 	}
 
-	banMap := make(map[int64]BanInfo)
-	if data := db.Get("GBANS"); data != "" {
-		json.Unmarshal([]byte(data), &banMap)
-	}
-
-	if info, exists := banMap[userID]; exists {
-		_, err := eOR(m, fmt.Sprintf(locales.Tr("gban.already_banned"), userID, Name, info.Reason, info.Time))
+	if info, exists := getGban(userID); exists {
+		_, err := eOR(m, fmt.Sprintf(tr(m, "gban.already_banned"), userID, Name, info.Reason, info.Time))
 		return err
 	}
 
-	msg, _ := eOR(m, locales.Tr("gban.banning"))
+	msg, _ := eOR(m, tr(m, "gban.banning"))
 
-	banMap[userID] = BanInfo{Reason: reason, Time: time.Now().Format(time.RFC1123)}
-	data, _ := json.Marshal(banMap)
-	db.Set("GBANS", string(data))
+	if err := setGban(userID, BanInfo{Reason: reason, Time: time.Now().Format(time.RFC1123)}); err != nil {
+		_, err = msg.Edit(fmt.Sprintf(tr(m, "gban.save_error"), err.Error()))
+		return err
+	}
 
 	chats := m.Client.Cache.InputPeers.InputChannels
 	var success int
@@ -57,33 +100,29 @@ func gbanUser(m *telegram.NewMessage) error {
 		}
 	}
 
-	logMessage(fmt.Sprintf(locales.Tr("gban.log_banned"), userID, Name, reason))
-	_, err := msg.Edit(fmt.Sprintf(locales.Tr("gban.banned"), userID, Name, reason, success))
+	logTo(logger.CategoryModeration, fmt.Sprintf(locales.Tr("gban.log_banned"), userID, Name, reason))
+	_, err := msg.Edit(fmt.Sprintf(tr(m, "gban.banned"), userID, Name, reason, success))
 	return err
 }
 
 func ungbanUser(m *telegram.NewMessage) error {
 	userID, Name, _ := ExtractUserMsg(m)
 	if userID == 0 {
-		_, err := eOR(m, locales.Tr("gban.usage_ungban"))
+		_, err := eOR(m, tr(m, "gban.usage_ungban"))
 		return err
 	}
 
-	banMap := make(map[int64]BanInfo)
-	if data := db.Get("GBANS"); data != "" {
-		json.Unmarshal([]byte(data), &banMap)
-	}
-
-	if _, exists := banMap[userID]; !exists {
-		_, err := eOR(m, fmt.Sprintf(locales.Tr("gban.not_banned"), userID, Name))
+	if _, exists := getGban(userID); !exists {
+		_, err := eOR(m, fmt.Sprintf(tr(m, "gban.not_banned"), userID, Name))
 		return err
 	}
 
-	msg, _ := eOR(m, locales.Tr("gban.unbanning"))
+	msg, _ := eOR(m, tr(m, "gban.unbanning"))
 
-	delete(banMap, userID)
-	data, _ := json.Marshal(banMap)
-	db.Set("GBANS", string(data))
+	if err := db.Del(gbanKey(userID)); err != nil {
+		_, err = msg.Edit(fmt.Sprintf(tr(m, "gban.save_error"), err.Error()))
+		return err
+	}
 
 	chats := m.Client.Cache.InputPeers.InputChannels
 	var success int
@@ -94,29 +133,26 @@ func ungbanUser(m *telegram.NewMessage) error {
 		}
 	}
 
-	logMessage(fmt.Sprintf(locales.Tr("gban.log_unbanned"), userID, Name))
-	_, err := msg.Edit(fmt.Sprintf(locales.Tr("gban.unbanned"), userID, Name, success))
+	logTo(logger.CategoryModeration, fmt.Sprintf(locales.Tr("gban.log_unbanned"), userID, Name))
+	_, err := msg.Edit(fmt.Sprintf(tr(m, "gban.unbanned"), userID, Name, success))
 	return err
 }
 
 func gbanned(m *telegram.NewMessage) error {
-	data := db.Get("GBANS")
-	if data == "" {
-		_, err := eOR(m, locales.Tr("gban.list_empty"))
+	banMap, err := listGbans()
+	if err != nil || len(banMap) == 0 {
+		_, err := eOR(m, tr(m, "gban.list_empty"))
 		return err
 	}
 
-	banMap := make(map[int64]BanInfo)
-	json.Unmarshal([]byte(data), &banMap)
-
-	msg, _ := eOR(m, locales.Tr("gban.fetching"))
+	msg, _ := eOR(m, tr(m, "gban.fetching"))
 
-	response := locales.Tr("gban.list_header") + "\n"
+	response := tr(m, "gban.list_header") + "\n"
 	for userID, info := range banMap {
-		response += fmt.Sprintf(locales.Tr("gban.list_entry"), userID, info.Reason) + "\n\n"
+		response += fmt.Sprintf(tr(m, "gban.list_entry"), userID, info.Reason) + "\n\n"
 	}
 
-	_, err := msg.Edit(response)
+	_, err = msg.Edit(response)
 	return err
 }
 
@@ -125,24 +161,24 @@ func toggleAntispam(m *telegram.NewMessage) error {
 
 	if args == "" {
 		if db.SIsMember("ANTISPAM", m.Chat.ID) {
-			_, err := eOR(m, locales.Tr("gban.antispam_off"))
+			_, err := eOR(m, tr(m, "gban.antispam_off"))
 			return err
 		}
-		_, err := eOR(m, locales.Tr("gban.antispam_on"))
+		_, err := eOR(m, tr(m, "gban.antispam_on"))
 		return err
 	}
 
 	switch args {
 	case "enable":
 		db.SRem("ANTISPAM", m.Chat.ID)
-		_, err := m.Reply(locales.Tr("gban.antispam_enabled"))
+		_, err := m.Reply(tr(m, "gban.antispam_enabled"))
 		return err
 	case "disable":
 		db.SAdd("ANTISPAM", m.Chat.ID)
-		_, err := m.Reply(locales.Tr("gban.antispam_disabled"))
+		_, err := m.Reply(tr(m, "gban.antispam_disabled"))
 		return err
 	default:
-		_, err := m.Reply(locales.Tr("gban.antispam_usage"))
+		_, err := m.Reply(tr(m, "gban.antispam_usage"))
 		return err
 	}
 }
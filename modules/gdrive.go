@@ -2,7 +2,6 @@ package modules
 
 import (
 	"NovaUserbot/db"
-	"NovaUserbot/locales"
 	"NovaUserbot/logger"
 	"context"
 	"encoding/json"
@@ -123,13 +122,13 @@ func getValidGDriveToken(config *GDriveConfig) (string, error) {
 func gdriveSetupCommand(m *telegram.NewMessage) error {
 	args := strings.TrimSpace(m.Args())
 	if args == "" {
-		_, err := eOR(m, locales.Tr("gdrive.setup_usage"))
+		_, err := eOR(m, tr(m, "gdrive.setup_usage"))
 		return err
 	}
 
 	parts := strings.SplitN(args, " ", 2)
 	if len(parts) < 2 {
-		_, err := eOR(m, locales.Tr("gdrive.setup_usage"))
+		_, err := eOR(m, tr(m, "gdrive.setup_usage"))
 		return err
 	}
 
@@ -142,34 +141,34 @@ func gdriveSetupCommand(m *telegram.NewMessage) error {
 	}
 
 	if err := saveGDriveConfig(config); err != nil {
-		_, err := eOR(m, locales.Tr("gdrive.setup_error"))
+		_, err := eOR(m, tr(m, "gdrive.setup_error"))
 		return err
 	}
 
 	oauth2Config := getGDriveOAuth2Config(config)
 	authURL := oauth2Config.AuthCodeURL("state-token", oauth2.AccessTypeOffline)
 
-	_, err := eOR(m, fmt.Sprintf(locales.Tr("gdrive.auth_url"), authURL))
+	_, err := eOR(m, fmt.Sprintf(tr(m, "gdrive.auth_url"), authURL))
 	return err
 }
 
 func gdriveAuthCommand(m *telegram.NewMessage) error {
 	code := strings.TrimSpace(m.Args())
 	if code == "" {
-		_, err := eOR(m, locales.Tr("gdrive.auth_usage"))
+		_, err := eOR(m, tr(m, "gdrive.auth_usage"))
 		return err
 	}
 
 	config, err := getGDriveConfig()
 	if err != nil {
-		_, err := eOR(m, locales.Tr("gdrive.not_configured"))
+		_, err := eOR(m, tr(m, "gdrive.not_configured"))
 		return err
 	}
 
 	oauth2Config := getGDriveOAuth2Config(config)
 	token, err := oauth2Config.Exchange(context.Background(), code)
 	if err != nil {
-		_, err := eOR(m, fmt.Sprintf(locales.Tr("gdrive.auth_error"), err.Error()))
+		_, err := eOR(m, fmt.Sprintf(tr(m, "gdrive.auth_error"), err.Error()))
 		return err
 	}
 
@@ -178,51 +177,51 @@ func gdriveAuthCommand(m *telegram.NewMessage) error {
 	config.TokenExpiry = token.Expiry.Unix()
 
 	if err := saveGDriveConfig(config); err != nil {
-		_, err := eOR(m, locales.Tr("gdrive.setup_error"))
+		_, err := eOR(m, tr(m, "gdrive.setup_error"))
 		return err
 	}
 
-	_, err = eOR(m, locales.Tr("gdrive.auth_success"))
+	_, err = eOR(m, tr(m, "gdrive.auth_success"))
 	return err
 }
 
 func gdriveUploadCommand(m *telegram.NewMessage) error {
 	if !m.IsReply() {
-		_, err := eOR(m, locales.Tr("gdrive.reply_to_file"))
+		_, err := eOR(m, tr(m, "gdrive.reply_to_file"))
 		return err
 	}
 
 	config, err := getGDriveConfig()
 	if err != nil {
-		_, err := eOR(m, locales.Tr("gdrive.not_configured"))
+		_, err := eOR(m, tr(m, "gdrive.not_configured"))
 		return err
 	}
 
 	reply, err := m.GetReplyMessage()
 	if err != nil {
-		_, err := eOR(m, locales.Tr("gdrive.fetch_error"))
+		_, err := eOR(m, tr(m, "gdrive.fetch_error"))
 		return err
 	}
 
 	if reply.Media() == nil {
-		_, err := eOR(m, locales.Tr("gdrive.no_media"))
+		_, err := eOR(m, tr(m, "gdrive.no_media"))
 		return err
 	}
 
-	msg, _ := eOR(m, locales.Tr("gdrive.downloading"))
+	msg, _ := eOR(m, tr(m, "gdrive.downloading"))
 
 	filePath, err := reply.Download()
 	if err != nil {
-		_, err := msg.Edit(locales.Tr("gdrive.download_error"))
+		_, err := msg.Edit(tr(m, "gdrive.download_error"))
 		return err
 	}
 	defer os.Remove(filePath)
 
-	msg.Edit(locales.Tr("gdrive.uploading"))
+	msg.Edit(tr(m, "gdrive.uploading"))
 
 	token, err := getValidGDriveToken(config)
 	if err != nil {
-		_, err := msg.Edit(fmt.Sprintf(locales.Tr("gdrive.token_error"), err.Error()))
+		_, err := msg.Edit(fmt.Sprintf(tr(m, "gdrive.token_error"), err.Error()))
 		return err
 	}
 
@@ -234,11 +233,11 @@ func gdriveUploadCommand(m *telegram.NewMessage) error {
 
 	fileLink, err := uploadToGDrive(filePath, fileName, token)
 	if err != nil {
-		_, err := msg.Edit(fmt.Sprintf(locales.Tr("gdrive.upload_error"), err.Error()))
+		_, err := msg.Edit(fmt.Sprintf(tr(m, "gdrive.upload_error"), err.Error()))
 		return err
 	}
 
-	_, err = msg.Edit(fmt.Sprintf(locales.Tr("gdrive.upload_success"), fileName, fileLink))
+	_, err = msg.Edit(fmt.Sprintf(tr(m, "gdrive.upload_success"), fileName, fileLink))
 	return err
 }
 
@@ -320,15 +319,15 @@ func uploadToGDrive(filePath, fileName, token string) (string, error) {
 func gdriveListCommand(m *telegram.NewMessage) error {
 	config, err := getGDriveConfig()
 	if err != nil {
-		_, err := eOR(m, locales.Tr("gdrive.not_configured"))
+		_, err := eOR(m, tr(m, "gdrive.not_configured"))
 		return err
 	}
 
-	msg, _ := eOR(m, locales.Tr("gdrive.fetching"))
+	msg, _ := eOR(m, tr(m, "gdrive.fetching"))
 
 	token, err := getValidGDriveToken(config)
 	if err != nil {
-		_, err := msg.Edit(fmt.Sprintf(locales.Tr("gdrive.token_error"), err.Error()))
+		_, err := msg.Edit(fmt.Sprintf(tr(m, "gdrive.token_error"), err.Error()))
 		return err
 	}
 
@@ -346,23 +345,23 @@ func gdriveListCommand(m *telegram.NewMessage) error {
 	httpClient := &http.Client{Timeout: 30 * time.Second}
 	resp, err := httpClient.Do(req)
 	if err != nil {
-		_, err := msg.Edit(locales.Tr("gdrive.list_error"))
+		_, err := msg.Edit(tr(m, "gdrive.list_error"))
 		return err
 	}
 	defer resp.Body.Close()
 
 	var result GDriveFileList
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		_, err := msg.Edit(locales.Tr("gdrive.list_error"))
+		_, err := msg.Edit(tr(m, "gdrive.list_error"))
 		return err
 	}
 
 	if len(result.Files) == 0 {
-		_, err := msg.Edit(locales.Tr("gdrive.no_files"))
+		_, err := msg.Edit(tr(m, "gdrive.no_files"))
 		return err
 	}
 
-	text := locales.Tr("gdrive.list_header")
+	text := tr(m, "gdrive.list_header")
 	for _, file := range result.Files {
 		text += fmt.Sprintf("\n• <a href='%s'>%s</a>", file.WebViewLink, file.Name)
 	}
@@ -374,21 +373,21 @@ func gdriveListCommand(m *telegram.NewMessage) error {
 func gdriveSearchCommand(m *telegram.NewMessage) error {
 	query := strings.TrimSpace(m.Args())
 	if query == "" {
-		_, err := eOR(m, locales.Tr("gdrive.search_usage"))
+		_, err := eOR(m, tr(m, "gdrive.search_usage"))
 		return err
 	}
 
 	config, err := getGDriveConfig()
 	if err != nil {
-		_, err := eOR(m, locales.Tr("gdrive.not_configured"))
+		_, err := eOR(m, tr(m, "gdrive.not_configured"))
 		return err
 	}
 
-	msg, _ := eOR(m, locales.Tr("gdrive.searching"))
+	msg, _ := eOR(m, tr(m, "gdrive.searching"))
 
 	token, err := getValidGDriveToken(config)
 	if err != nil {
-		_, err := msg.Edit(fmt.Sprintf(locales.Tr("gdrive.token_error"), err.Error()))
+		_, err := msg.Edit(fmt.Sprintf(tr(m, "gdrive.token_error"), err.Error()))
 		return err
 	}
 
@@ -403,23 +402,23 @@ func gdriveSearchCommand(m *telegram.NewMessage) error {
 	httpClient := &http.Client{Timeout: 30 * time.Second}
 	resp, err := httpClient.Do(req)
 	if err != nil {
-		_, err := msg.Edit(locales.Tr("gdrive.search_error"))
+		_, err := msg.Edit(tr(m, "gdrive.search_error"))
 		return err
 	}
 	defer resp.Body.Close()
 
 	var result GDriveFileList
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		_, err := msg.Edit(locales.Tr("gdrive.search_error"))
+		_, err := msg.Edit(tr(m, "gdrive.search_error"))
 		return err
 	}
 
 	if len(result.Files) == 0 {
-		_, err := msg.Edit(fmt.Sprintf(locales.Tr("gdrive.no_results"), query))
+		_, err := msg.Edit(fmt.Sprintf(tr(m, "gdrive.no_results"), query))
 		return err
 	}
 
-	text := fmt.Sprintf(locales.Tr("gdrive.search_header"), query)
+	text := fmt.Sprintf(tr(m, "gdrive.search_header"), query)
 	for _, file := range result.Files {
 		text += fmt.Sprintf("\n• <a href='%s'>%s</a>", file.WebViewLink, file.Name)
 	}
@@ -431,7 +430,7 @@ func gdriveSearchCommand(m *telegram.NewMessage) error {
 func gdriveDownloadCommand(m *telegram.NewMessage) error {
 	fileID := strings.TrimSpace(m.Args())
 	if fileID == "" {
-		_, err := eOR(m, locales.Tr("gdrive.download_usage"))
+		_, err := eOR(m, tr(m, "gdrive.download_usage"))
 		return err
 	}
 
@@ -447,15 +446,15 @@ func gdriveDownloadCommand(m *telegram.NewMessage) error {
 
 	config, err := getGDriveConfig()
 	if err != nil {
-		_, err := eOR(m, locales.Tr("gdrive.not_configured"))
+		_, err := eOR(m, tr(m, "gdrive.not_configured"))
 		return err
 	}
 
-	msg, _ := eOR(m, locales.Tr("gdrive.downloading"))
+	msg, _ := eOR(m, tr(m, "gdrive.downloading"))
 
 	token, err := getValidGDriveToken(config)
 	if err != nil {
-		_, err := msg.Edit(fmt.Sprintf(locales.Tr("gdrive.token_error"), err.Error()))
+		_, err := msg.Edit(fmt.Sprintf(tr(m, "gdrive.token_error"), err.Error()))
 		return err
 	}
 
@@ -465,14 +464,14 @@ func gdriveDownloadCommand(m *telegram.NewMessage) error {
 	client := &http.Client{Timeout: 30 * time.Second}
 	metaResp, err := client.Do(metaReq)
 	if err != nil {
-		_, err := msg.Edit(locales.Tr("gdrive.file_not_found"))
+		_, err := msg.Edit(tr(m, "gdrive.file_not_found"))
 		return err
 	}
 	defer metaResp.Body.Close()
 
 	var fileMeta GDriveFile
 	if err := json.NewDecoder(metaResp.Body).Decode(&fileMeta); err != nil {
-		_, err := msg.Edit(locales.Tr("gdrive.file_not_found"))
+		_, err := msg.Edit(tr(m, "gdrive.file_not_found"))
 		return err
 	}
 
@@ -482,7 +481,7 @@ func gdriveDownloadCommand(m *telegram.NewMessage) error {
 	downloadClient := &http.Client{Timeout: 10 * time.Minute}
 	downloadResp, err := downloadClient.Do(downloadReq)
 	if err != nil {
-		_, err := msg.Edit(locales.Tr("gdrive.download_failed"))
+		_, err := msg.Edit(tr(m, "gdrive.download_failed"))
 		return err
 	}
 	defer downloadResp.Body.Close()
@@ -490,7 +489,7 @@ func gdriveDownloadCommand(m *telegram.NewMessage) error {
 	tmpFile := filepath.Join("/tmp", fileMeta.Name)
 	outFile, err := os.Create(tmpFile)
 	if err != nil {
-		_, err := msg.Edit(locales.Tr("gdrive.download_failed"))
+		_, err := msg.Edit(tr(m, "gdrive.download_failed"))
 		return err
 	}
 
@@ -498,20 +497,20 @@ func gdriveDownloadCommand(m *telegram.NewMessage) error {
 	outFile.Close()
 	if err != nil {
 		os.Remove(tmpFile)
-		_, err := msg.Edit(locales.Tr("gdrive.download_failed"))
+		_, err := msg.Edit(tr(m, "gdrive.download_failed"))
 		return err
 	}
 
-	msg.Edit(locales.Tr("gdrive.uploading_telegram"))
+	msg.Edit(tr(m, "gdrive.uploading_telegram"))
 
-	_, err = m.Respond(fmt.Sprintf(locales.Tr("gdrive.file_caption"), fileMeta.Name), &telegram.SendOptions{
+	_, err = m.Respond(fmt.Sprintf(tr(m, "gdrive.file_caption"), fileMeta.Name), &telegram.SendOptions{
 		Media: tmpFile,
 	})
 	os.Remove(tmpFile)
 
 	if err != nil {
 		logger.Errorf("Failed to send file: %v", err)
-		_, err := msg.Edit(locales.Tr("gdrive.send_error"))
+		_, err := msg.Edit(tr(m, "gdrive.send_error"))
 		return err
 	}
 
@@ -522,7 +521,7 @@ func gdriveDownloadCommand(m *telegram.NewMessage) error {
 func gdriveDeleteCommand(m *telegram.NewMessage) error {
 	fileID := strings.TrimSpace(m.Args())
 	if fileID == "" {
-		_, err := eOR(m, locales.Tr("gdrive.delete_usage"))
+		_, err := eOR(m, tr(m, "gdrive.delete_usage"))
 		return err
 	}
 
@@ -538,15 +537,15 @@ func gdriveDeleteCommand(m *telegram.NewMessage) error {
 
 	config, err := getGDriveConfig()
 	if err != nil {
-		_, err := eOR(m, locales.Tr("gdrive.not_configured"))
+		_, err := eOR(m, tr(m, "gdrive.not_configured"))
 		return err
 	}
 
-	msg, _ := eOR(m, locales.Tr("gdrive.deleting"))
+	msg, _ := eOR(m, tr(m, "gdrive.deleting"))
 
 	token, err := getValidGDriveToken(config)
 	if err != nil {
-		_, err := msg.Edit(fmt.Sprintf(locales.Tr("gdrive.token_error"), err.Error()))
+		_, err := msg.Edit(fmt.Sprintf(tr(m, "gdrive.token_error"), err.Error()))
 		return err
 	}
 
@@ -556,17 +555,17 @@ func gdriveDeleteCommand(m *telegram.NewMessage) error {
 	client := &http.Client{Timeout: 30 * time.Second}
 	resp, err := client.Do(req)
 	if err != nil {
-		_, err := msg.Edit(locales.Tr("gdrive.delete_error"))
+		_, err := msg.Edit(tr(m, "gdrive.delete_error"))
 		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
-		_, err := msg.Edit(locales.Tr("gdrive.delete_error"))
+		_, err := msg.Edit(tr(m, "gdrive.delete_error"))
 		return err
 	}
 
-	_, err = msg.Edit(locales.Tr("gdrive.delete_success"))
+	_, err = msg.Edit(tr(m, "gdrive.delete_success"))
 	return err
 }
 
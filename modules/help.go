@@ -4,6 +4,7 @@ import (
 	"NovaUserbot/locales"
 	"NovaUserbot/logger"
 	"NovaUserbot/utils"
+	"errors"
 	"sort"
 	"strconv"
 	"strings"
@@ -11,6 +12,8 @@ import (
 	"github.com/amarnathcjd/gogram/telegram"
 )
 
+var errNoAssistant = errors.New("no assistant bot")
+
 type Handler struct {
 	Command       string
 	Description   string
@@ -103,13 +106,13 @@ func findBestMatch(query string) (string, []Handler, bool) {
 	return "", nil, false
 }
 
-func formatModuleHelp(module string, handlers []Handler) string {
-	msg := strings.Replace(locales.Tr("help.commands_header"), "%s", module, 1)
+func formatModuleHelp(lang, module string, handlers []Handler) string {
+	msg := locales.Format(locales.TrLang(lang, "help.commands_header"), locales.Args{"module": module})
 	for _, h := range handlers {
-		entry := locales.Tr("help.command_entry")
-		entry = strings.Replace(entry, "%s", h.Command, 1)
-		entry = strings.Replace(entry, "%s", h.Description, 1)
-		msg += entry
+		msg += locales.Format(locales.TrLang(lang, "help.command_entry"), locales.Args{
+			"command":     h.Command,
+			"description": h.Description,
+		})
 	}
 	return msg
 }
@@ -123,8 +126,8 @@ func HelpInline(i *telegram.InlineQuery) error {
 		if client != nil && client.Me() != nil && client.Me().Username != "" {
 			ownerLink = "t.me/" + client.Me().Username
 		}
-		b.Article(locales.Tr("help.not_allowed_title"), locales.Tr("help.not_allowed_desc"), locales.Tr("help.not_allowed_desc"),
-			&telegram.ArticleOptions{ReplyMarkup: telegram.NewKeyboard().NewRow(1, btn.URL(locales.Tr("help.owner_btn"), ownerLink)).Build()})
+		b.Article(locales.TrUser(i.SenderID, "help.not_allowed_title"), locales.TrUser(i.SenderID, "help.not_allowed_desc"), locales.TrUser(i.SenderID, "help.not_allowed_desc"),
+			&telegram.ArticleOptions{ReplyMarkup: telegram.NewKeyboard().NewRow(1, btn.URL(locales.TrUser(i.SenderID, "help.owner_btn"), ownerLink)).Build()})
 		i.Answer(b.Results())
 		return nil
 	}
@@ -137,14 +140,14 @@ func HelpInline(i *telegram.InlineQuery) error {
 
 		handlers, exists := HelpMap[module]
 		if !exists {
-			b.Article(locales.Tr("help.not_allowed_title"), locales.Tr("help.module_not_found"), locales.Tr("help.module_not_found"), nil)
+			b.Article(locales.TrUser(i.SenderID, "help.not_allowed_title"), locales.TrUser(i.SenderID, "help.module_not_found"), locales.TrUser(i.SenderID, "help.module_not_found"), nil)
 			i.Answer(b.Results())
 			return nil
 		}
 
-		msg := formatModuleHelp(module, handlers)
+		msg := formatModuleHelp(locales.GetInstance().GetUserLanguage(i.SenderID), module, handlers)
 		replyMarkup := telegram.NewKeyboard().NewRow(1,
-			telegram.ButtonBuilder{}.Data(locales.Tr("help.back_btn"), "help_page_0"),
+			telegram.ButtonBuilder{}.Data(locales.TrUser(i.SenderID, "help.back_btn"), "help_page_0"),
 		).Build()
 
 		b.Article(module+" Help", "Help for "+module, msg, &telegram.ArticleOptions{ReplyMarkup: replyMarkup, ID: "help_" + strings.ReplaceAll(module, " ", "_")})
@@ -152,7 +155,7 @@ func HelpInline(i *telegram.InlineQuery) error {
 		return nil
 	}
 
-	b.Article("Help Menu", "Available Help Menu", locales.Tr("help.menu_title"), &telegram.ArticleOptions{ReplyMarkup: PaginateHelp(0), ID: "help"})
+	b.Article("Help Menu", "Available Help Menu", locales.TrUser(i.SenderID, "help.menu_title"), &telegram.ArticleOptions{ReplyMarkup: PaginateHelp(0), ID: "help"})
 	i.Answer(b.Results())
 	return nil
 }
@@ -179,6 +182,15 @@ func PaginateHelp(index int) *telegram.ReplyInlineMarkup {
 	return telegram.NewKeyboard().NewGrid(4, 2, btns...).Build()
 }
 
+// assistantInline queries the assistant bot inline. Without one it fails, so
+// callers fall back to plain text.
+func assistantInline(m *telegram.NewMessage, query string) (*telegram.MessagesBotResults, error) {
+	if tgbot == nil {
+		return nil, errNoAssistant
+	}
+	return m.Client.InlineQuery(tbotId, &telegram.InlineOptions{Query: query})
+}
+
 func HelpCmd(m *telegram.NewMessage) error {
 	args := strings.TrimSpace(m.Args())
 
@@ -186,10 +198,10 @@ func HelpCmd(m *telegram.NewMessage) error {
 		module, _, found := findBestMatch(args)
 		if found {
 			inlineQuery := "help " + strings.ReplaceAll(module, " ", "_")
-			results, err := m.Client.InlineQuery(tbotId, &telegram.InlineOptions{Query: inlineQuery})
+			results, err := assistantInline(m, inlineQuery)
 			if err != nil || len(results.Results) == 0 {
 				handlers := HelpMap[module]
-				msg := formatModuleHelp(module, handlers)
+				msg := formatModuleHelp(msgLang(m), module, handlers)
 				_, err := eOR(m, msg)
 				return err
 			}
@@ -204,23 +216,23 @@ func HelpCmd(m *telegram.NewMessage) error {
 			if err != nil {
 				logger.Error("Help module error:", err)
 				handlers := HelpMap[module]
-				msg := formatModuleHelp(module, handlers)
+				msg := formatModuleHelp(msgLang(m), module, handlers)
 				_, _ = eOR(m, msg)
 			}
 			return err
 		}
 
-		_, err := eOR(m, locales.Tr("help.module_not_found"))
+		_, err := eOR(m, tr(m, "help.module_not_found"))
 		return err
 	}
 
-	results, err := m.Client.InlineQuery(tbotId, &telegram.InlineOptions{Query: "help"})
+	results, err := assistantInline(m, "help")
 	if err != nil || len(results.Results) == 0 {
-		text := locales.Tr("help.menu_title") + "\n\n"
+		text := tr(m, "help.menu_title") + "\n\n"
 		for _, mod := range ModuleList {
 			text += "• <b>" + mod + "</b>\n"
 		}
-		text += "\n" + locales.Tr("help.usage_hint")
+		text += "\n" + tr(m, "help.usage_hint")
 		_, err := eOR(m, text)
 		return err
 	}
@@ -234,7 +246,7 @@ func HelpCmd(m *telegram.NewMessage) error {
 	})
 	if err != nil {
 		logger.Error("Help error:", err)
-		eOR(m, locales.Tr("help.fetch_error"))
+		eOR(m, tr(m, "help.fetch_error"))
 	}
 	return err
 }
@@ -243,7 +255,7 @@ func HelpCbk(cb *telegram.InlineCallbackQuery) error {
 	data := string(cb.Data)
 
 	if !utils.IsIn64Array(sudoers, cb.Sender.ID) && cb.Sender.ID != ubId {
-		cb.Client.AnswerCallbackQuery(cb.QueryID, locales.Tr("help.not_allowed_desc"), &telegram.CallbackOptions{Alert: true})
+		cb.Client.AnswerCallbackQuery(cb.QueryID, locales.TrUser(cb.SenderID, "help.not_allowed_desc"), &telegram.CallbackOptions{Alert: true})
 		return nil
 	}
 
@@ -255,10 +267,10 @@ func HelpCbk(cb *telegram.InlineCallbackQuery) error {
 			return nil
 		}
 
-		msg := formatModuleHelp(module, handlers)
+		msg := formatModuleHelp(locales.GetInstance().GetUserLanguage(cb.SenderID), module, handlers)
 
 		replyMarkup := telegram.NewKeyboard().NewRow(1,
-			telegram.ButtonBuilder{}.Data(locales.Tr("help.back_btn"), "help_page_"+parts[2]),
+			telegram.ButtonBuilder{}.Data(locales.TrUser(cb.SenderID, "help.back_btn"), "help_page_"+parts[2]),
 		).Build()
 
 		cb.Edit(msg, &telegram.SendOptions{ReplyMarkup: replyMarkup, ParseMode: "html"})
@@ -268,7 +280,7 @@ func HelpCbk(cb *telegram.InlineCallbackQuery) error {
 	if strings.Contains(data, "help_page_") {
 		parts := strings.Split(data, "_")
 		index, _ := strconv.Atoi(parts[2])
-		cb.Edit(locales.Tr("help.menu_title"), &telegram.SendOptions{ReplyMarkup: PaginateHelp(index), ParseMode: "html"})
+		cb.Edit(locales.TrUser(cb.SenderID, "help.menu_title"), &telegram.SendOptions{ReplyMarkup: PaginateHelp(index), ParseMode: "html"})
 	}
 
 	return nil
@@ -276,7 +288,9 @@ func HelpCbk(cb *telegram.InlineCallbackQuery) error {
 
 func LoadHelpHandler(c *telegram.Client) {
 	LoadModulesOrder()
-	tgbot.AddInlineCallbackHandler("help", HelpCbk)
-	tgbot.On("inline:help", HelpInline)
+	if tgbot != nil {
+		tgbot.AddInlineCallbackHandler("help", HelpCbk)
+		tgbot.On("inline:help", HelpInline)
+	}
 	AddHandler(&Handler{Command: "help", Func: HelpCmd, Description: "Show help menu or search modules/commands", ModuleName: "Core"}, c)
 }
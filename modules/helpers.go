@@ -2,6 +2,7 @@ package modules
 
 import (
 	"NovaUserbot/db"
+	"NovaUserbot/locales"
 	"NovaUserbot/logger"
 	"NovaUserbot/utils"
 	"fmt"
@@ -17,24 +18,33 @@ import (
 
 type TgLogSender struct{}
 
-func (t *TgLogSender) SendLog(msg string) error {
-	return logMessage(msg)
+func (t *TgLogSender) SendLog(category logger.Category, msg string) error {
+	return logTo(category, msg)
 }
 
-func logMessage(msg string) error {
+// logClient returns the client that sends logs and notifications: the
+// assistant bot, or the userbot itself when running without one.
+func logClient() *telegram.Client {
+	if tgbot != nil {
+		return tgbot
+	}
+	return client
+}
+
+func logMessage(msg string, opts ...*telegram.SendOptions) error {
 	logChatStr := db.Get("LOG_CHAT")
 	logChat := utils.StringToInt64(logChatStr)
 	if logChat == 0 {
 		logChat = ubId
 	}
 
-	peer, err := tgbot.GetSendablePeer(logChat)
+	peer, err := logClient().GetSendablePeer(logChat)
 	if err != nil {
 		logger.Errorf("logMessage peer error: %v", err)
 		return err
 	}
 
-	_, err = tgbot.SendMessage(peer, msg)
+	_, err = logClient().SendMessage(peer, msg, opts...)
 	return err
 }
 
@@ -55,7 +65,7 @@ func IsAdmin(user, chat int64) bool {
 // ============================================================================
 
 func msgLink(m *telegram.NewMessage) string {
-	if m.IsPrivate() {
+	if m.IsPrivate() || m.Channel == nil {
 		return ""
 	}
 	if m.Channel.Username != "" {
@@ -64,6 +74,29 @@ func msgLink(m *telegram.NewMessage) string {
 	return fmt.Sprintf("https://t.me/c/%d/%d", m.ChatID(), m.ID)
 }
 
+// msgLang is the language that applies to m: the chat's override, then the
+// sender's own language, then the global one.
+func msgLang(m *telegram.NewMessage) string {
+	return locales.GetInstance().Resolve(m.SenderID(), m.ChatID())
+}
+
+func tr(m *telegram.NewMessage, key string) string {
+	return locales.TrLang(msgLang(m), key)
+}
+
+// chatTitle returns a human readable label for the chat a message was sent in
+func chatTitle(m *telegram.NewMessage) string {
+	switch {
+	case m.Channel != nil && m.Channel.Title != "":
+		return m.Channel.Title
+	case m.Chat != nil && m.Chat.Title != "":
+		return m.Chat.Title
+	case m.IsPrivate():
+		return locales.Tr("common.private_chat")
+	}
+	return locales.Tr("common.unknown_chat")
+}
+
 // eOR edits message if sent by owner, otherwise replies
 func eOR(m *telegram.NewMessage, text string, opts ...telegram.SendOptions) (*telegram.NewMessage, error) {
 	ptrs := make([]*telegram.SendOptions, len(opts))
@@ -1,7 +1,6 @@
 package modules
 
 import (
-	"NovaUserbot/locales"
 	"NovaUserbot/utils"
 	"fmt"
 	"os"
@@ -146,7 +145,7 @@ func imageSharpenCommand(m *telegram.NewMessage) error {
 func imageResizeCommand(m *telegram.NewMessage) error {
 	args := strings.TrimSpace(m.Args())
 	if args == "" {
-		_, err := eOR(m, locales.Tr("imagetools.resize_usage"))
+		_, err := eOR(m, tr(m, "imagetools.resize_usage"))
 		return err
 	}
 
@@ -165,12 +164,12 @@ func imageResizeCommand(m *telegram.NewMessage) error {
 func colorSampleCommand(m *telegram.NewMessage) error {
 	args := strings.TrimSpace(m.Args())
 	if args == "" {
-		_, err := eOR(m, locales.Tr("imagetools.csample_usage"))
+		_, err := eOR(m, tr(m, "imagetools.csample_usage"))
 		return err
 	}
 
 	if !checkImageMagick() {
-		_, err := eOR(m, locales.Tr("imagetools.imagemagick_missing"))
+		_, err := eOR(m, tr(m, "imagetools.imagemagick_missing"))
 		return err
 	}
 
@@ -181,11 +180,11 @@ func colorSampleCommand(m *telegram.NewMessage) error {
 	cmd := fmt.Sprintf("convert -size 200x100 xc:%q %q", color, outputPath)
 	_, err := utils.RunCommand(cmd)
 	if err != nil {
-		_, err := eOR(m, locales.Tr("imagetools.invalid_color"))
+		_, err := eOR(m, tr(m, "imagetools.invalid_color"))
 		return err
 	}
 
-	_, err = m.Respond(fmt.Sprintf(locales.Tr("imagetools.csample_result"), color), &telegram.SendOptions{
+	_, err = m.Respond(fmt.Sprintf(tr(m, "imagetools.csample_result"), color), &telegram.SendOptions{
 		Media: outputPath,
 	})
 	if m.Sender.ID == ubId {
@@ -196,39 +195,39 @@ func colorSampleCommand(m *telegram.NewMessage) error {
 
 func processImage(m *telegram.NewMessage, operation string, processor func(inputPath, outputPath string) error) error {
 	if !m.IsReply() {
-		_, err := eOR(m, locales.Tr("imagetools.reply_required"))
+		_, err := eOR(m, tr(m, "imagetools.reply_required"))
 		return err
 	}
 
 	if !checkImageMagick() {
-		_, err := eOR(m, locales.Tr("imagetools.imagemagick_missing"))
+		_, err := eOR(m, tr(m, "imagetools.imagemagick_missing"))
 		return err
 	}
 
 	reply, err := m.GetReplyMessage()
 	if err != nil {
-		_, err := eOR(m, locales.Tr("imagetools.fetch_error"))
+		_, err := eOR(m, tr(m, "imagetools.fetch_error"))
 		return err
 	}
 
 	if reply.Photo() == nil && reply.Sticker() == nil && reply.Document() == nil {
-		_, err := eOR(m, locales.Tr("imagetools.no_image"))
+		_, err := eOR(m, tr(m, "imagetools.no_image"))
 		return err
 	}
 
-	msg, _ := eOR(m, locales.Tr("imagetools.downloading"))
+	msg, _ := eOR(m, tr(m, "imagetools.downloading"))
 
 	inputPath, err := reply.Download()
 	if err != nil {
 		if msg != nil {
-			msg.Edit(locales.Tr("imagetools.download_error"))
+			msg.Edit(tr(m, "imagetools.download_error"))
 		}
 		return err
 	}
 	defer os.Remove(inputPath)
 
 	if msg != nil {
-		msg.Edit(locales.Tr("imagetools.processing"))
+		msg.Edit(tr(m, "imagetools.processing"))
 	}
 
 	ext := filepath.Ext(inputPath)
@@ -240,19 +239,19 @@ func processImage(m *telegram.NewMessage, operation string, processor func(input
 
 	if err := processor(inputPath, outputPath); err != nil {
 		if msg != nil {
-			msg.Edit(fmt.Sprintf(locales.Tr("imagetools.process_error"), err.Error()))
+			msg.Edit(fmt.Sprintf(tr(m, "imagetools.process_error"), err.Error()))
 		}
 		return err
 	}
 
 	if msg != nil {
-		msg.Edit(locales.Tr("imagetools.uploading"))
+		msg.Edit(tr(m, "imagetools.uploading"))
 	}
 
 	_, err = m.Respond("", &telegram.SendOptions{Media: outputPath})
 	if err != nil {
 		if msg != nil {
-			msg.Edit(locales.Tr("imagetools.upload_error"))
+			msg.Edit(tr(m, "imagetools.upload_error"))
 		}
 		return err
 	}
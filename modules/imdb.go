@@ -385,16 +385,16 @@ func ImDBInlineSearchHandler(m *telegram.InlineQuery) error {
 	b := m.Builder()
 
 	if !utils.IsIn64Array(sudoers, m.Sender.ID) && m.Sender.ID != ubId {
-		b.Article(locales.Tr("imdb.not_allowed_title"), locales.Tr("imdb.not_allowed"), locales.Tr("imdb.not_allowed"))
+		b.Article(locales.TrUser(m.SenderID, "imdb.not_allowed_title"), locales.TrUser(m.SenderID, "imdb.not_allowed"), locales.TrUser(m.SenderID, "imdb.not_allowed"))
 		m.Answer(b.Results())
 		return nil
 	}
 
 	if m.Args() == "" {
-		b.Article(locales.Tr("imdb.no_query_title"), locales.Tr("imdb.no_query_desc"), locales.Tr("imdb.no_query_title"), &telegram.ArticleOptions{
+		b.Article(locales.TrUser(m.SenderID, "imdb.no_query_title"), locales.TrUser(m.SenderID, "imdb.no_query_desc"), locales.TrUser(m.SenderID, "imdb.no_query_title"), &telegram.ArticleOptions{
 			ReplyMarkup: telegram.Button.Keyboard(
 				telegram.Button.Row(
-					telegram.Button.SwitchInline(locales.Tr("imdb.search_btn"), true, "imdb "),
+					telegram.Button.SwitchInline(locales.TrUser(m.SenderID, "imdb.search_btn"), true, "imdb "),
 				),
 			),
 		})
@@ -404,10 +404,10 @@ func ImDBInlineSearchHandler(m *telegram.InlineQuery) error {
 
 	results, err := quickSearchImdb(m.Args())
 	if err != nil {
-		b.Article(locales.Tr("imdb.error_title"), locales.Tr("imdb.error_desc"), locales.Tr("imdb.error_title"), &telegram.ArticleOptions{
+		b.Article(locales.TrUser(m.SenderID, "imdb.error_title"), locales.TrUser(m.SenderID, "imdb.error_desc"), locales.TrUser(m.SenderID, "imdb.error_title"), &telegram.ArticleOptions{
 			ReplyMarkup: telegram.Button.Keyboard(
 				telegram.Button.Row(
-					telegram.Button.SwitchInline(locales.Tr("imdb.search_again_btn"), true, "imdb "),
+					telegram.Button.SwitchInline(locales.TrUser(m.SenderID, "imdb.search_again_btn"), true, "imdb "),
 				),
 			),
 		})
@@ -416,10 +416,10 @@ func ImDBInlineSearchHandler(m *telegram.InlineQuery) error {
 	}
 
 	if len(results) == 0 {
-		b.Article(locales.Tr("imdb.no_results_title"), locales.Tr("imdb.no_results_desc"), locales.Tr("imdb.no_results_title"), &telegram.ArticleOptions{
+		b.Article(locales.TrUser(m.SenderID, "imdb.no_results_title"), locales.TrUser(m.SenderID, "imdb.no_results_desc"), locales.TrUser(m.SenderID, "imdb.no_results_title"), &telegram.ArticleOptions{
 			ReplyMarkup: telegram.Button.Keyboard(
 				telegram.Button.Row(
-					telegram.Button.SwitchInline(locales.Tr("imdb.search_again_btn"), true, "imdb "),
+					telegram.Button.SwitchInline(locales.TrUser(m.SenderID, "imdb.search_again_btn"), true, "imdb "),
 				),
 			),
 		})
@@ -437,9 +437,9 @@ func ImDBInlineSearchHandler(m *telegram.InlineQuery) error {
 		)
 	}
 
-	kyb.AddRow(telegram.Button.SwitchInline(locales.Tr("imdb.search_again_btn"), true, "imdb "))
+	kyb.AddRow(telegram.Button.SwitchInline(locales.TrUser(m.SenderID, "imdb.search_again_btn"), true, "imdb "))
 
-	b.Article(locales.Tr("imdb.search_results_title"), fmt.Sprintf(locales.Tr("imdb.search_results_desc"), len(results), m.Args()), locales.Tr("imdb.search_results_text"), &telegram.ArticleOptions{
+	b.Article(locales.TrUser(m.SenderID, "imdb.search_results_title"), fmt.Sprintf(locales.TrUser(m.SenderID, "imdb.search_results_desc"), len(results), m.Args()), locales.TrUser(m.SenderID, "imdb.search_results_text"), &telegram.ArticleOptions{
 		ID:          "imdb_search",
 		ReplyMarkup: kyb.Build(),
 	})
@@ -568,20 +568,20 @@ func ImdbInlineOnSendHandler(u *telegram.InlineSend) error {
 func ImdbCallbackHandler(cb *telegram.InlineCallbackQuery) error {
 
 	if !utils.IsIn64Array(sudoers, cb.Sender.ID) && cb.Sender.ID != ubId {
-		cb.Client.AnswerCallbackQuery(cb.QueryID, locales.Tr("imdb.not_allowed"), &telegram.CallbackOptions{Alert: true})
+		cb.Client.AnswerCallbackQuery(cb.QueryID, locales.TrUser(cb.SenderID, "imdb.not_allowed"), &telegram.CallbackOptions{Alert: true})
 		return nil
 	}
 
 	dt := strings.Split(string(cb.Data), "_")
 	if len(dt) != 2 {
-		cb.Client.AnswerCallbackQuery(cb.QueryID, locales.Tr("imdb.invalid_data"), &telegram.CallbackOptions{Alert: true})
+		cb.Client.AnswerCallbackQuery(cb.QueryID, locales.TrUser(cb.SenderID, "imdb.invalid_data"), &telegram.CallbackOptions{Alert: true})
 		return nil
 	}
 
 	imdbID := dt[1]
 	data, err := GetIMDBTitle(imdbID)
 	if err != nil {
-		cb.Client.AnswerCallbackQuery(cb.QueryID, locales.Tr("imdb.fetch_error"), &telegram.CallbackOptions{Alert: true})
+		cb.Client.AnswerCallbackQuery(cb.QueryID, locales.TrUser(cb.SenderID, "imdb.fetch_error"), &telegram.CallbackOptions{Alert: true})
 		return nil
 	}
 
@@ -592,7 +592,7 @@ func ImdbCallbackHandler(cb *telegram.InlineCallbackQuery) error {
 			ReplyMarkup: telegram.NewKeyboard().AddRow(
 				telegram.Button.URL("🔗 IMDb Link", fmt.Sprintf("https://www.imdb.com/title/%s/", imdbID)),
 			).AddRow(
-				telegram.Button.SwitchInline(locales.Tr("imdb.search_again_btn"), true, "imdb "),
+				telegram.Button.SwitchInline(locales.TrUser(cb.SenderID, "imdb.search_again_btn"), true, "imdb "),
 			).Build(),
 		})
 	} else {
@@ -600,7 +600,7 @@ func ImdbCallbackHandler(cb *telegram.InlineCallbackQuery) error {
 			ReplyMarkup: telegram.NewKeyboard().AddRow(
 				telegram.Button.URL("🔗 IMDb Link", fmt.Sprintf("https://www.imdb.com/title/%s/", imdbID)),
 			).AddRow(
-				telegram.Button.SwitchInline(locales.Tr("imdb.search_again_btn"), true, "imdb "),
+				telegram.Button.SwitchInline(locales.TrUser(cb.SenderID, "imdb.search_again_btn"), true, "imdb "),
 			).Build(),
 		})
 	}
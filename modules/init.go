@@ -27,6 +27,18 @@ var (
 	cmdPrefix string
 )
 
+const degradedModeNote = "running without the assistant bot: logs and reminders are sent from your account " +
+	"(Saved Messages unless LOG_CHAT is set), the inline help menu and the IMDb module are disabled"
+
+func deviceConfig(d config.DeviceConfig) telegram.DeviceConfig {
+	return telegram.DeviceConfig{
+		DeviceModel:   d.Model,
+		SystemVersion: d.SystemVersion,
+		AppVersion:    d.AppVersion,
+		LangCode:      d.LangCode,
+	}
+}
+
 func InitTgClients() (*telegram.Client, error) {
 	if err := locales.Init(Db); err != nil {
 		logger.Warn("i18n init:", err)
@@ -36,10 +48,20 @@ func InitTgClients() (*telegram.Client, error) {
 		Output: logger.GetLogWriter(),
 	})
 
+	var proxy telegram.Proxy
+	if cfg.Proxy != "" {
+		var err error
+		if proxy, err = telegram.ProxyFromURL(cfg.Proxy); err != nil {
+			return nil, fmt.Errorf("invalid proxy: %v", err)
+		}
+	}
+
 	var err error
 	client, err = telegram.NewClient(telegram.ClientConfig{
 		AppID:         cfg.ApiId,
 		AppHash:       cfg.ApiHash,
+		DeviceConfig:  deviceConfig(cfg.Device),
+		Proxy:         proxy,
 		LogLevel:      telegram.LogInfo,
 		Logger:        gogramLogger,
 		StringSession: cfg.StringSession,
@@ -57,25 +79,6 @@ func InitTgClients() (*telegram.Client, error) {
 		return nil, err
 	}
 
-	tgbot, err = telegram.NewClient(telegram.ClientConfig{
-		AppID:       cfg.ApiId,
-		AppHash:     cfg.ApiHash,
-		LogLevel:    telegram.LogInfo,
-		Logger:      gogramLogger,
-		SessionName: "asstbot",
-		Session:     "asstbot.db",
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	if err = tgbot.Connect(); err != nil {
-		return nil, err
-	}
-	if err = tgbot.LoginBot(cfg.Token); err != nil {
-		return nil, err
-	}
-
 	user, err := client.GetMe()
 	if err != nil || user == nil {
 		return nil, fmt.Errorf("failed to get user info: %v", err)
@@ -83,12 +86,15 @@ func InitTgClients() (*telegram.Client, error) {
 	ubId = user.ID
 	logger.Infof("Logged in as %s (%d)", user.Username, ubId)
 
-	bot, err := tgbot.GetMe()
-	if err != nil || bot == nil {
-		return nil, fmt.Errorf("failed to get bot info: %v", err)
+	if cfg.Token == "" {
+		logger.Warn("TOKEN is not set, " + degradedModeNote)
+	} else if err := startAssistant(proxy, gogramLogger); err != nil {
+		logger.Warnf("Assistant bot login failed (%v), %s", err, degradedModeNote)
+		if tgbot != nil {
+			tgbot.Stop()
+			tgbot = nil
+		}
 	}
-	tbotId = bot.ID
-	logger.Infof("Bot logged in as %s (%d)", bot.Username, tbotId)
 
 	cmdPrefix = db.Get("CMD_HANDLER")
 	if cmdPrefix == "" {
@@ -102,24 +108,65 @@ func InitTgClients() (*telegram.Client, error) {
 	return client, nil
 }
 
+// startAssistant logs in the assistant bot. Without it the userbot still runs,
+// see logClient.
+func startAssistant(proxy telegram.Proxy, gogramLogger telegram.Logger) error {
+	var err error
+	tgbot, err = telegram.NewClient(telegram.ClientConfig{
+		AppID:        cfg.ApiId,
+		AppHash:      cfg.ApiHash,
+		DeviceConfig: deviceConfig(cfg.BotDevice),
+		Proxy:        proxy,
+		LogLevel:     telegram.LogInfo,
+		Logger:       gogramLogger,
+		SessionName:  "asstbot",
+		Session:      "asstbot.db",
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = tgbot.Connect(); err != nil {
+		return err
+	}
+	if err = tgbot.LoginBot(cfg.Token); err != nil {
+		return err
+	}
+
+	bot, err := tgbot.GetMe()
+	if err != nil || bot == nil {
+		return fmt.Errorf("failed to get bot info: %v", err)
+	}
+	tbotId = bot.ID
+	logger.Infof("Bot logged in as %s (%d)", bot.Username, tbotId)
+	return nil
+}
+
 func loadAllModules(c *telegram.Client) {
 	defer LoadHelpHandler(c)
 
 	LoadSystemModule(c)
 
 	LoadAdminModule(c)
+	LoadModerationModule(c)
 	LoadSudoModule(c)
 	LoadGbanHandler(c)
 	LoadBanGuardModule(c)
+	LoadAntiFloodModule(c)
 
 	LoadMyinfo(c)
 	LoadProfileModule(c)
+	LoadRotationModule(c)
+	LoadProfileTrackerModule(c)
 	LoadPmAssistantHandler(c)
 	LoadAFKModule(c)
 	LoadStoriesModule(c)
+	LoadStoryWatchModule(c)
 
 	LoadSearchModule(c)
-	LoadIMDBModule(c)
+	if tgbot != nil {
+		LoadIMDBModule(c)
+	}
 	LoadUnsplashModule(c)
 
 	LoadAudioToolsModule(c)
@@ -127,13 +174,17 @@ func loadAllModules(c *telegram.Client) {
 	LoadMediaToolsModule(c)
 	LoadFilesModule(c)
 	LoadFileShareModule(c)
+	LoadExportModule(c)
+	LoadCloneModule(c)
 	LoadPasteModule(c)
 	LoadGDriveModule(c)
 
 	LoadDbCmds(c)
+	LoadBackupModule(c)
 	LoadLanguageModule(c)
 	LoadLoggingModule(c)
 	LoadTagLogger(c)
+	LoadMsgLoggerModule(c)
 	LoadRemindersModule(c)
 	LoadSpeedTestModule(c)
 
@@ -144,6 +195,7 @@ func loadAllModules(c *telegram.Client) {
 
 	logger.Info("All modules loaded")
 	logger.Startup(fmt.Sprintf(locales.Tr("system.started"), time.Since(startTime).String()))
+	reportRestart()
 }
 
 func AddHandlers(handlers []*Handler, c *telegram.Client) {
@@ -187,33 +239,34 @@ func InitUb() {
 	if err := logger.Init(); err != nil {
 		logger.Warn("Logger init warning:", err)
 	}
+	setupTempDir()
 
 	var err error
 	cfg, err = config.LoadConfig()
 	if err != nil {
-		logger.Fatal("Config error:", err)
+		logger.Fatalf("%v\nRun with --check-config to see the loaded values.", err)
 	}
 
+	db.SetPrefix(cfg.DbPrefix)
 	Db, err = db.InitDB(cfg.DbUrl)
 	if err != nil {
 		logger.Fatal("Database error:", err)
 	}
+	prepareDatabase()
 
 	loadSudoers()
 
-	client, err := InitTgClients()
-	if err != nil {
+	if _, err := InitTgClients(); err != nil {
 		logger.Fatal("Telegram error:", err)
 	}
 
-	client.Idle()
-
-	if tgbot != nil {
-		tgbot.Stop()
+	if !waitForShutdown() {
+		shutdown("Userbot stopped")
+		return
 	}
-	if client != nil {
-		client.Stop()
+
+	shutdown("Userbot restarting")
+	if err := reexec(); err != nil {
+		logger.Fatalf("Restart failed: %v", err)
 	}
-	db.Close()
-	logger.Shutdown("Userbot stopped")
 }
@@ -1,68 +1,288 @@
 package modules
 
 import (
-	"NovaUserbot/db"
 	"NovaUserbot/locales"
 	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/amarnathcjd/gogram/telegram"
 )
 
+const (
+	langScopeGlobal = "global"
+	langScopeChat   = "chat"
+	langScopeUser   = "me"
+
+	langPackMaxSize   = 512 * 1024
+	langReportMaxKeys = 15
+)
+
+// SetLanguage sets the global language, or with -chat / -me an override for
+// the current chat or the sender. Sudos without a flag set their own language
+// instead of the owner's global one.
 func SetLanguage(m *telegram.NewMessage) error {
-	lang := strings.TrimSpace(strings.ToLower(m.Args()))
+	fields := strings.Fields(strings.ToLower(m.Args()))
 
-	if lang == "" {
+	scope := langScopeGlobal
+	if m.SenderID() != ubId {
+		scope = langScopeUser
+	}
+	if len(fields) > 0 {
+		switch fields[0] {
+		case "-chat":
+			scope, fields = langScopeChat, fields[1:]
+		case "-me":
+			scope, fields = langScopeUser, fields[1:]
+		}
+	}
+
+	if len(fields) == 0 {
 		langs := locales.GetAvailableLanguages()
-		msg := locales.Tr("lang_settings.available_header")
+		slices.Sort(langs)
+		msg := tr(m, "lang_settings.available_header")
 		for _, l := range langs {
 			name := locales.GetLanguageName(l)
-			msg += fmt.Sprintf(locales.Tr("lang_settings.available_entry"), l, name)
+			msg += fmt.Sprintf(tr(m, "lang_settings.available_entry"), l, name)
 		}
-		msg += locales.Tr("lang_settings.usage")
+		msg += tr(m, "lang_settings.usage")
 		_, err := eOR(m, msg)
 		return err
 	}
 
-	available := locales.GetAvailableLanguages()
-	found := false
-	for _, l := range available {
-		if l == lang {
-			found = true
-			break
+	lang := fields[0]
+	t := locales.GetInstance()
+
+	if lang == "off" || lang == "reset" {
+		var err error
+		switch scope {
+		case langScopeChat:
+			err = t.SetChatLanguage(m.ChatID(), "")
+		case langScopeUser:
+			err = t.SetUserLanguage(m.SenderID(), "")
+		default:
+			err = t.SetGlobalLanguage("en")
+		}
+		if err != nil {
+			_, err = eOR(m, tr(m, "lang_settings.set_error"))
+			return err
 		}
+		_, err = eOR(m, fmt.Sprintf(tr(m, "lang_settings.reset"), scope))
+		return err
 	}
 
-	if !found {
-		_, err := eOR(m, fmt.Sprintf(locales.Tr("lang_settings.not_found"), lang))
+	if !slices.Contains(locales.GetAvailableLanguages(), lang) {
+		_, err := eOR(m, fmt.Sprintf(tr(m, "lang_settings.not_found"), lang))
 		return err
 	}
 
-	if err := db.Set("BOT_LANGUAGE", lang); err != nil {
-		_, err = eOR(m, locales.Tr("lang_settings.set_error"))
+	var err error
+	switch scope {
+	case langScopeChat:
+		err = t.SetChatLanguage(m.ChatID(), lang)
+	case langScopeUser:
+		err = t.SetUserLanguage(m.SenderID(), lang)
+	default:
+		err = t.SetGlobalLanguage(lang)
+	}
+	if err != nil {
+		_, err = eOR(m, tr(m, "lang_settings.set_error"))
 		return err
 	}
 
-	locales.GetInstance().SetGlobalLanguage(lang)
 	langName := locales.GetLanguageName(lang)
-	_, err := eOR(m, fmt.Sprintf(locales.Tr("lang_settings.changed"), langName, lang))
+	msg := fmt.Sprintf(tr(m, "lang_settings.changed"), langName, lang)
+	if scope != langScopeGlobal {
+		msg += fmt.Sprintf(tr(m, "lang_settings.scope_line"), scope)
+	}
+	_, err = eOR(m, msg)
 	return err
 }
 
 func GetLanguage(m *telegram.NewMessage) error {
-	lang := db.Get("BOT_LANGUAGE")
-	if lang == "" {
-		lang = "en"
-	}
+	t := locales.GetInstance()
+	lang := t.Resolve(m.SenderID(), m.ChatID())
 	langName := locales.GetLanguageName(lang)
-	_, err := eOR(m, fmt.Sprintf(locales.Tr("lang_settings.current"), langName, lang))
+
+	msg := fmt.Sprintf(tr(m, "lang_settings.current"), langName, lang)
+	msg += fmt.Sprintf(tr(m, "lang_settings.global_line"), t.GetGlobalLanguage())
+	if chatLang := t.GetChatLanguage(m.ChatID()); chatLang != "" {
+		msg += fmt.Sprintf(tr(m, "lang_settings.chat_line"), chatLang)
+	}
+	if t.HasUserLanguage(m.SenderID()) {
+		msg += fmt.Sprintf(tr(m, "lang_settings.user_line"), t.GetUserLanguage(m.SenderID()))
+	}
+	_, err := eOR(m, msg)
 	return err
 }
 
+// formatLangReport summarises how a pack compares to en.yml, listing the keys
+// with broken placeholders first since those block loading.
+func formatLangReport(m *telegram.NewMessage, report locales.Report) string {
+	lang := msgLang(m)
+	var sb strings.Builder
+
+	sb.WriteString(locales.Format(tr(m, "lang_settings.report_coverage"), locales.Args{
+		"coverage":   fmt.Sprintf("%.1f", report.Coverage()),
+		"translated": report.Total - len(report.Missing),
+		"total":      report.Total,
+	}))
+
+	sections := []struct {
+		key  string
+		keys []string
+	}{
+		{"lang_settings.report_mismatched", report.Mismatched},
+		{"lang_settings.report_missing", report.Missing},
+		{"lang_settings.report_unknown", report.Unknown},
+	}
+	for _, section := range sections {
+		if len(section.keys) == 0 {
+			continue
+		}
+		sb.WriteString(locales.Format(locales.TrLangN(lang, section.key, len(section.keys)), locales.Args{"count": len(section.keys)}))
+		for i, key := range section.keys {
+			if i >= langReportMaxKeys {
+				sb.WriteString(fmt.Sprintf(tr(m, "lang_settings.report_more"), len(section.keys)-langReportMaxKeys))
+				break
+			}
+			sb.WriteString(fmt.Sprintf(tr(m, "lang_settings.report_key"), html.EscapeString(key)))
+		}
+	}
+	return sb.String()
+}
+
+// LoadLangPack loads a .yml pack sent as a document, validating it against
+// en.yml, and keeps it in the packs directory so it survives restarts.
+func LoadLangPack(m *telegram.NewMessage) error {
+	if !m.IsReply() {
+		_, err := eOR(m, tr(m, "lang_settings.load_usage"))
+		return err
+	}
+
+	reply, err := m.GetReplyMessage()
+	if err != nil || reply.File == nil {
+		_, err := eOR(m, tr(m, "lang_settings.load_usage"))
+		return err
+	}
+	if reply.File.Size > langPackMaxSize {
+		_, err := eOR(m, tr(m, "lang_settings.load_too_big"))
+		return err
+	}
+
+	code := strings.ToLower(strings.TrimSpace(m.Args()))
+	if code == "" {
+		code = strings.ToLower(strings.TrimSuffix(strings.TrimSuffix(reply.File.Name, ".yml"), ".yaml"))
+	}
+	if !locales.ValidLangCode(code) {
+		_, err := eOR(m, fmt.Sprintf(tr(m, "lang_settings.load_bad_code"), html.EscapeString(code)))
+		return err
+	}
+
+	path, err := reply.Download(&telegram.DownloadOptions{FileName: filepath.Join(os.TempDir(), "langpack_"+code+".yml")})
+	if err != nil {
+		_, err := eOR(m, tr(m, "lang_settings.load_download_error"))
+		return err
+	}
+	defer os.Remove(path)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		_, err := eOR(m, tr(m, "lang_settings.load_download_error"))
+		return err
+	}
+
+	report, err := locales.LoadPack(code, data)
+	if err != nil {
+		_, err := eOR(m, fmt.Sprintf(tr(m, "lang_settings.load_rejected"), html.EscapeString(err.Error()))+formatLangReport(m, report))
+		return err
+	}
+
+	if err := locales.SavePack(code, data); err != nil {
+		_, err := eOR(m, fmt.Sprintf(tr(m, "lang_settings.load_save_error"), html.EscapeString(err.Error())))
+		return err
+	}
+
+	msg := locales.Format(tr(m, "lang_settings.load_success"), locales.Args{
+		"name": html.EscapeString(locales.GetLanguageName(code)),
+		"code": code,
+	})
+	_, err = eOR(m, msg+formatLangReport(m, report))
+	return err
+}
+
+func ReloadLangPacks(m *telegram.NewMessage) error {
+	_, err := locales.Reload()
+
+	msg := locales.Format(locales.TrLangN(msgLang(m), "lang_settings.reloaded", len(locales.GetAvailableLanguages())),
+		locales.Args{"count": len(locales.GetAvailableLanguages()), "dir": html.EscapeString(locales.PackDir())})
+	if err != nil {
+		msg += fmt.Sprintf(tr(m, "lang_settings.reload_errors"), html.EscapeString(err.Error()))
+	}
+	_, err = eOR(m, msg)
+	return err
+}
+
+// LangCheck shows translation coverage for every language, or the full
+// report for one, so translators can see what is left to do.
+func LangCheck(m *telegram.NewMessage) error {
+	if code := strings.ToLower(strings.TrimSpace(m.Args())); code != "" {
+		report, err := locales.Validate(code)
+		if err != nil {
+			_, err := eOR(m, fmt.Sprintf(tr(m, "lang_settings.not_found"), html.EscapeString(code)))
+			return err
+		}
+		msg := locales.Format(tr(m, "lang_settings.check_title"), locales.Args{
+			"name": html.EscapeString(locales.GetLanguageName(code)),
+			"code": code,
+		})
+		_, err = eOR(m, msg+formatLangReport(m, report))
+		return err
+	}
+
+	reports := locales.ValidateAll()
+	if len(reports) == 0 {
+		_, err := eOR(m, tr(m, "lang_settings.check_empty"))
+		return err
+	}
+
+	msg := tr(m, "lang_settings.check_header")
+	for _, report := range reports {
+		msg += locales.Format(tr(m, "lang_settings.check_entry"), locales.Args{
+			"code":       report.Lang,
+			"name":       html.EscapeString(locales.GetLanguageName(report.Lang)),
+			"coverage":   fmt.Sprintf("%.1f", report.Coverage()),
+			"missing":    len(report.Missing),
+			"mismatched": len(report.Mismatched),
+		})
+	}
+	_, err := eOR(m, msg+tr(m, "lang_settings.check_hint"))
+	return err
+}
+
+func validLanguage(value string) (string, error) {
+	value = strings.ToLower(value)
+	if !slices.Contains(locales.GetAvailableLanguages(), value) {
+		return "", fmt.Errorf("language %q is not loaded", value)
+	}
+	return value, nil
+}
+
 func LoadLanguageModule(c *telegram.Client) {
+	RegisterVars([]*ConfigVar{
+		{Key: "BOT_LANGUAGE", Module: "Language", Default: "en", Description: "Global reply language", Validate: validLanguage,
+			OnChange: func(string) { locales.GetInstance().ReloadPreferences() }},
+	})
+
 	handlers := []*Handler{
-		{Func: SetLanguage, Command: "setlang", Description: "Set bot language", ModuleName: "Language"},
+		{Func: SetLanguage, Command: "setlang", Description: "Set bot language ([-chat | -me] <code | off>)", ModuleName: "Language"},
 		{Func: GetLanguage, Command: "lang", Description: "Show current language", ModuleName: "Language"},
+		{Func: LoadLangPack, Command: "loadlang", Description: "Load a .yml language pack (reply to the file) [code]", ModuleName: "Language", DisAllowSudos: true},
+		{Func: ReloadLangPacks, Command: "reloadlang", Description: "Reload language packs from disk", ModuleName: "Language", DisAllowSudos: true},
+		{Func: LangCheck, Command: "langcheck", Description: "Show translation coverage [code]", ModuleName: "Language"},
 	}
 	AddHandlers(handlers, c)
 }
@@ -0,0 +1,176 @@
+package modules
+
+import (
+	"NovaUserbot/db"
+	"NovaUserbot/locales"
+	"NovaUserbot/logger"
+	"NovaUserbot/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+const (
+	shutdownTimeout  = 15 * time.Second
+	restartNoticeKey = "RESTART_NOTICE"
+)
+
+// The lifecycle tracks the background workers modules start, so they can be
+// cancelled and drained on shutdown instead of being cut off mid-work.
+var (
+	lifecycleCtx, lifecycleCancel = context.WithCancel(context.Background())
+	lifecycleWorkers              sync.WaitGroup
+
+	restartRequested = make(chan struct{})
+	restartOnce      sync.Once
+
+	tempDir    string
+	origTmpDir string
+)
+
+// startWorker runs fn in the background until shutdown. fn must return once
+// ctx is done.
+func startWorker(name string, fn func(ctx context.Context)) {
+	lifecycleWorkers.Add(1)
+	go func() {
+		defer lifecycleWorkers.Done()
+		defer logger.Debugf("Worker %s stopped", name)
+		fn(lifecycleCtx)
+	}()
+}
+
+// setupTempDir points TMPDIR at a private directory, so files left behind by
+// interrupted downloads and ffmpeg jobs are removed on shutdown.
+func setupTempDir() {
+	origTmpDir = os.Getenv("TMPDIR")
+	dir, err := os.MkdirTemp("", "nova-")
+	if err != nil {
+		logger.Warnf("Could not create temp dir: %v", err)
+		return
+	}
+	tempDir = dir
+	os.Setenv("TMPDIR", dir)
+}
+
+// waitForShutdown blocks until SIGINT/SIGTERM or a .restart, and reports
+// whether a restart was requested.
+func waitForShutdown() bool {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	select {
+	case sig := <-signals:
+		logger.Infof("Received %s, shutting down", sig)
+		return false
+	case <-restartRequested:
+		logger.Info("Restart requested")
+		return true
+	}
+}
+
+// shutdown cancels the workers, waits for them and for running commands up
+// to shutdownTimeout, flushes pending log messages and stops the clients.
+func shutdown(reason string) {
+	logger.Shutdown(reason)
+	lifecycleCancel()
+
+	deadline := time.Now().Add(shutdownTimeout)
+	done := make(chan struct{})
+	go func() {
+		lifecycleWorkers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Until(deadline)):
+		logger.Warn("Some workers did not stop in time")
+	}
+
+	if !utils.WaitCommands(time.Until(deadline)) {
+		logger.Warn("Killed commands still running at shutdown")
+	}
+	if !logger.Flush(time.Until(deadline) + time.Second) {
+		logger.Info("Dropped log messages that could not be sent in time")
+	}
+
+	if tgbot != nil {
+		tgbot.Stop()
+	}
+	if client != nil {
+		client.Stop()
+	}
+	db.Close()
+
+	if tempDir != "" {
+		os.RemoveAll(tempDir)
+		os.Setenv("TMPDIR", origTmpDir)
+	}
+}
+
+// reexec replaces the process with a fresh copy of itself. Windows has no
+// exec, so a new process is started instead.
+func reexec() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	if runtime.GOOS == "windows" {
+		cmd := exec.Command(exe, os.Args[1:]...)
+		cmd.Stdout, cmd.Stderr, cmd.Stdin = os.Stdout, os.Stderr, os.Stdin
+		if err := cmd.Start(); err != nil {
+			return err
+		}
+		os.Exit(0)
+	}
+	return syscall.Exec(exe, os.Args, os.Environ())
+}
+
+type restartNotice struct {
+	ChatID    int64 `json:"chat_id"`
+	MessageID int32 `json:"message_id"`
+	SentAt    int64 `json:"sent_at"`
+}
+
+func Restart(m *telegram.NewMessage) error {
+	msg, err := eOR(m, tr(m, "system.restarting"))
+	if err != nil {
+		return err
+	}
+
+	notice, _ := json.Marshal(restartNotice{ChatID: m.ChatID(), MessageID: msg.ID, SentAt: time.Now().UnixMilli()})
+	db.Set(restartNoticeKey, string(notice))
+
+	restartOnce.Do(func() { close(restartRequested) })
+	return nil
+}
+
+// reportRestart edits the .restart message once the userbot is back up.
+func reportRestart() {
+	data := db.Get(restartNoticeKey)
+	if data == "" {
+		return
+	}
+	db.Del(restartNoticeKey)
+
+	var notice restartNotice
+	if err := json.Unmarshal([]byte(data), &notice); err != nil {
+		return
+	}
+
+	took := time.Since(time.UnixMilli(notice.SentAt)).Round(100 * time.Millisecond)
+	text := fmt.Sprintf(locales.TrFor(ubId, notice.ChatID, "system.restarted"), took)
+	if _, err := client.EditMessage(notice.ChatID, notice.MessageID, text); err != nil {
+		logger.Warnf("Could not report restart: %v", err)
+	}
+}
@@ -2,64 +2,255 @@ package modules
 
 import (
 	"NovaUserbot/db"
-	"NovaUserbot/locales"
 	"NovaUserbot/logger"
+	"NovaUserbot/utils"
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/amarnathcjd/gogram/telegram"
 )
 
+// LogRoute sends one log category to its own chat, optionally inside a forum
+// topic. Categories without a route go to LOG_CHAT.
+type LogRoute struct {
+	ChatID  int64 `json:"chat_id"`
+	TopicID int32 `json:"topic_id,omitempty"`
+}
+
+var (
+	logRoutes     = make(map[logger.Category]LogRoute)
+	logRoutesLock sync.RWMutex
+)
+
+func loadLogRoutes() {
+	routes := make(map[logger.Category]LogRoute)
+	if data := db.Get("LOG_ROUTES"); data != "" {
+		if err := json.Unmarshal([]byte(data), &routes); err != nil {
+			logger.Warnf("Invalid log routes: %v", err)
+		}
+	}
+
+	logRoutesLock.Lock()
+	logRoutes = routes
+	logRoutesLock.Unlock()
+}
+
+func getLogRoute(category logger.Category) (LogRoute, bool) {
+	logRoutesLock.RLock()
+	defer logRoutesLock.RUnlock()
+	route, ok := logRoutes[category]
+	return route, ok
+}
+
+// updateLogRoute sets the route for a category, or removes it when route is nil.
+func updateLogRoute(category logger.Category, route *LogRoute) error {
+	logRoutesLock.Lock()
+	defer logRoutesLock.Unlock()
+
+	routes := make(map[logger.Category]LogRoute, len(logRoutes)+1)
+	for c, r := range logRoutes {
+		routes[c] = r
+	}
+	if route == nil {
+		delete(routes, category)
+	} else {
+		routes[category] = *route
+	}
+
+	data, err := json.Marshal(routes)
+	if err != nil {
+		return err
+	}
+	if err := db.Set("LOG_ROUTES", string(data)); err != nil {
+		return err
+	}
+	logRoutes = routes
+	return nil
+}
+
+// logRouteFor returns where category is routed, or LOG_CHAT (Saved Messages
+// when unset) when it has no route.
+func logRouteFor(category logger.Category) LogRoute {
+	if route, ok := getLogRoute(category); ok {
+		return route
+	}
+	chat := utils.StringToInt64(db.Get("LOG_CHAT"))
+	if chat == 0 {
+		chat = ubId
+	}
+	return LogRoute{ChatID: chat}
+}
+
+// logTo posts msg to the chat routed for category, falling back to LOG_CHAT
+// when the category has no route or its chat can't be reached.
+func logTo(category logger.Category, msg string, opts ...*telegram.SendOptions) error {
+	route, ok := getLogRoute(category)
+	if !ok {
+		return logMessage(msg, opts...)
+	}
+
+	peer, err := logClient().GetSendablePeer(route.ChatID)
+	if err != nil {
+		return logMessage(msg, opts...)
+	}
+
+	sendOpts := &telegram.SendOptions{}
+	if len(opts) > 0 && opts[0] != nil {
+		copied := *opts[0]
+		sendOpts = &copied
+	}
+	sendOpts.TopicID = route.TopicID
+
+	if _, err = logClient().SendMessage(peer, msg, sendOpts); err != nil {
+		return logMessage(msg, opts...)
+	}
+	return nil
+}
+
 func SetLogChat(m *telegram.NewMessage) error {
 	args := m.Args()
 	if args == "" {
-		_, err := eOR(m, locales.Tr("logging.usage_setlog"))
+		_, err := eOR(m, tr(m, "logging.usage_setlog"))
 		return err
 	}
 
+	if fields := strings.Fields(args); len(fields) > 0 {
+		if category, ok := logger.ParseCategory(fields[0]); ok {
+			return setLogRoute(m, category, fields[1:])
+		}
+	}
+
 	chatId, err := strconv.ParseInt(args, 10, 64)
 	if err != nil {
-		_, err = eOR(m, locales.Tr("logging.invalid_chat"))
+		_, err = eOR(m, tr(m, "logging.invalid_chat"))
 		return err
 	}
 
-	peer, err := tgbot.GetSendablePeer(chatId)
+	peer, err := logClient().GetSendablePeer(chatId)
 	if err != nil {
-		_, err = eOR(m, locales.Tr("logging.assistant_not_in_chat"))
+		_, err = eOR(m, tr(m, "logging.assistant_not_in_chat"))
 		return err
 	}
 
-	_, err = tgbot.SendMessage(peer, fmt.Sprintf(locales.Tr("logging.log_set_success"), args))
+	_, err = logClient().SendMessage(peer, fmt.Sprintf(tr(m, "logging.log_set_success"), args))
 	if err != nil {
-		_, err = eOR(m, locales.Tr("logging.send_error"))
+		_, err = eOR(m, tr(m, "logging.send_error"))
 		return err
 	}
 
 	db.Set("LOG_CHAT", strconv.FormatInt(chatId, 10))
 	logger.Event("LOG_CHAT", fmt.Sprintf("Log channel set to %d", chatId))
-	_, err = eOR(m, fmt.Sprintf(locales.Tr("logging.log_set_success"), args))
+	_, err = eOR(m, fmt.Sprintf(tr(m, "logging.log_set_success"), args))
+	return err
+}
+
+func setLogRoute(m *telegram.NewMessage, category logger.Category, args []string) error {
+	if len(args) == 0 || len(args) > 2 {
+		_, err := eOR(m, tr(m, "logging.usage_setlog"))
+		return err
+	}
+
+	chatId, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		_, err = eOR(m, tr(m, "logging.invalid_chat"))
+		return err
+	}
+
+	route := LogRoute{ChatID: chatId}
+	if len(args) == 2 {
+		topicId, err := strconv.ParseInt(args[1], 10, 32)
+		if err != nil || topicId <= 0 {
+			_, err = eOR(m, tr(m, "logging.invalid_topic"))
+			return err
+		}
+		route.TopicID = int32(topicId)
+	}
+
+	peer, err := logClient().GetSendablePeer(chatId)
+	if err != nil {
+		_, err = eOR(m, tr(m, "logging.assistant_not_in_chat"))
+		return err
+	}
+
+	text := fmt.Sprintf(tr(m, "logging.route_set"), category, formatLogRoute(route))
+	if _, err = logClient().SendMessage(peer, text, &telegram.SendOptions{TopicID: route.TopicID}); err != nil {
+		_, err = eOR(m, tr(m, "logging.send_error"))
+		return err
+	}
+
+	if err := updateLogRoute(category, &route); err != nil {
+		_, err = eOR(m, tr(m, "logging.route_error"))
+		return err
+	}
+	logger.Eventf("LOG_ROUTE", "%s logs routed to %s", category, formatLogRoute(route))
+	_, err = eOR(m, text)
 	return err
 }
 
+func formatLogRoute(route LogRoute) string {
+	if route.TopicID != 0 {
+		return fmt.Sprintf("%d/%d", route.ChatID, route.TopicID)
+	}
+	return strconv.FormatInt(route.ChatID, 10)
+}
+
 func GetLogChat(m *telegram.NewMessage) error {
 	config := db.Get("LOG_CHAT")
-	if config == "" {
-		_, err := eOR(m, locales.Tr("logging.not_set"))
+
+	logRoutesLock.RLock()
+	var routes strings.Builder
+	for _, category := range logger.Categories {
+		if route, ok := logRoutes[category]; ok {
+			routes.WriteString(fmt.Sprintf(tr(m, "logging.route_entry"), category, formatLogRoute(route)))
+		}
+	}
+	logRoutesLock.RUnlock()
+
+	if config == "" && routes.Len() == 0 {
+		_, err := eOR(m, tr(m, "logging.not_set"))
 		return err
 	}
-	_, err := eOR(m, fmt.Sprintf(locales.Tr("logging.log_result"), config))
+	if config == "" {
+		config = tr(m, "logging.default_chat")
+	}
+
+	text := fmt.Sprintf(tr(m, "logging.log_result"), config)
+	if routes.Len() > 0 {
+		text += "\n" + tr(m, "logging.routes_header") + routes.String()
+	}
+	_, err := eOR(m, text)
 	return err
 }
 
 func DelLogChat(m *telegram.NewMessage) error {
+	if args := m.Args(); args != "" {
+		category, ok := logger.ParseCategory(args)
+		if !ok {
+			_, err := eOR(m, fmt.Sprintf(tr(m, "logging.invalid_category"), logCategoryList()))
+			return err
+		}
+		if _, exists := getLogRoute(category); !exists {
+			_, err := eOR(m, tr(m, "logging.route_not_found"))
+			return err
+		}
+		if err := updateLogRoute(category, nil); err != nil {
+			_, err = eOR(m, tr(m, "logging.route_error"))
+			return err
+		}
+		_, err := eOR(m, fmt.Sprintf(tr(m, "logging.route_deleted"), category))
+		return err
+	}
+
 	if !db.Exists("LOG_CHAT") {
-		_, err := eOR(m, locales.Tr("logging.not_found"))
+		_, err := eOR(m, tr(m, "logging.not_found"))
 		return err
 	}
 	db.Del("LOG_CHAT")
 	logger.SetLogToChannel(false)
-	_, err := eOR(m, locales.Tr("logging.deleted"))
+	_, err := eOR(m, tr(m, "logging.deleted"))
 	return err
 }
 
@@ -69,23 +260,36 @@ func ToggleLogging(m *telegram.NewMessage) error {
 	switch args {
 	case "on", "enable":
 		logger.SetLogToChannel(true)
-		_, err := eOR(m, locales.Tr("logging.enabled"))
+		_, err := eOR(m, tr(m, "logging.enabled"))
 		return err
 	case "off", "disable":
 		logger.SetLogToChannel(false)
-		_, err := eOR(m, locales.Tr("logging.disabled"))
+		_, err := eOR(m, tr(m, "logging.disabled"))
 		return err
 	default:
-		_, err := eOR(m, locales.Tr("logging.usage_toggle"))
+		_, err := eOR(m, tr(m, "logging.usage_toggle"))
 		return err
 	}
 }
 
+func logCategoryList() string {
+	names := make([]string, len(logger.Categories))
+	for i, c := range logger.Categories {
+		names[i] = string(c)
+	}
+	return strings.Join(names, ", ")
+}
+
 func LoadLoggingModule(c *telegram.Client) {
+	loadLogRoutes()
+	RegisterVars([]*ConfigVar{
+		{Key: "LOG_CHAT", Module: "Logging", Type: VarChatID, Description: "Chat logs are sent to when no category route matches"},
+	})
+
 	handlers := []*Handler{
-		{Command: "setlog", Func: SetLogChat, Description: "Set log channel", ModuleName: "Logging"},
-		{Command: "getlog", Func: GetLogChat, Description: "Get log channel", ModuleName: "Logging"},
-		{Command: "dellog", Func: DelLogChat, Description: "Delete log channel", ModuleName: "Logging"},
+		{Command: "setlog", Func: SetLogChat, Description: "Set log channel, or route a category: <category> <chat_id> [topic_id]", ModuleName: "Logging"},
+		{Command: "getlog", Func: GetLogChat, Description: "Get log channel and category routes", ModuleName: "Logging"},
+		{Command: "dellog", Func: DelLogChat, Description: "Delete log channel, or a category route", ModuleName: "Logging"},
 		{Command: "logging", Func: ToggleLogging, Description: "Toggle logging on/off", ModuleName: "Logging"},
 	}
 	AddHandlers(handlers, c)
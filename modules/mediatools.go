@@ -1,7 +1,6 @@
 package modules
 
 import (
-	"NovaUserbot/locales"
 	"NovaUserbot/utils"
 	"fmt"
 	"os"
@@ -14,27 +13,27 @@ import (
 
 func mediaInfoCommand(m *telegram.NewMessage) error {
 	if !m.IsReply() {
-		_, err := eOR(m, locales.Tr("mediatools.reply_required"))
+		_, err := eOR(m, tr(m, "mediatools.reply_required"))
 		return err
 	}
 
 	reply, err := m.GetReplyMessage()
 	if err != nil {
-		_, err := eOR(m, locales.Tr("mediatools.fetch_error"))
+		_, err := eOR(m, tr(m, "mediatools.fetch_error"))
 		return err
 	}
 
 	if reply.Media() == nil {
-		_, err := eOR(m, locales.Tr("mediatools.no_media"))
+		_, err := eOR(m, tr(m, "mediatools.no_media"))
 		return err
 	}
 
-	msg, _ := eOR(m, locales.Tr("mediatools.analyzing"))
+	msg, _ := eOR(m, tr(m, "mediatools.analyzing"))
 
 	filePath, err := reply.Download()
 	if err != nil {
 		if msg != nil {
-			msg.Edit(locales.Tr("mediatools.download_error"))
+			msg.Edit(tr(m, "mediatools.download_error"))
 		}
 		return err
 	}
@@ -54,7 +53,7 @@ func mediaInfoCommand(m *telegram.NewMessage) error {
 		output = output[:4000] + "\n...[truncated]"
 	}
 
-	result := fmt.Sprintf(locales.Tr("mediatools.info_result"), output)
+	result := fmt.Sprintf(tr(m, "mediatools.info_result"), output)
 	if msg != nil {
 		_, err = msg.Edit(result, &telegram.SendOptions{ParseMode: "HTML"})
 	}
@@ -111,7 +110,7 @@ func getBasicFileInfo(m *telegram.NewMessage) string {
 
 func videoRotateCommand(m *telegram.NewMessage) error {
 	if !m.IsReply() {
-		_, err := eOR(m, locales.Tr("mediatools.reply_required"))
+		_, err := eOR(m, tr(m, "mediatools.reply_required"))
 		return err
 	}
 
@@ -127,34 +126,34 @@ func videoRotateCommand(m *telegram.NewMessage) error {
 	}
 
 	if !checkFFmpeg() {
-		_, err := eOR(m, locales.Tr("mediatools.ffmpeg_missing"))
+		_, err := eOR(m, tr(m, "mediatools.ffmpeg_missing"))
 		return err
 	}
 
 	reply, err := m.GetReplyMessage()
 	if err != nil {
-		_, err := eOR(m, locales.Tr("mediatools.fetch_error"))
+		_, err := eOR(m, tr(m, "mediatools.fetch_error"))
 		return err
 	}
 
 	if reply.Video() == nil && reply.Photo() == nil {
-		_, err := eOR(m, locales.Tr("mediatools.no_video_photo"))
+		_, err := eOR(m, tr(m, "mediatools.no_video_photo"))
 		return err
 	}
 
-	msg, _ := eOR(m, locales.Tr("mediatools.downloading"))
+	msg, _ := eOR(m, tr(m, "mediatools.downloading"))
 
 	inputPath, err := reply.Download()
 	if err != nil {
 		if msg != nil {
-			msg.Edit(locales.Tr("mediatools.download_error"))
+			msg.Edit(tr(m, "mediatools.download_error"))
 		}
 		return err
 	}
 	defer os.Remove(inputPath)
 
 	if msg != nil {
-		msg.Edit(locales.Tr("mediatools.processing"))
+		msg.Edit(tr(m, "mediatools.processing"))
 	}
 
 	ext := filepath.Ext(inputPath)
@@ -185,7 +184,7 @@ func videoRotateCommand(m *telegram.NewMessage) error {
 
 		if !checkImageMagick() {
 			if msg != nil {
-				msg.Edit(locales.Tr("imagetools.imagemagick_missing"))
+				msg.Edit(tr(m, "imagetools.imagemagick_missing"))
 			}
 			return nil
 		}
@@ -195,19 +194,19 @@ func videoRotateCommand(m *telegram.NewMessage) error {
 	output, err := utils.RunCommand(cmd)
 	if err != nil {
 		if msg != nil {
-			msg.Edit(fmt.Sprintf(locales.Tr("mediatools.process_error"), output))
+			msg.Edit(fmt.Sprintf(tr(m, "mediatools.process_error"), output))
 		}
 		return err
 	}
 
 	if msg != nil {
-		msg.Edit(locales.Tr("mediatools.uploading"))
+		msg.Edit(tr(m, "mediatools.uploading"))
 	}
 
 	_, err = m.Respond("", &telegram.SendOptions{Media: outputPath})
 	if err != nil {
 		if msg != nil {
-			msg.Edit(locales.Tr("mediatools.upload_error"))
+			msg.Edit(tr(m, "mediatools.upload_error"))
 		}
 		return err
 	}
@@ -220,23 +219,23 @@ func videoRotateCommand(m *telegram.NewMessage) error {
 
 func videoCompressCommand(m *telegram.NewMessage) error {
 	if !m.IsReply() {
-		_, err := eOR(m, locales.Tr("mediatools.reply_required"))
+		_, err := eOR(m, tr(m, "mediatools.reply_required"))
 		return err
 	}
 
 	if !checkFFmpeg() {
-		_, err := eOR(m, locales.Tr("mediatools.ffmpeg_missing"))
+		_, err := eOR(m, tr(m, "mediatools.ffmpeg_missing"))
 		return err
 	}
 
 	reply, err := m.GetReplyMessage()
 	if err != nil {
-		_, err := eOR(m, locales.Tr("mediatools.fetch_error"))
+		_, err := eOR(m, tr(m, "mediatools.fetch_error"))
 		return err
 	}
 
 	if reply.Video() == nil {
-		_, err := eOR(m, locales.Tr("mediatools.no_video"))
+		_, err := eOR(m, tr(m, "mediatools.no_video"))
 		return err
 	}
 
@@ -248,19 +247,19 @@ func videoCompressCommand(m *telegram.NewMessage) error {
 		}
 	}
 
-	msg, _ := eOR(m, locales.Tr("mediatools.downloading"))
+	msg, _ := eOR(m, tr(m, "mediatools.downloading"))
 
 	inputPath, err := reply.Download()
 	if err != nil {
 		if msg != nil {
-			msg.Edit(locales.Tr("mediatools.download_error"))
+			msg.Edit(tr(m, "mediatools.download_error"))
 		}
 		return err
 	}
 	defer os.Remove(inputPath)
 
 	if msg != nil {
-		msg.Edit(locales.Tr("mediatools.compressing"))
+		msg.Edit(tr(m, "mediatools.compressing"))
 	}
 
 	outputPath := filepath.Join(os.TempDir(), fmt.Sprintf("compress_%d.mp4", m.ID))
@@ -270,19 +269,19 @@ func videoCompressCommand(m *telegram.NewMessage) error {
 	output, err := utils.RunCommand(cmd)
 	if err != nil {
 		if msg != nil {
-			msg.Edit(fmt.Sprintf(locales.Tr("mediatools.process_error"), output))
+			msg.Edit(fmt.Sprintf(tr(m, "mediatools.process_error"), output))
 		}
 		return err
 	}
 
 	if msg != nil {
-		msg.Edit(locales.Tr("mediatools.uploading"))
+		msg.Edit(tr(m, "mediatools.uploading"))
 	}
 
 	_, err = m.Respond("", &telegram.SendOptions{Media: outputPath})
 	if err != nil {
 		if msg != nil {
-			msg.Edit(locales.Tr("mediatools.upload_error"))
+			msg.Edit(tr(m, "mediatools.upload_error"))
 		}
 		return err
 	}
@@ -295,39 +294,39 @@ func videoCompressCommand(m *telegram.NewMessage) error {
 
 func videoToGifCommand(m *telegram.NewMessage) error {
 	if !m.IsReply() {
-		_, err := eOR(m, locales.Tr("mediatools.reply_required"))
+		_, err := eOR(m, tr(m, "mediatools.reply_required"))
 		return err
 	}
 
 	if !checkFFmpeg() {
-		_, err := eOR(m, locales.Tr("mediatools.ffmpeg_missing"))
+		_, err := eOR(m, tr(m, "mediatools.ffmpeg_missing"))
 		return err
 	}
 
 	reply, err := m.GetReplyMessage()
 	if err != nil {
-		_, err := eOR(m, locales.Tr("mediatools.fetch_error"))
+		_, err := eOR(m, tr(m, "mediatools.fetch_error"))
 		return err
 	}
 
 	if reply.Video() == nil {
-		_, err := eOR(m, locales.Tr("mediatools.no_video"))
+		_, err := eOR(m, tr(m, "mediatools.no_video"))
 		return err
 	}
 
-	msg, _ := eOR(m, locales.Tr("mediatools.downloading"))
+	msg, _ := eOR(m, tr(m, "mediatools.downloading"))
 
 	inputPath, err := reply.Download()
 	if err != nil {
 		if msg != nil {
-			msg.Edit(locales.Tr("mediatools.download_error"))
+			msg.Edit(tr(m, "mediatools.download_error"))
 		}
 		return err
 	}
 	defer os.Remove(inputPath)
 
 	if msg != nil {
-		msg.Edit(locales.Tr("mediatools.converting"))
+		msg.Edit(tr(m, "mediatools.converting"))
 	}
 
 	outputPath := filepath.Join(os.TempDir(), fmt.Sprintf("gif_%d.gif", m.ID))
@@ -337,19 +336,19 @@ func videoToGifCommand(m *telegram.NewMessage) error {
 	output, err := utils.RunCommand(cmd)
 	if err != nil {
 		if msg != nil {
-			msg.Edit(fmt.Sprintf(locales.Tr("mediatools.process_error"), output))
+			msg.Edit(fmt.Sprintf(tr(m, "mediatools.process_error"), output))
 		}
 		return err
 	}
 
 	if msg != nil {
-		msg.Edit(locales.Tr("mediatools.uploading"))
+		msg.Edit(tr(m, "mediatools.uploading"))
 	}
 
 	_, err = m.Respond("", &telegram.SendOptions{Media: outputPath})
 	if err != nil {
 		if msg != nil {
-			msg.Edit(locales.Tr("mediatools.upload_error"))
+			msg.Edit(tr(m, "mediatools.upload_error"))
 		}
 		return err
 	}
@@ -362,34 +361,34 @@ func videoToGifCommand(m *telegram.NewMessage) error {
 
 func gifToVideoCommand(m *telegram.NewMessage) error {
 	if !m.IsReply() {
-		_, err := eOR(m, locales.Tr("mediatools.reply_required"))
+		_, err := eOR(m, tr(m, "mediatools.reply_required"))
 		return err
 	}
 
 	if !checkFFmpeg() {
-		_, err := eOR(m, locales.Tr("mediatools.ffmpeg_missing"))
+		_, err := eOR(m, tr(m, "mediatools.ffmpeg_missing"))
 		return err
 	}
 
 	reply, err := m.GetReplyMessage()
 	if err != nil {
-		_, err := eOR(m, locales.Tr("mediatools.fetch_error"))
+		_, err := eOR(m, tr(m, "mediatools.fetch_error"))
 		return err
 	}
 
-	msg, _ := eOR(m, locales.Tr("mediatools.downloading"))
+	msg, _ := eOR(m, tr(m, "mediatools.downloading"))
 
 	inputPath, err := reply.Download()
 	if err != nil {
 		if msg != nil {
-			msg.Edit(locales.Tr("mediatools.download_error"))
+			msg.Edit(tr(m, "mediatools.download_error"))
 		}
 		return err
 	}
 	defer os.Remove(inputPath)
 
 	if msg != nil {
-		msg.Edit(locales.Tr("mediatools.converting"))
+		msg.Edit(tr(m, "mediatools.converting"))
 	}
 
 	outputPath := filepath.Join(os.TempDir(), fmt.Sprintf("video_%d.mp4", m.ID))
@@ -399,19 +398,19 @@ func gifToVideoCommand(m *telegram.NewMessage) error {
 	output, err := utils.RunCommand(cmd)
 	if err != nil {
 		if msg != nil {
-			msg.Edit(fmt.Sprintf(locales.Tr("mediatools.process_error"), output))
+			msg.Edit(fmt.Sprintf(tr(m, "mediatools.process_error"), output))
 		}
 		return err
 	}
 
 	if msg != nil {
-		msg.Edit(locales.Tr("mediatools.uploading"))
+		msg.Edit(tr(m, "mediatools.uploading"))
 	}
 
 	_, err = m.Respond("", &telegram.SendOptions{Media: outputPath})
 	if err != nil {
 		if msg != nil {
-			msg.Edit(locales.Tr("mediatools.upload_error"))
+			msg.Edit(tr(m, "mediatools.upload_error"))
 		}
 		return err
 	}
@@ -424,19 +423,19 @@ func gifToVideoCommand(m *telegram.NewMessage) error {
 
 func videoTrimCommand(m *telegram.NewMessage) error {
 	if !m.IsReply() {
-		_, err := eOR(m, locales.Tr("mediatools.reply_required"))
+		_, err := eOR(m, tr(m, "mediatools.reply_required"))
 		return err
 	}
 
 	args := strings.TrimSpace(m.Args())
 	if args == "" {
-		_, err := eOR(m, locales.Tr("mediatools.vtrim_usage"))
+		_, err := eOR(m, tr(m, "mediatools.vtrim_usage"))
 		return err
 	}
 
 	parts := strings.Fields(args)
 	if len(parts) < 2 {
-		_, err := eOR(m, locales.Tr("mediatools.vtrim_usage"))
+		_, err := eOR(m, tr(m, "mediatools.vtrim_usage"))
 		return err
 	}
 
@@ -444,39 +443,39 @@ func videoTrimCommand(m *telegram.NewMessage) error {
 	endTime := parts[1]
 
 	if !isValidTimeFormat(startTime) || !isValidTimeFormat(endTime) {
-		_, err := eOR(m, locales.Tr("audiotools.invalid_time_format"))
+		_, err := eOR(m, tr(m, "audiotools.invalid_time_format"))
 		return err
 	}
 
 	if !checkFFmpeg() {
-		_, err := eOR(m, locales.Tr("mediatools.ffmpeg_missing"))
+		_, err := eOR(m, tr(m, "mediatools.ffmpeg_missing"))
 		return err
 	}
 
 	reply, err := m.GetReplyMessage()
 	if err != nil {
-		_, err := eOR(m, locales.Tr("mediatools.fetch_error"))
+		_, err := eOR(m, tr(m, "mediatools.fetch_error"))
 		return err
 	}
 
 	if reply.Video() == nil {
-		_, err := eOR(m, locales.Tr("mediatools.no_video"))
+		_, err := eOR(m, tr(m, "mediatools.no_video"))
 		return err
 	}
 
-	msg, _ := eOR(m, locales.Tr("mediatools.downloading"))
+	msg, _ := eOR(m, tr(m, "mediatools.downloading"))
 
 	inputPath, err := reply.Download()
 	if err != nil {
 		if msg != nil {
-			msg.Edit(locales.Tr("mediatools.download_error"))
+			msg.Edit(tr(m, "mediatools.download_error"))
 		}
 		return err
 	}
 	defer os.Remove(inputPath)
 
 	if msg != nil {
-		msg.Edit(locales.Tr("mediatools.processing"))
+		msg.Edit(tr(m, "mediatools.processing"))
 	}
 
 	ext := filepath.Ext(inputPath)
@@ -490,19 +489,19 @@ func videoTrimCommand(m *telegram.NewMessage) error {
 	output, err := utils.RunCommand(cmd)
 	if err != nil {
 		if msg != nil {
-			msg.Edit(fmt.Sprintf(locales.Tr("mediatools.process_error"), output))
+			msg.Edit(fmt.Sprintf(tr(m, "mediatools.process_error"), output))
 		}
 		return err
 	}
 
 	if msg != nil {
-		msg.Edit(locales.Tr("mediatools.uploading"))
+		msg.Edit(tr(m, "mediatools.uploading"))
 	}
 
 	_, err = m.Respond("", &telegram.SendOptions{Media: outputPath})
 	if err != nil {
 		if msg != nil {
-			msg.Edit(locales.Tr("mediatools.upload_error"))
+			msg.Edit(tr(m, "mediatools.upload_error"))
 		}
 		return err
 	}
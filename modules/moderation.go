@@ -0,0 +1,539 @@
+package modules
+
+import (
+	"NovaUserbot/db"
+	"NovaUserbot/locales"
+	"NovaUserbot/logger"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+const (
+	warnsPrefix        = "WARNS:"
+	warnSettingsPrefix = "WARN_SETTINGS:"
+	defaultWarnLimit   = 3
+	defaultWarnAction  = "ban"
+	// Telegram treats restrictions shorter than 30 seconds or longer than
+	// 366 days as permanent.
+	minRestriction = time.Minute
+	maxRestriction = 366 * 24 * time.Hour
+)
+
+// Warn is one warning given to a user in a chat.
+type Warn struct {
+	Reason string    `json:"reason,omitempty"`
+	By     int64     `json:"by"`
+	Time   time.Time `json:"time"`
+}
+
+// WarnSettings is what happens in a chat once a user reaches Limit warnings.
+// Duration applies to the tban and tmute actions.
+type WarnSettings struct {
+	Limit    int           `json:"limit"`
+	Action   string        `json:"action"`
+	Duration time.Duration `json:"duration,omitempty"`
+}
+
+var warnsMutex sync.Mutex
+
+// auditAction posts an admin action to the moderation log.
+func auditAction(m *telegram.NewMessage, action string, userID int64, userName, details string) {
+	if getVar("ADMIN_AUDIT") == "false" {
+		return
+	}
+	by := strings.TrimSpace(m.Sender.FirstName + " " + m.Sender.LastName)
+	text := fmt.Sprintf(locales.Tr("admin.audit"), strings.ToUpper(action), html.EscapeString(chatTitle(m)), m.ChatID(), m.Sender.ID, html.EscapeString(by))
+	if userID != 0 {
+		text += fmt.Sprintf(locales.Tr("admin.audit_user"), userID, html.EscapeString(userName))
+	}
+	if details != "" {
+		text += fmt.Sprintf(locales.Tr("admin.audit_details"), html.EscapeString(details))
+	}
+	if msgLink(m) != "" {
+		text += fmt.Sprintf(locales.Tr("admin.audit_link"), msgLink(m))
+	}
+	logTo(logger.CategoryModeration, text)
+}
+
+func isSupergroup(m *telegram.NewMessage) bool {
+	return m.Channel != nil && m.Channel.Megagroup
+}
+
+// restrictFor bans or mutes a user until d from now. Telegram lifts the
+// restriction by itself once it expires.
+func restrictFor(chatID, userID int64, ban bool, d time.Duration) error {
+	rights := &telegram.ChatBannedRights{UntilDate: int32(time.Now().Add(d).Unix())}
+	_, err := client.EditBanned(chatID, userID, &telegram.BannedOptions{Ban: ban, Mute: !ban, Rights: rights})
+	return err
+}
+
+func timedRestrict(m *telegram.NewMessage, ban bool) error {
+	usage, action := "admin.usage_tmute", "tmute"
+	if ban {
+		usage, action = "admin.usage_tban", "tban"
+	}
+	if !isSupergroup(m) {
+		_, err := eOR(m, tr(m, "admin.supergroups_only"))
+		return err
+	}
+
+	userID, userName, rest := ExtractUserMsg(m)
+	durArg, reason, _ := strings.Cut(rest, " ")
+	d, err := parseDurationString(durArg)
+	if userID == 0 || err != nil {
+		_, err := eOR(m, tr(m, usage))
+		return err
+	}
+	if d < minRestriction || d > maxRestriction {
+		_, err := eOR(m, tr(m, "admin.invalid_duration"))
+		return err
+	}
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		reason = tr(m, "common.no_reason")
+	}
+
+	if err := restrictFor(m.ChatID(), userID, ban, d); err != nil {
+		_, err := eOR(m, fmt.Sprintf(tr(m, "admin.restrict_error"), html.EscapeString(err.Error())))
+		return err
+	}
+
+	until := time.Now().Add(d)
+	auditAction(m, action, userID, userName, fmt.Sprintf("%s, %s", formatDurationHuman(d), reason))
+	_, err = eOR(m, fmt.Sprintf(tr(m, "admin."+action+"_done"), userID, html.EscapeString(userName), formatDurationHuman(d), until.Format("2006-01-02 15:04"), html.EscapeString(reason)))
+	return err
+}
+
+func TbanUser(m *telegram.NewMessage) error {
+	return timedRestrict(m, true)
+}
+
+func TmuteUser(m *telegram.NewMessage) error {
+	return timedRestrict(m, false)
+}
+
+func getWarnSettings(chatID int64) WarnSettings {
+	settings := WarnSettings{Limit: defaultWarnLimit, Action: defaultWarnAction}
+	if data := db.Get(warnSettingsPrefix + strconv.FormatInt(chatID, 10)); data != "" {
+		if err := json.Unmarshal([]byte(data), &settings); err != nil {
+			logger.Errorf("Corrupted warn settings of %d: %v", chatID, err)
+		}
+	}
+	return settings
+}
+
+func saveWarnSettings(chatID int64, settings WarnSettings) error {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	return db.Set(warnSettingsPrefix+strconv.FormatInt(chatID, 10), string(data))
+}
+
+func getChatWarns(chatID int64) map[int64][]Warn {
+	warns := make(map[int64][]Warn)
+	if data := db.Get(warnsPrefix + strconv.FormatInt(chatID, 10)); data != "" {
+		if err := json.Unmarshal([]byte(data), &warns); err != nil {
+			logger.Errorf("Corrupted warns of %d: %v", chatID, err)
+		}
+	}
+	return warns
+}
+
+func saveChatWarns(chatID int64, warns map[int64][]Warn) error {
+	key := warnsPrefix + strconv.FormatInt(chatID, 10)
+	if len(warns) == 0 {
+		return db.Del(key)
+	}
+	data, err := json.Marshal(warns)
+	if err != nil {
+		return err
+	}
+	return db.Set(key, string(data))
+}
+
+func (s WarnSettings) describe() string {
+	return describeAction(s.Action, s.Duration)
+}
+
+func describeAction(action string, d time.Duration) string {
+	if action == "tban" || action == "tmute" {
+		return action + " " + formatDurationHuman(d)
+	}
+	return action
+}
+
+var (
+	errBadAction   = errors.New("unknown action")
+	errBadDuration = errors.New("invalid duration")
+)
+
+// parseAction parses "ban", "kick", "mute", "tban <d>" or "tmute <d>". A
+// missing or out of range duration returns errBadDuration.
+func parseAction(args []string) (action string, d time.Duration, err error) {
+	if len(args) == 0 || !slices.Contains([]string{"ban", "kick", "mute", "tban", "tmute"}, args[0]) {
+		return "", 0, errBadAction
+	}
+	if args[0] != "tban" && args[0] != "tmute" {
+		return args[0], 0, nil
+	}
+	if len(args) < 2 {
+		return "", 0, errBadAction
+	}
+	d, err = parseDurationString(args[1])
+	if err != nil || d < minRestriction || d > maxRestriction {
+		return "", 0, errBadDuration
+	}
+	return args[0], d, nil
+}
+
+// restrictsOnly reports whether an action only works in supergroups. In basic
+// groups Telegram has no per-member restrictions, so a mute removes the user.
+func restrictsOnly(action string) bool {
+	return action == "mute" || action == "tmute" || action == "tban"
+}
+
+// punish applies a ban, kick, mute, tban or tmute action to a user. Callers
+// must not pass a restrictsOnly action for a basic group.
+func punish(chatID, userID int64, action string, d time.Duration) error {
+	var err error
+	switch action {
+	case "kick":
+		_, err = client.KickParticipant(chatID, userID)
+	case "mute":
+		_, err = client.EditBanned(chatID, userID, &telegram.BannedOptions{Mute: true})
+	case "tban", "tmute":
+		err = restrictFor(chatID, userID, action == "tban", d)
+	default:
+		_, err = client.EditBanned(chatID, userID, &telegram.BannedOptions{Ban: true})
+	}
+	return err
+}
+
+func WarnUser(m *telegram.NewMessage) error {
+	if !m.IsGroup() {
+		_, err := eOR(m, tr(m, "admin.groups_only"))
+		return err
+	}
+	userID, userName, reason := ExtractUserMsg(m)
+	if userID == 0 {
+		_, err := eOR(m, tr(m, "admin.usage_warn"))
+		return err
+	}
+	if userID == ubId || IsAdmin(userID, m.ChatID()) {
+		_, err := eOR(m, tr(m, "admin.warn_admin"))
+		return err
+	}
+
+	settings := getWarnSettings(m.ChatID())
+
+	warnsMutex.Lock()
+	warns := getChatWarns(m.ChatID())
+	warns[userID] = append(warns[userID], Warn{Reason: reason, By: m.Sender.ID, Time: time.Now()})
+	count := len(warns[userID])
+	if count >= settings.Limit {
+		delete(warns, userID)
+	}
+	err := saveChatWarns(m.ChatID(), warns)
+	warnsMutex.Unlock()
+	if err != nil {
+		_, err := eOR(m, fmt.Sprintf(tr(m, "admin.restrict_error"), html.EscapeString(err.Error())))
+		return err
+	}
+
+	if reason == "" {
+		reason = tr(m, "common.no_reason")
+	}
+	auditAction(m, "warn", userID, userName, fmt.Sprintf("%d/%d, %s", count, settings.Limit, reason))
+
+	if count < settings.Limit {
+		_, err := eOR(m, fmt.Sprintf(tr(m, "admin.warned"), userID, html.EscapeString(userName), count, settings.Limit, html.EscapeString(reason)))
+		return err
+	}
+
+	action, d, note := settings.Action, settings.Duration, ""
+	if !isSupergroup(m) && restrictsOnly(action) {
+		action, d, note = "kick", 0, tr(m, "admin.warn_kick_fallback")
+	}
+	if err := punish(m.ChatID(), userID, action, d); err != nil {
+		_, err := eOR(m, fmt.Sprintf(tr(m, "admin.restrict_error"), html.EscapeString(err.Error())))
+		return err
+	}
+	auditAction(m, action, userID, userName, fmt.Sprintf(locales.Tr("admin.warn_limit_reason"), settings.Limit))
+	_, err = eOR(m, fmt.Sprintf(tr(m, "admin.warn_limit_reached"), userID, html.EscapeString(userName), settings.Limit, describeAction(action, d))+note)
+	return err
+}
+
+func WarnsCmd(m *telegram.NewMessage) error {
+	userID, userName := ExtractUser(m)
+	if userID == 0 {
+		settings := getWarnSettings(m.ChatID())
+		_, err := eOR(m, fmt.Sprintf(tr(m, "admin.warn_settings"), settings.Limit, settings.describe()))
+		return err
+	}
+
+	warns := getChatWarns(m.ChatID())[userID]
+	if len(warns) == 0 {
+		_, err := eOR(m, fmt.Sprintf(tr(m, "admin.no_warns"), userID, html.EscapeString(userName)))
+		return err
+	}
+
+	text := fmt.Sprintf(tr(m, "admin.warns_header"), userID, html.EscapeString(userName), len(warns), getWarnSettings(m.ChatID()).Limit)
+	for i, w := range warns {
+		text += fmt.Sprintf(tr(m, "admin.warns_entry"), i+1, w.Time.Format("2006-01-02"), html.EscapeString(orNone(w.Reason)))
+	}
+	_, err := eOR(m, text)
+	return err
+}
+
+// RmWarnCmd removes a user's latest warning, or all of them with resetwarns.
+func RmWarnCmd(m *telegram.NewMessage) error {
+	userID, userName := ExtractUser(m)
+	if userID == 0 {
+		_, err := eOR(m, tr(m, "admin.usage_rmwarn"))
+		return err
+	}
+	all := strings.Contains(strings.Fields(m.Text())[0], "resetwarns")
+
+	warnsMutex.Lock()
+	warns := getChatWarns(m.ChatID())
+	if all || len(warns[userID]) <= 1 {
+		delete(warns, userID)
+	} else {
+		warns[userID] = warns[userID][:len(warns[userID])-1]
+	}
+	left := len(warns[userID])
+	err := saveChatWarns(m.ChatID(), warns)
+	warnsMutex.Unlock()
+	if err != nil {
+		_, err := eOR(m, fmt.Sprintf(tr(m, "admin.restrict_error"), html.EscapeString(err.Error())))
+		return err
+	}
+
+	auditAction(m, "unwarn", userID, userName, strconv.Itoa(left))
+	_, err = eOR(m, fmt.Sprintf(tr(m, "admin.warns_removed"), userID, html.EscapeString(userName), left))
+	return err
+}
+
+func WarnLimitCmd(m *telegram.NewMessage) error {
+	limit, err := strconv.Atoi(strings.TrimSpace(m.Args()))
+	if err != nil || limit < 1 || limit > 20 {
+		_, err := eOR(m, tr(m, "admin.usage_warnlimit"))
+		return err
+	}
+	settings := getWarnSettings(m.ChatID())
+	settings.Limit = limit
+	if err := saveWarnSettings(m.ChatID(), settings); err != nil {
+		_, err := eOR(m, fmt.Sprintf(tr(m, "admin.restrict_error"), html.EscapeString(err.Error())))
+		return err
+	}
+	_, err = eOR(m, fmt.Sprintf(tr(m, "admin.warn_settings"), settings.Limit, settings.describe()))
+	return err
+}
+
+func WarnActionCmd(m *telegram.NewMessage) error {
+	action, d, err := parseAction(strings.Fields(strings.ToLower(m.Args())))
+	if errors.Is(err, errBadDuration) {
+		_, err := eOR(m, tr(m, "admin.invalid_duration"))
+		return err
+	}
+	if err != nil {
+		_, err := eOR(m, tr(m, "admin.usage_warnaction"))
+		return err
+	}
+	if !isSupergroup(m) && restrictsOnly(action) {
+		_, err := eOR(m, tr(m, "admin.warnaction_supergroups_only"))
+		return err
+	}
+
+	settings := getWarnSettings(m.ChatID())
+	settings.Action, settings.Duration = action, d
+
+	if err := saveWarnSettings(m.ChatID(), settings); err != nil {
+		_, err := eOR(m, fmt.Sprintf(tr(m, "admin.restrict_error"), html.EscapeString(err.Error())))
+		return err
+	}
+	_, err = eOR(m, fmt.Sprintf(tr(m, "admin.warn_settings"), settings.Limit, settings.describe()))
+	return err
+}
+
+// lockType is a .lock name and the default chat right it takes away.
+type lockType struct {
+	Name  string
+	Right func(r *telegram.ChatBannedRights) *bool
+}
+
+var lockTypes = []lockType{
+	{"all", func(r *telegram.ChatBannedRights) *bool { return &r.SendMessages }},
+	{"text", func(r *telegram.ChatBannedRights) *bool { return &r.SendPlain }},
+	{"media", func(r *telegram.ChatBannedRights) *bool { return &r.SendMedia }},
+	{"photo", func(r *telegram.ChatBannedRights) *bool { return &r.SendPhotos }},
+	{"video", func(r *telegram.ChatBannedRights) *bool { return &r.SendVideos }},
+	{"round", func(r *telegram.ChatBannedRights) *bool { return &r.SendRoundvideos }},
+	{"audio", func(r *telegram.ChatBannedRights) *bool { return &r.SendAudios }},
+	{"voice", func(r *telegram.ChatBannedRights) *bool { return &r.SendVoices }},
+	{"document", func(r *telegram.ChatBannedRights) *bool { return &r.SendDocs }},
+	{"sticker", func(r *telegram.ChatBannedRights) *bool { return &r.SendStickers }},
+	{"gif", func(r *telegram.ChatBannedRights) *bool { return &r.SendGifs }},
+	{"game", func(r *telegram.ChatBannedRights) *bool { return &r.SendGames }},
+	{"inline", func(r *telegram.ChatBannedRights) *bool { return &r.SendInline }},
+	{"link", func(r *telegram.ChatBannedRights) *bool { return &r.EmbedLinks }},
+	{"poll", func(r *telegram.ChatBannedRights) *bool { return &r.SendPolls }},
+	{"invite", func(r *telegram.ChatBannedRights) *bool { return &r.InviteUsers }},
+	{"pin", func(r *telegram.ChatBannedRights) *bool { return &r.PinMessages }},
+	{"info", func(r *telegram.ChatBannedRights) *bool { return &r.ChangeInfo }},
+	{"topics", func(r *telegram.ChatBannedRights) *bool { return &r.ManageTopics }},
+}
+
+func lockNames() string {
+	names := make([]string, len(lockTypes))
+	for i, l := range lockTypes {
+		names[i] = l.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// defaultRights fetches the chat's current default restrictions from Telegram,
+// as the cached chat may be out of date.
+func defaultRights(peer telegram.InputPeer) (*telegram.ChatBannedRights, error) {
+	var chats []telegram.Chat
+	switch p := peer.(type) {
+	case *telegram.InputPeerChannel:
+		resp, err := client.ChannelsGetChannels([]telegram.InputChannel{&telegram.InputChannelObj{ChannelID: p.ChannelID, AccessHash: p.AccessHash}})
+		if err != nil {
+			return nil, err
+		}
+		switch r := resp.(type) {
+		case *telegram.MessagesChatsObj:
+			chats = r.Chats
+		case *telegram.MessagesChatsSlice:
+			chats = r.Chats
+		}
+	case *telegram.InputPeerChat:
+		resp, err := client.MessagesGetChats([]int64{p.ChatID})
+		if err != nil {
+			return nil, err
+		}
+		switch r := resp.(type) {
+		case *telegram.MessagesChatsObj:
+			chats = r.Chats
+		case *telegram.MessagesChatsSlice:
+			chats = r.Chats
+		}
+	default:
+		return nil, fmt.Errorf("not a group")
+	}
+
+	for _, chat := range chats {
+		switch c := chat.(type) {
+		case *telegram.Channel:
+			if c.DefaultBannedRights != nil {
+				return c.DefaultBannedRights, nil
+			}
+		case *telegram.ChatObj:
+			if c.DefaultBannedRights != nil {
+				return c.DefaultBannedRights, nil
+			}
+		}
+	}
+	return &telegram.ChatBannedRights{}, nil
+}
+
+// LockCmd handles .lock and .unlock, changing the chat's default permissions.
+func LockCmd(m *telegram.NewMessage) error {
+	if !m.IsGroup() {
+		_, err := eOR(m, tr(m, "admin.groups_only"))
+		return err
+	}
+	lock := !strings.Contains(strings.Fields(m.Text())[0], "unlock")
+	name := strings.ToLower(strings.TrimSpace(m.Args()))
+	idx := slices.IndexFunc(lockTypes, func(l lockType) bool { return l.Name == name })
+	if idx < 0 {
+		_, err := eOR(m, fmt.Sprintf(tr(m, "admin.usage_lock"), lockNames()))
+		return err
+	}
+
+	peer, err := client.ResolvePeer(m.ChatID())
+	if err != nil {
+		_, err := eOR(m, fmt.Sprintf(tr(m, "admin.restrict_error"), html.EscapeString(err.Error())))
+		return err
+	}
+	rights, err := defaultRights(peer)
+	if err != nil {
+		_, err := eOR(m, fmt.Sprintf(tr(m, "admin.restrict_error"), html.EscapeString(err.Error())))
+		return err
+	}
+
+	*lockTypes[idx].Right(rights) = lock
+	rights.UntilDate = 0
+	if _, err := client.MessagesEditChatDefaultBannedRights(peer, rights); err != nil && !telegram.MatchError(err, "CHAT_NOT_MODIFIED") {
+		_, err := eOR(m, fmt.Sprintf(tr(m, "admin.restrict_error"), html.EscapeString(err.Error())))
+		return err
+	}
+
+	key, action := "admin.unlocked", "unlock"
+	if lock {
+		key, action = "admin.locked", "lock"
+	}
+	auditAction(m, action, 0, "", name)
+	_, err = eOR(m, fmt.Sprintf(tr(m, key), name))
+	return err
+}
+
+func LocksCmd(m *telegram.NewMessage) error {
+	if !m.IsGroup() {
+		_, err := eOR(m, tr(m, "admin.groups_only"))
+		return err
+	}
+	peer, err := client.ResolvePeer(m.ChatID())
+	if err != nil {
+		_, err := eOR(m, fmt.Sprintf(tr(m, "admin.restrict_error"), html.EscapeString(err.Error())))
+		return err
+	}
+	rights, err := defaultRights(peer)
+	if err != nil {
+		_, err := eOR(m, fmt.Sprintf(tr(m, "admin.restrict_error"), html.EscapeString(err.Error())))
+		return err
+	}
+
+	text := tr(m, "admin.locks_header")
+	for _, l := range lockTypes {
+		state := "🔓"
+		if *l.Right(rights) {
+			state = "🔒"
+		}
+		text += fmt.Sprintf("%s <code>%s</code>\n", state, l.Name)
+	}
+	_, err = eOR(m, text)
+	return err
+}
+
+func LoadModerationModule(c *telegram.Client) {
+	RegisterVars([]*ConfigVar{
+		{Key: "ADMIN_AUDIT", Module: "Admin", Type: VarBool, Default: "true", Description: "Post admin actions such as bans, warns and locks to the moderation log"},
+	})
+
+	handlers := []*Handler{
+		{Command: "tban", Func: TbanUser, Description: "Ban a user for a while (<user> <duration> [reason])", ModuleName: "Admin"},
+		{Command: "tmute", Func: TmuteUser, Description: "Mute a user for a while (<user> <duration> [reason])", ModuleName: "Admin"},
+		{Command: "warn", Func: WarnUser, Description: "Warn a user, acting once they reach the warn limit", ModuleName: "Admin"},
+		{Command: "warns", Func: WarnsCmd, Description: "Show a user's warnings, or the chat's warn settings", ModuleName: "Admin"},
+		{Command: "rmwarn", Func: RmWarnCmd, Description: "Remove a user's latest warning", ModuleName: "Admin"},
+		{Command: "resetwarns", Func: RmWarnCmd, Description: "Remove all of a user's warnings", ModuleName: "Admin"},
+		{Command: "warnlimit", Func: WarnLimitCmd, Description: "Set how many warnings trigger the warn action", ModuleName: "Admin"},
+		{Command: "warnaction", Func: WarnActionCmd, Description: "Set the warn action (ban, kick, mute, tban <d>, tmute <d>)", ModuleName: "Admin"},
+		{Command: "lock", Func: LockCmd, Description: "Stop members sending a kind of content (media, sticker, link...)", ModuleName: "Admin"},
+		{Command: "unlock", Func: LockCmd, Description: "Allow a locked kind of content again", ModuleName: "Admin"},
+		{Command: "locks", Func: LocksCmd, Description: "Show the chat's locks", ModuleName: "Admin"},
+	}
+	AddHandlers(handlers, c)
+}
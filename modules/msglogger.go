@@ -0,0 +1,395 @@
+package modules
+
+import (
+	"NovaUserbot/db"
+	"NovaUserbot/locales"
+	"NovaUserbot/logger"
+	"NovaUserbot/utils"
+	"encoding/json"
+	"fmt"
+	"html"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+type MsgLoggerConfig struct {
+	LogChat   int64   `json:"log_chat"`
+	PMs       bool    `json:"pms"`
+	Chats     []int64 `json:"chats"`
+	Limit     int     `json:"limit"`
+	Retention int64   `json:"retention"`
+}
+
+type cachedMessage struct {
+	ChatID     int64
+	ChatTitle  string
+	SenderID   int64
+	SenderName string
+	Text       string
+	FileID     string
+	Link       string
+	Time       time.Time
+}
+
+// msgCacheKey identifies a message the same way delete updates do: message
+// IDs are only unique per channel, and shared by all PMs and basic groups.
+type msgCacheKey struct {
+	channel int64
+	id      int32
+}
+
+const (
+	msgLogDefaultLimit     = 200
+	msgLogMaxLimit         = 2000
+	msgLogDefaultRetention = 24 * time.Hour
+	msgLogMaxRetention     = 7 * 24 * time.Hour
+	msgLogMaxCaption       = 1024
+	// msgLogTextLength caps each logged text, so an edit with both the old
+	// and new text stays below Telegram's 4096 character limit.
+	msgLogTextLength = 1500
+)
+
+var (
+	msgLogConfig     MsgLoggerConfig
+	msgLogConfigLock sync.RWMutex
+
+	msgCache = struct {
+		sync.Mutex
+		items map[msgCacheKey]*cachedMessage
+		order map[int64][]msgCacheKey
+	}{
+		items: make(map[msgCacheKey]*cachedMessage),
+		order: make(map[int64][]msgCacheKey),
+	}
+)
+
+func loadMsgLoggerConfig() {
+	config := MsgLoggerConfig{Limit: msgLogDefaultLimit, Retention: int64(msgLogDefaultRetention.Seconds())}
+	if data := db.Get("MSG_LOGGER"); data != "" {
+		if err := json.Unmarshal([]byte(data), &config); err != nil {
+			logger.Errorf("Message logger config error: %v", err)
+		}
+	}
+
+	msgLogConfigLock.Lock()
+	msgLogConfig = config
+	msgLogConfigLock.Unlock()
+}
+
+func getMsgLoggerConfig() MsgLoggerConfig {
+	msgLogConfigLock.RLock()
+	defer msgLogConfigLock.RUnlock()
+
+	config := msgLogConfig
+	config.Chats = slices.Clone(msgLogConfig.Chats)
+	return config
+}
+
+func setMsgLoggerConfig(config MsgLoggerConfig) error {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	if err := db.Set("MSG_LOGGER", string(data)); err != nil {
+		return err
+	}
+
+	msgLogConfigLock.Lock()
+	msgLogConfig = config
+	msgLogConfigLock.Unlock()
+	return nil
+}
+
+func msgLogEnabled(config MsgLoggerConfig, m *telegram.NewMessage) bool {
+	if m.IsPrivate() {
+		return config.PMs || utils.IsIn64Array(config.Chats, m.ChatID())
+	}
+	return utils.IsIn64Array(config.Chats, m.ChatID())
+}
+
+func msgKey(m *telegram.NewMessage) msgCacheKey {
+	if m.Channel != nil {
+		return msgCacheKey{channel: m.Channel.ID, id: m.ID}
+	}
+	return msgCacheKey{id: m.ID}
+}
+
+// ============================================================================
+// Cache
+// ============================================================================
+
+// cacheMessage stores a message and evicts the oldest entries of that chat
+// once they exceed the configured count or retention.
+func cacheMessage(key msgCacheKey, msg *cachedMessage, config MsgLoggerConfig) {
+	msgCache.Lock()
+	defer msgCache.Unlock()
+
+	if _, exists := msgCache.items[key]; !exists {
+		msgCache.order[msg.ChatID] = append(msgCache.order[msg.ChatID], key)
+	}
+	msgCache.items[key] = msg
+
+	order := msgCache.order[msg.ChatID]
+	retention := time.Duration(config.Retention) * time.Second
+	for len(order) > 0 {
+		oldest, ok := msgCache.items[order[0]]
+		if ok && len(order) <= config.Limit && time.Since(oldest.Time) <= retention {
+			break
+		}
+		delete(msgCache.items, order[0])
+		order = order[1:]
+	}
+	msgCache.order[msg.ChatID] = order
+}
+
+func lookupCachedMessage(key msgCacheKey, remove bool) *cachedMessage {
+	msgCache.Lock()
+	defer msgCache.Unlock()
+
+	msg, ok := msgCache.items[key]
+	if !ok {
+		return nil
+	}
+	if remove {
+		// the key stays in the chat order and is skipped on eviction
+		delete(msgCache.items, key)
+	}
+	return msg
+}
+
+func dropChatCache(chatID int64) {
+	msgCache.Lock()
+	defer msgCache.Unlock()
+
+	for _, key := range msgCache.order[chatID] {
+		delete(msgCache.items, key)
+	}
+	delete(msgCache.order, chatID)
+}
+
+// ============================================================================
+// Event Handlers
+// ============================================================================
+
+func cacheIncomingMessage(m *telegram.NewMessage) error {
+	if m.Message.Out || m.Sender == nil || m.Sender.Bot {
+		return nil
+	}
+
+	config := getMsgLoggerConfig()
+	if !msgLogEnabled(config, m) {
+		return nil
+	}
+
+	msg := &cachedMessage{
+		ChatID:     m.ChatID(),
+		ChatTitle:  chatTitle(m),
+		SenderID:   m.Sender.ID,
+		SenderName: strings.TrimSpace(m.Sender.FirstName + " " + m.Sender.LastName),
+		Text:       m.Text(),
+		Link:       msgLink(m),
+		Time:       time.Now(),
+	}
+	if m.File != nil {
+		msg.FileID = m.File.FileID
+	}
+
+	cacheMessage(msgKey(m), msg, config)
+	return nil
+}
+
+func onMessageDeleted(d *telegram.DeleteMessage) error {
+	for _, id := range d.Messages {
+		msg := lookupCachedMessage(msgCacheKey{channel: d.ChannelID, id: id}, true)
+		if msg == nil {
+			continue
+		}
+
+		text := fmt.Sprintf(locales.Tr("msg_logger.deleted"),
+			html.EscapeString(msg.ChatTitle), msg.SenderID, html.EscapeString(msg.SenderName),
+			msg.Time.Format("02 Jan 15:04"), html.EscapeString(msgLogText(msg.Text)))
+
+		if err := sendMsgLog(text, msg.FileID); err != nil {
+			logger.Errorf("Message logger delete error: %v", err)
+		}
+	}
+	return nil
+}
+
+func onMessageEdited(m *telegram.NewMessage) error {
+	if m.Message.Out || m.Sender == nil {
+		return nil
+	}
+
+	key := msgKey(m)
+	old := lookupCachedMessage(key, false)
+	if old == nil || old.Text == m.Text() {
+		return nil
+	}
+
+	text := fmt.Sprintf(locales.Tr("msg_logger.edited"),
+		html.EscapeString(old.ChatTitle), old.SenderID, html.EscapeString(old.SenderName),
+		html.EscapeString(msgLogText(old.Text)), html.EscapeString(msgLogText(m.Text())))
+
+	updated := *old
+	updated.Text = m.Text()
+	if m.File != nil {
+		updated.FileID = m.File.FileID
+	}
+	cacheMessage(key, &updated, getMsgLoggerConfig())
+
+	opts := &telegram.SendOptions{ParseMode: "HTML"}
+	if old.Link != "" {
+		btn := telegram.ButtonBuilder{}
+		opts.ReplyMarkup = telegram.NewKeyboard().NewRow(1, btn.URL(locales.Tr("tag_logger.go_to_message"), old.Link)).Build()
+	}
+	return sendToMsgLog(text, opts)
+}
+
+func msgLogText(text string) string {
+	if text == "" {
+		return locales.Tr("tag_logger.no_text")
+	}
+	if runes := []rune(text); len(runes) > msgLogTextLength {
+		return string(runes[:msgLogTextLength]) + "…"
+	}
+	return text
+}
+
+// sendMsgLog posts a log entry with the original media re-uploaded by file ID.
+// If the media can't be sent (expired reference, caption too long) the text
+// goes out on its own.
+func sendMsgLog(text, fileID string) error {
+	if fileID != "" && len([]rune(text)) <= msgLogMaxCaption {
+		if media, err := telegram.ResolveBotFileID(fileID); err == nil {
+			if err := sendToMsgLog(text, &telegram.SendOptions{ParseMode: "HTML", Media: media}); err == nil {
+				return nil
+			}
+		}
+	}
+	if fileID != "" {
+		text += locales.Tr("msg_logger.media_unavailable")
+	}
+	return sendToMsgLog(text, &telegram.SendOptions{ParseMode: "HTML"})
+}
+
+func sendToMsgLog(text string, opts *telegram.SendOptions) error {
+	config := getMsgLoggerConfig()
+	if config.LogChat == 0 {
+		return logMessage(text, opts)
+	}
+
+	peer, err := logClient().GetSendablePeer(config.LogChat)
+	if err != nil {
+		return err
+	}
+	_, err = logClient().SendMessage(peer, text, opts)
+	return err
+}
+
+// ============================================================================
+// Command
+// ============================================================================
+
+func msgLogCommand(m *telegram.NewMessage) error {
+	args := strings.Fields(strings.ToLower(m.Args()))
+	config := getMsgLoggerConfig()
+
+	if len(args) == 0 {
+		logChat := tr(m, "msg_logger.log_chat_default")
+		if config.LogChat != 0 {
+			logChat = strconv.FormatInt(config.LogChat, 10)
+		}
+		_, err := eOR(m, fmt.Sprintf(tr(m, "msg_logger.status"),
+			msgLogEnabled(config, m), config.PMs, len(config.Chats), logChat,
+			config.Limit, fmtDur(time.Duration(config.Retention)*time.Second)))
+		return err
+	}
+
+	var reply string
+	switch args[0] {
+	case "on":
+		if !utils.IsIn64Array(config.Chats, m.ChatID()) {
+			config.Chats = append(config.Chats, m.ChatID())
+		}
+		reply = tr(m, "msg_logger.chat_enabled")
+	case "off":
+		config.Chats = slices.DeleteFunc(config.Chats, func(id int64) bool { return id == m.ChatID() })
+		dropChatCache(m.ChatID())
+		reply = tr(m, "msg_logger.chat_disabled")
+	case "pm":
+		if len(args) < 2 || (args[1] != "on" && args[1] != "off") {
+			_, err := eOR(m, tr(m, "msg_logger.usage"))
+			return err
+		}
+		config.PMs = args[1] == "on"
+		reply = fmt.Sprintf(tr(m, "msg_logger.pm_set"), args[1])
+	case "chat":
+		if len(args) < 2 {
+			_, err := eOR(m, tr(m, "msg_logger.usage"))
+			return err
+		}
+		chatID, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			_, err := eOR(m, tr(m, "logging.invalid_chat"))
+			return err
+		}
+		if _, err := logClient().GetSendablePeer(chatID); err != nil {
+			_, err := eOR(m, tr(m, "logging.assistant_not_in_chat"))
+			return err
+		}
+		config.LogChat = chatID
+		reply = fmt.Sprintf(tr(m, "msg_logger.log_chat_set"), chatID)
+	case "limit":
+		limit := 0
+		if len(args) > 1 {
+			limit, _ = strconv.Atoi(args[1])
+		}
+		if limit < 1 || limit > msgLogMaxLimit {
+			_, err := eOR(m, fmt.Sprintf(tr(m, "msg_logger.invalid_limit"), msgLogMaxLimit))
+			return err
+		}
+		config.Limit = limit
+		reply = fmt.Sprintf(tr(m, "msg_logger.limit_set"), limit)
+	case "keep":
+		var keep time.Duration
+		var err error
+		if len(args) > 1 {
+			keep, err = parseDurationString(args[1])
+		}
+		if len(args) < 2 || err != nil || keep < time.Minute || keep > msgLogMaxRetention {
+			_, err := eOR(m, tr(m, "msg_logger.invalid_retention"))
+			return err
+		}
+		config.Retention = int64(keep.Seconds())
+		reply = fmt.Sprintf(tr(m, "msg_logger.retention_set"), fmtDur(keep))
+	default:
+		_, err := eOR(m, tr(m, "msg_logger.usage"))
+		return err
+	}
+
+	if err := setMsgLoggerConfig(config); err != nil {
+		_, err := eOR(m, tr(m, "msg_logger.save_error"))
+		return err
+	}
+	_, err := eOR(m, reply)
+	return err
+}
+
+func LoadMsgLoggerModule(c *telegram.Client) {
+	handlers := []*Handler{
+		{Command: "msglog", Func: msgLogCommand, Description: "Log deleted/edited messages (on | off | pm on/off | chat <id> | limit <n> | keep <duration>)", ModuleName: "Message Logger", DisAllowSudos: true},
+	}
+	AddHandlers(handlers, c)
+
+	loadMsgLoggerConfig()
+
+	c.On("message", cacheIncomingMessage)
+	c.On("edit", onMessageEdited)
+	c.On("delete", onMessageDeleted)
+}
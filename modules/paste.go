@@ -2,7 +2,6 @@ package modules
 
 import (
 	"NovaUserbot/db"
-	"NovaUserbot/locales"
 	"bytes"
 	"encoding/json"
 	"fmt"
@@ -429,28 +428,28 @@ func pasteCommand(m *telegram.NewMessage) error {
 	if m.IsReply() {
 		reply, err := m.GetReplyMessage()
 		if err != nil {
-			_, err := eOR(m, locales.Tr("paste.fetch_error"))
+			_, err := eOR(m, tr(m, "paste.fetch_error"))
 			return err
 		}
 
 		if reply.Media() != nil {
 			doc := reply.Document()
 			if doc == nil {
-				_, err := eOR(m, locales.Tr("paste.unsupported_media"))
+				_, err := eOR(m, tr(m, "paste.unsupported_media"))
 				return err
 			}
 
 			if doc.Size > maxPasteSize {
-				_, err := eOR(m, locales.Tr("paste.file_too_large"))
+				_, err := eOR(m, tr(m, "paste.file_too_large"))
 				return err
 			}
 
-			msg, _ = eOR(m, locales.Tr("paste.downloading"))
+			msg, _ = eOR(m, tr(m, "paste.downloading"))
 
 			filePath, err := reply.Download()
 			if err != nil {
 				if msg != nil {
-					msg.Edit(locales.Tr("paste.download_error"))
+					msg.Edit(tr(m, "paste.download_error"))
 				}
 				return err
 			}
@@ -459,7 +458,7 @@ func pasteCommand(m *telegram.NewMessage) error {
 			data, err := os.ReadFile(filePath)
 			if err != nil {
 				if msg != nil {
-					msg.Edit(locales.Tr("paste.read_error"))
+					msg.Edit(tr(m, "paste.read_error"))
 				}
 				return err
 			}
@@ -467,18 +466,18 @@ func pasteCommand(m *telegram.NewMessage) error {
 			filename = filepath.Base(filePath)
 
 			if msg != nil {
-				msg.Edit(locales.Tr("paste.uploading"))
+				msg.Edit(tr(m, "paste.uploading"))
 			}
 		} else if reply.Text() != "" {
 			content = reply.Text()
 			filename = "paste.txt"
 		} else {
-			_, err := eOR(m, locales.Tr("paste.no_content"))
+			_, err := eOR(m, tr(m, "paste.no_content"))
 			return err
 		}
 	} else {
 		if textContent == "" {
-			_, err := eOR(m, locales.Tr("paste.usage"))
+			_, err := eOR(m, tr(m, "paste.usage"))
 			return err
 		}
 		content = textContent
@@ -486,12 +485,12 @@ func pasteCommand(m *telegram.NewMessage) error {
 	}
 
 	if len(content) > maxPasteSize {
-		_, err := eOR(m, locales.Tr("paste.content_too_large"))
+		_, err := eOR(m, tr(m, "paste.content_too_large"))
 		return err
 	}
 
 	if msg == nil {
-		msg, _ = eOR(m, locales.Tr("paste.uploading"))
+		msg, _ = eOR(m, tr(m, "paste.uploading"))
 	}
 
 	var pasteURL string
@@ -512,51 +511,51 @@ func pasteCommand(m *telegram.NewMessage) error {
 
 	if err != nil {
 		if msg != nil {
-			msg.Edit(fmt.Sprintf(locales.Tr("paste.upload_error"), err.Error()))
+			msg.Edit(fmt.Sprintf(tr(m, "paste.upload_error"), err.Error()))
 		}
 		return err
 	}
 
 	if msg != nil {
-		_, err = msg.Edit(fmt.Sprintf(locales.Tr("paste.success"), service, pasteURL))
+		_, err = msg.Edit(fmt.Sprintf(tr(m, "paste.success"), service, pasteURL))
 	}
 	return err
 }
 
 func readFileCommand(m *telegram.NewMessage) error {
 	if !m.IsReply() {
-		_, err := eOR(m, locales.Tr("read.reply_required"))
+		_, err := eOR(m, tr(m, "read.reply_required"))
 		return err
 	}
 
 	reply, err := m.GetReplyMessage()
 	if err != nil {
-		_, err := eOR(m, locales.Tr("read.fetch_error"))
+		_, err := eOR(m, tr(m, "read.fetch_error"))
 		return err
 	}
 
 	if reply.Media() == nil {
-		_, err := eOR(m, locales.Tr("read.no_file"))
+		_, err := eOR(m, tr(m, "read.no_file"))
 		return err
 	}
 
 	doc := reply.Document()
 	if doc == nil {
-		_, err := eOR(m, locales.Tr("read.no_file"))
+		_, err := eOR(m, tr(m, "read.no_file"))
 		return err
 	}
 
 	if doc.Size > maxPasteSize {
-		_, err := eOR(m, locales.Tr("read.file_too_large"))
+		_, err := eOR(m, tr(m, "read.file_too_large"))
 		return err
 	}
 
-	msg, _ := eOR(m, locales.Tr("read.downloading"))
+	msg, _ := eOR(m, tr(m, "read.downloading"))
 
 	filePath, err := reply.Download()
 	if err != nil {
 		if msg != nil {
-			msg.Edit(locales.Tr("read.download_error"))
+			msg.Edit(tr(m, "read.download_error"))
 		}
 		return err
 	}
@@ -565,7 +564,7 @@ func readFileCommand(m *telegram.NewMessage) error {
 	data, err := os.ReadFile(filePath)
 	if err != nil {
 		if msg != nil {
-			msg.Edit(locales.Tr("read.read_error"))
+			msg.Edit(tr(m, "read.read_error"))
 		}
 		return err
 	}
@@ -597,7 +596,7 @@ func readFileCommand(m *telegram.NewMessage) error {
 	}
 
 	filename := filepath.Base(filePath)
-	result := fmt.Sprintf(locales.Tr("read.result"), filename, len(lines), output)
+	result := fmt.Sprintf(tr(m, "read.result"), filename, len(lines), output)
 
 	if msg != nil {
 		_, err = msg.Edit(result, &telegram.SendOptions{ParseMode: "HTML"})
@@ -40,9 +40,10 @@ func OnPrivateMessage(m *telegram.NewMessage) error {
 	count := messageCounts[userID]
 	if count >= 3 {
 		countMutex.Unlock()
-		m.Reply(fmt.Sprintf(locales.Tr("pm_permit.message_limit"), client.Me().FirstName))
+		m.Reply(fmt.Sprintf(tr(m, "pm_permit.message_limit"), client.Me().FirstName))
 		peer, _ := m.Client.GetSendablePeer(userID)
 		m.Client.ContactsBlock(false, peer)
+		logTo(logger.CategoryPM, fmt.Sprintf(locales.Tr("pm_permit.log_blocked"), userID, m.Sender.FirstName))
 		return nil
 	}
 	messageCounts[userID] = count + 1
@@ -82,54 +83,54 @@ func OnPrivateMessage(m *telegram.NewMessage) error {
 func ApproveUser(m *telegram.NewMessage) error {
 	userID, name := ExtractUser(m)
 	if userID == 0 {
-		_, err := eOR(m, locales.Tr("pm_permit.invalid_user"))
+		_, err := eOR(m, tr(m, "pm_permit.invalid_user"))
 		return err
 	}
 
 	if db.SIsMember("APPROVED_USERS", userID) {
-		_, err := eOR(m, fmt.Sprintf(locales.Tr("pm_permit.already_approved"), userID, name))
+		_, err := eOR(m, fmt.Sprintf(tr(m, "pm_permit.already_approved"), userID, name))
 		return err
 	}
 
 	if err := db.SAdd("APPROVED_USERS", userID); err != nil {
-		_, err = eOR(m, locales.Tr("pm_permit.approve_error"))
+		_, err = eOR(m, tr(m, "pm_permit.approve_error"))
 		return err
 	}
 
-	_, err := eOR(m, fmt.Sprintf(locales.Tr("pm_permit.approved"), userID, name))
+	_, err := eOR(m, fmt.Sprintf(tr(m, "pm_permit.approved"), userID, name))
 	return err
 }
 
 func DisapproveUser(m *telegram.NewMessage) error {
 	userId, name := ExtractUser(m)
 	if userId == 0 {
-		_, err := eOR(m, locales.Tr("pm_permit.invalid_user"))
+		_, err := eOR(m, tr(m, "pm_permit.invalid_user"))
 		return err
 	}
 
 	if !db.SIsMember("APPROVED_USERS", userId) {
-		_, err := eOR(m, fmt.Sprintf(locales.Tr("pm_permit.not_approved"), userId, name))
+		_, err := eOR(m, fmt.Sprintf(tr(m, "pm_permit.not_approved"), userId, name))
 		return err
 	}
 
 	if err := db.SRem("APPROVED_USERS", userId); err != nil {
-		_, err = eOR(m, locales.Tr("pm_permit.disapprove_error"))
+		_, err = eOR(m, tr(m, "pm_permit.disapprove_error"))
 		return err
 	}
 
-	_, err := eOR(m, fmt.Sprintf(locales.Tr("pm_permit.disapproved"), userId, name))
+	_, err := eOR(m, fmt.Sprintf(tr(m, "pm_permit.disapproved"), userId, name))
 	return err
 }
 
 func ApprovedUsers(m *telegram.NewMessage) error {
 	users, err := db.SMembers("APPROVED_USERS")
 	if err != nil {
-		_, err = eOR(m, locales.Tr("pm_permit.fetch_error"))
+		_, err = eOR(m, tr(m, "pm_permit.fetch_error"))
 		return err
 	}
 
-	msg, _ := eOR(m, locales.Tr("pm_permit.fetching"))
-	output := locales.Tr("pm_permit.list_header") + "\n"
+	msg, _ := eOR(m, tr(m, "pm_permit.fetching"))
+	output := tr(m, "pm_permit.list_header") + "\n"
 
 	for _, id := range users {
 		user, err := m.Client.GetUser(utils.StringToInt64(id))
@@ -146,20 +147,24 @@ func ApprovedUsers(m *telegram.NewMessage) error {
 func SetPromt(m *telegram.NewMessage) error {
 	prompt := m.Args()
 	if prompt == "" {
-		_, err := eOR(m, locales.Tr("pm_permit.usage_prompt"))
+		_, err := eOR(m, tr(m, "pm_permit.usage_prompt"))
 		return err
 	}
 
 	if err := db.Set("PM_AI_PROMT", prompt); err != nil {
-		_, err = eOR(m, locales.Tr("pm_permit.prompt_error"))
+		_, err = eOR(m, tr(m, "pm_permit.prompt_error"))
 		return err
 	}
 
-	_, err := eOR(m, locales.Tr("pm_permit.prompt_set"))
+	_, err := eOR(m, tr(m, "pm_permit.prompt_set"))
 	return err
 }
 
 func LoadPmAssistantHandler(c *telegram.Client) {
+	RegisterVars([]*ConfigVar{
+		{Key: "PM_AI_PROMT", Module: "Pm Permit", Description: "Prompt for the AI assistant that answers unknown PMs"},
+	})
+
 	handlers := []*Handler{
 		{ModuleName: "Pm Permit", Command: "ap", Description: "Approve a user", Func: ApproveUser},
 		{ModuleName: "Pm Permit", Command: "dap", Description: "Disapprove a user", Func: DisapproveUser},
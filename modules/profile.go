@@ -1,7 +1,6 @@
 package modules
 
 import (
-	"NovaUserbot/locales"
 	"fmt"
 	"os"
 	"strconv"
@@ -13,11 +12,11 @@ import (
 func setNameCommand(m *telegram.NewMessage) error {
 	args := strings.TrimSpace(m.Args())
 	if args == "" {
-		_, err := eOR(m, locales.Tr("profile.setname_usage"))
+		_, err := eOR(m, tr(m, "profile.setname_usage"))
 		return err
 	}
 
-	msg, _ := eOR(m, locales.Tr("profile.updating"))
+	msg, _ := eOR(m, tr(m, "profile.updating"))
 
 	firstName := args
 	lastName := ""
@@ -32,13 +31,13 @@ func setNameCommand(m *telegram.NewMessage) error {
 
 	if err != nil {
 		if msg != nil {
-			msg.Edit(fmt.Sprintf(locales.Tr("profile.update_error"), err.Error()))
+			msg.Edit(fmt.Sprintf(tr(m, "profile.update_error"), err.Error()))
 		}
 		return err
 	}
 
 	if msg != nil {
-		msg.Edit(fmt.Sprintf(locales.Tr("profile.name_changed"), args))
+		msg.Edit(fmt.Sprintf(tr(m, "profile.name_changed"), args))
 	}
 	return nil
 }
@@ -46,63 +45,63 @@ func setNameCommand(m *telegram.NewMessage) error {
 func setBioCommand(m *telegram.NewMessage) error {
 	args := strings.TrimSpace(m.Args())
 	if args == "" {
-		_, err := eOR(m, locales.Tr("profile.setbio_usage"))
+		_, err := eOR(m, tr(m, "profile.setbio_usage"))
 		return err
 	}
 
-	msg, _ := eOR(m, locales.Tr("profile.updating"))
+	msg, _ := eOR(m, tr(m, "profile.updating"))
 
 	_, err := m.Client.AccountUpdateProfile("", "", args)
 
 	if err != nil {
 		if msg != nil {
-			msg.Edit(fmt.Sprintf(locales.Tr("profile.update_error"), err.Error()))
+			msg.Edit(fmt.Sprintf(tr(m, "profile.update_error"), err.Error()))
 		}
 		return err
 	}
 
 	if msg != nil {
-		msg.Edit(fmt.Sprintf(locales.Tr("profile.bio_changed"), args))
+		msg.Edit(fmt.Sprintf(tr(m, "profile.bio_changed"), args))
 	}
 	return nil
 }
 
 func setPicCommand(m *telegram.NewMessage) error {
 	if !m.IsReply() {
-		_, err := eOR(m, locales.Tr("profile.reply_required"))
+		_, err := eOR(m, tr(m, "profile.reply_required"))
 		return err
 	}
 
 	reply, err := m.GetReplyMessage()
 	if err != nil {
-		_, err := eOR(m, locales.Tr("profile.fetch_error"))
+		_, err := eOR(m, tr(m, "profile.fetch_error"))
 		return err
 	}
 
 	if reply.Photo() == nil && reply.Video() == nil && reply.Document() == nil {
-		_, err := eOR(m, locales.Tr("profile.no_media"))
+		_, err := eOR(m, tr(m, "profile.no_media"))
 		return err
 	}
 
-	msg, _ := eOR(m, locales.Tr("profile.downloading"))
+	msg, _ := eOR(m, tr(m, "profile.downloading"))
 
 	filePath, err := reply.Download()
 	if err != nil {
 		if msg != nil {
-			msg.Edit(locales.Tr("profile.download_error"))
+			msg.Edit(tr(m, "profile.download_error"))
 		}
 		return err
 	}
 	defer os.Remove(filePath)
 
 	if msg != nil {
-		msg.Edit(locales.Tr("profile.uploading"))
+		msg.Edit(tr(m, "profile.uploading"))
 	}
 
 	file, err := m.Client.UploadFile(filePath)
 	if err != nil {
 		if msg != nil {
-			msg.Edit(locales.Tr("profile.upload_error"))
+			msg.Edit(tr(m, "profile.upload_error"))
 		}
 		return err
 	}
@@ -128,13 +127,13 @@ func setPicCommand(m *telegram.NewMessage) error {
 
 	if uploadErr != nil {
 		if msg != nil {
-			msg.Edit(fmt.Sprintf(locales.Tr("profile.update_error"), uploadErr.Error()))
+			msg.Edit(fmt.Sprintf(tr(m, "profile.update_error"), uploadErr.Error()))
 		}
 		return uploadErr
 	}
 
 	if msg != nil {
-		msg.Edit(locales.Tr("profile.pic_changed"))
+		msg.Edit(tr(m, "profile.pic_changed"))
 	}
 	return nil
 }
@@ -151,19 +150,19 @@ func delPfpCommand(m *telegram.NewMessage) error {
 		}
 	}
 
-	msg, _ := eOR(m, locales.Tr("profile.deleting"))
+	msg, _ := eOR(m, tr(m, "profile.deleting"))
 
 	photos, err := m.Client.GetProfilePhotos(m.Sender.ID, &telegram.PhotosOptions{Limit: count})
 	if err != nil {
 		if msg != nil {
-			msg.Edit(fmt.Sprintf(locales.Tr("profile.fetch_error")+": %s", err.Error()))
+			msg.Edit(fmt.Sprintf(tr(m, "profile.fetch_error")+": %s", err.Error()))
 		}
 		return err
 	}
 
 	if len(photos) == 0 {
 		if msg != nil {
-			msg.Edit(locales.Tr("profile.no_pfp"))
+			msg.Edit(tr(m, "profile.no_pfp"))
 		}
 		return nil
 	}
@@ -181,7 +180,7 @@ func delPfpCommand(m *telegram.NewMessage) error {
 
 	if len(photoList) == 0 {
 		if msg != nil {
-			msg.Edit(locales.Tr("profile.no_pfp"))
+			msg.Edit(tr(m, "profile.no_pfp"))
 		}
 		return nil
 	}
@@ -189,13 +188,13 @@ func delPfpCommand(m *telegram.NewMessage) error {
 	_, err = m.Client.PhotosDeletePhotos(photoList)
 	if err != nil {
 		if msg != nil {
-			msg.Edit(fmt.Sprintf(locales.Tr("profile.update_error"), err.Error()))
+			msg.Edit(fmt.Sprintf(tr(m, "profile.update_error"), err.Error()))
 		}
 		return err
 	}
 
 	if msg != nil {
-		msg.Edit(fmt.Sprintf(locales.Tr("profile.pfp_deleted"), len(photoList)))
+		msg.Edit(fmt.Sprintf(tr(m, "profile.pfp_deleted"), len(photoList)))
 	}
 	return nil
 }
@@ -208,11 +207,11 @@ func getPfpCommand(m *telegram.NewMessage) error {
 	if m.IsReply() {
 		reply, err := m.GetReplyMessage()
 		if err != nil {
-			_, err := eOR(m, locales.Tr("profile.fetch_error"))
+			_, err := eOR(m, tr(m, "profile.fetch_error"))
 			return err
 		}
 		if reply.Sender == nil {
-			_, err := eOR(m, locales.Tr("profile.user_not_found"))
+			_, err := eOR(m, tr(m, "profile.user_not_found"))
 			return err
 		}
 		targetPeer = reply.Sender.ID
@@ -240,19 +239,19 @@ func getPfpCommand(m *telegram.NewMessage) error {
 		}
 	}
 
-	msg, _ := eOR(m, locales.Tr("profile.fetching"))
+	msg, _ := eOR(m, tr(m, "profile.fetching"))
 
 	photos, err := m.Client.GetProfilePhotos(targetPeer, &telegram.PhotosOptions{Limit: limit})
 	if err != nil {
 		if msg != nil {
-			msg.Edit(fmt.Sprintf(locales.Tr("profile.fetch_error")+": %v", err))
+			msg.Edit(fmt.Sprintf(tr(m, "profile.fetch_error")+": %v", err))
 		}
 		return err
 	}
 
 	if len(photos) == 0 {
 		if msg != nil {
-			msg.Edit(locales.Tr("profile.no_pfp_found"))
+			msg.Edit(tr(m, "profile.no_pfp_found"))
 		}
 		return nil
 	}
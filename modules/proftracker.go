@@ -0,0 +1,439 @@
+package modules
+
+import (
+	"NovaUserbot/db"
+	"NovaUserbot/locales"
+	"NovaUserbot/logger"
+	"NovaUserbot/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+const (
+	profileWatchKey     = "PROFILE_WATCH"
+	profileSnapPrefix   = "PROFSNAP:"
+	profileHistPrefix   = "PROFHIST:"
+	profileHistoryLimit = 100
+	profileShownHistory = 20
+	profileFetchGap     = 2 * time.Second
+)
+
+// ProfileSnapshot is the last seen public profile of a tracked user.
+type ProfileSnapshot struct {
+	ID        int64     `json:"id"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name,omitempty"`
+	Usernames []string  `json:"usernames,omitempty"`
+	Bio       string    `json:"bio,omitempty"`
+	PhotoID   int64     `json:"photo_id,omitempty"`
+	Seen      time.Time `json:"seen"`
+}
+
+func (p ProfileSnapshot) name() string {
+	return strings.TrimSpace(p.FirstName + " " + p.LastName)
+}
+
+func (p ProfileSnapshot) usernames() string {
+	if len(p.Usernames) == 0 {
+		return ""
+	}
+	return "@" + strings.Join(p.Usernames, ", @")
+}
+
+// ProfileChange is one entry of a user's profile history.
+type ProfileChange struct {
+	Time  time.Time `json:"time"`
+	Field string    `json:"field"`
+	Old   string    `json:"old"`
+	New   string    `json:"new"`
+}
+
+var profileTrackMutex sync.Mutex
+
+func userUsernames(user *telegram.UserObj) []string {
+	var names []string
+	if user.Username != "" {
+		names = append(names, user.Username)
+	}
+	for _, u := range user.Usernames {
+		if u.Active && !slices.Contains(names, u.Username) {
+			names = append(names, u.Username)
+		}
+	}
+	return names
+}
+
+// fetchProfile reads the full profile of a user, including the bio.
+func fetchProfile(userID int64) (ProfileSnapshot, error) {
+	input, err := client.GetSendableUser(userID)
+	if err != nil {
+		return ProfileSnapshot{}, err
+	}
+	full, err := client.UsersGetFullUser(input)
+	if err != nil {
+		return ProfileSnapshot{}, err
+	}
+
+	snap := ProfileSnapshot{ID: userID, Seen: time.Now()}
+	for _, u := range full.Users {
+		if user, ok := u.(*telegram.UserObj); ok && user.ID == userID {
+			snap.FirstName, snap.LastName = user.FirstName, user.LastName
+			snap.Usernames = userUsernames(user)
+		}
+	}
+	if full.FullUser != nil {
+		snap.Bio = full.FullUser.About
+		if photo, ok := full.FullUser.ProfilePhoto.(*telegram.PhotoObj); ok {
+			snap.PhotoID = photo.ID
+		}
+	}
+	return snap, nil
+}
+
+func getProfileSnapshot(userID int64) (ProfileSnapshot, bool) {
+	data := db.Get(profileSnapPrefix + strconv.FormatInt(userID, 10))
+	if data == "" {
+		return ProfileSnapshot{}, false
+	}
+	var snap ProfileSnapshot
+	if err := json.Unmarshal([]byte(data), &snap); err != nil {
+		logger.Errorf("Corrupted profile snapshot of %d: %v", userID, err)
+		return ProfileSnapshot{}, false
+	}
+	return snap, true
+}
+
+func getProfileHistory(userID int64) []ProfileChange {
+	data := db.Get(profileHistPrefix + strconv.FormatInt(userID, 10))
+	if data == "" {
+		return nil
+	}
+	var history []ProfileChange
+	if err := json.Unmarshal([]byte(data), &history); err != nil {
+		logger.Errorf("Corrupted profile history of %d: %v", userID, err)
+		return nil
+	}
+	return history
+}
+
+func profileChanges(old, cur ProfileSnapshot) []ProfileChange {
+	var changes []ProfileChange
+	add := func(field, before, after string) {
+		if before != after {
+			changes = append(changes, ProfileChange{Time: cur.Seen, Field: field, Old: before, New: after})
+		}
+	}
+	add("name", old.name(), cur.name())
+	add("username", old.usernames(), cur.usernames())
+	add("bio", old.Bio, cur.Bio)
+	if old.PhotoID != cur.PhotoID {
+		changes = append(changes, ProfileChange{Time: cur.Seen, Field: "photo", Old: strconv.FormatInt(old.PhotoID, 10), New: strconv.FormatInt(cur.PhotoID, 10)})
+	}
+	return changes
+}
+
+// recordProfile stores cur and logs what changed since the last snapshot.
+// The first snapshot of a user is stored silently. With partial set, only
+// the name and usernames are compared, as name updates carry no bio or photo.
+func recordProfile(cur ProfileSnapshot, partial bool) {
+	profileTrackMutex.Lock()
+	defer profileTrackMutex.Unlock()
+
+	old, known := getProfileSnapshot(cur.ID)
+	if partial {
+		if !known {
+			return
+		}
+		cur.Bio, cur.PhotoID = old.Bio, old.PhotoID
+	}
+
+	var changes []ProfileChange
+	if known {
+		changes = profileChanges(old, cur)
+	}
+
+	id := strconv.FormatInt(cur.ID, 10)
+	data, _ := json.Marshal(cur)
+	db.Set(profileSnapPrefix+id, string(data))
+	if len(changes) == 0 {
+		return
+	}
+
+	history := append(getProfileHistory(cur.ID), changes...)
+	if len(history) > profileHistoryLimit {
+		history = history[len(history)-profileHistoryLimit:]
+	}
+	data, _ = json.Marshal(history)
+	db.Set(profileHistPrefix+id, string(data))
+
+	reportProfileChanges(cur, changes)
+}
+
+func profileTrackerChat() int64 {
+	if chat := utils.StringToInt64(getVar("PROFILE_TRACKER")); chat != 0 {
+		return chat
+	}
+	if chat := utils.StringToInt64(db.Get("LOG_CHAT")); chat != 0 {
+		return chat
+	}
+	return ubId
+}
+
+func reportProfileChanges(cur ProfileSnapshot, changes []ProfileChange) {
+	text := fmt.Sprintf(locales.Tr("proftracker.changed_header"), cur.ID, html.EscapeString(cur.name()))
+	for _, c := range changes {
+		if c.Field == "photo" {
+			text += locales.Tr("proftracker.changed_photo")
+			continue
+		}
+		text += fmt.Sprintf(locales.Tr("proftracker.changed_field"), locales.Tr("proftracker.field_"+c.Field), html.EscapeString(orNone(c.Old)), html.EscapeString(orNone(c.New)))
+	}
+
+	peer, err := logClient().GetSendablePeer(profileTrackerChat())
+	if err != nil {
+		logger.Errorf("Profile tracker: %v", err)
+		return
+	}
+	if _, err := logClient().SendMessage(peer, text); err != nil {
+		logger.Errorf("Profile tracker: %v", err)
+	}
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// trackedUsers returns the watched users, plus all contacts when
+// PROFILE_TRACK_CONTACTS is on.
+func trackedUsers() []int64 {
+	var ids []int64
+	members, _ := db.SMembers(profileWatchKey)
+	for _, m := range members {
+		if id, err := strconv.ParseInt(m, 10, 64); err == nil {
+			ids = append(ids, id)
+		}
+	}
+
+	if getVar("PROFILE_TRACK_CONTACTS") == "true" {
+		if contacts, err := client.ContactsGetContacts(0); err == nil {
+			if c, ok := contacts.(*telegram.ContactsContactsObj); ok {
+				for _, contact := range c.Contacts {
+					if !slices.Contains(ids, contact.UserID) {
+						ids = append(ids, contact.UserID)
+					}
+				}
+			}
+		} else {
+			logger.Warnf("Profile tracker: could not fetch contacts: %v", err)
+		}
+	}
+	return ids
+}
+
+func isTrackedUser(userID int64) bool {
+	if db.SIsMember(profileWatchKey, userID) {
+		return true
+	}
+	if getVar("PROFILE_TRACK_CONTACTS") != "true" {
+		return false
+	}
+	user, err := client.GetUser(userID)
+	return err == nil && user.Contact
+}
+
+// refreshProfiles fetches every tracked profile, spaced out to stay clear of
+// flood limits, and waits out any flood wait it still gets.
+func refreshProfiles(ctx context.Context) {
+	for _, id := range trackedUsers() {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(profileFetchGap):
+		}
+
+		snap, err := fetchProfile(id)
+		if wait := telegram.GetFloodWait(err); wait > 0 {
+			logger.Warnf("Profile tracker: flood wait of %ds", wait)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Duration(wait) * time.Second):
+			}
+			continue
+		}
+		if err != nil {
+			logger.Debugf("Profile tracker: could not fetch %d: %v", id, err)
+			continue
+		}
+		recordProfile(snap, false)
+	}
+}
+
+func runProfileTracker(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	var lastRefresh time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if time.Since(lastRefresh) < minutesVar("PROFILE_TRACK_INTERVAL") {
+			continue
+		}
+		lastRefresh = time.Now()
+		refreshProfiles(ctx)
+	}
+}
+
+// profileUpdateHandler reacts to name changes pushed by Telegram right away;
+// bios and photos are only seen by the periodic refresh or a UpdateUser.
+func profileUpdateHandler(u telegram.Update, c *telegram.Client) error {
+	switch upd := u.(type) {
+	case *telegram.UpdateUserName:
+		if !isTrackedUser(upd.UserID) {
+			return nil
+		}
+		snap := ProfileSnapshot{ID: upd.UserID, FirstName: upd.FirstName, LastName: upd.LastName, Seen: time.Now()}
+		for _, un := range upd.Usernames {
+			if un.Active {
+				snap.Usernames = append(snap.Usernames, un.Username)
+			}
+		}
+		recordProfile(snap, true)
+	case *telegram.UpdateUser:
+		if !isTrackedUser(upd.UserID) {
+			return nil
+		}
+		if snap, err := fetchProfile(upd.UserID); err == nil {
+			recordProfile(snap, false)
+		}
+	}
+	return nil
+}
+
+func ProfWatchCmd(m *telegram.NewMessage) error {
+	sub := strings.ToLower(strings.TrimSpace(m.Args()))
+	if sub == "list" {
+		members, _ := db.SMembers(profileWatchKey)
+		if len(members) == 0 {
+			_, err := eOR(m, tr(m, "proftracker.list_empty"))
+			return err
+		}
+
+		text := fmt.Sprintf(tr(m, "proftracker.list_header"), profileTrackerChat())
+		for _, member := range members {
+			id, _ := strconv.ParseInt(member, 10, 64)
+			name := member
+			if snap, ok := getProfileSnapshot(id); ok {
+				name = snap.name()
+			}
+			text += fmt.Sprintf(tr(m, "proftracker.list_entry"), html.EscapeString(name), id)
+		}
+		if getVar("PROFILE_TRACK_CONTACTS") == "true" {
+			text += tr(m, "proftracker.list_contacts")
+		}
+		_, err := eOR(m, text)
+		return err
+	}
+
+	remove := strings.HasPrefix(sub, "remove")
+	var userID int64
+	if arg := strings.TrimSpace(strings.TrimPrefix(sub, "remove")); remove && arg != "" {
+		if id, err := strconv.ParseInt(arg, 10, 64); err == nil {
+			userID = id
+		} else {
+			userID, _ = GetUserInfo(strings.TrimPrefix(arg, "@"))
+		}
+	} else {
+		userID, _ = ExtractUser(m)
+	}
+	if userID == 0 {
+		_, err := eOR(m, tr(m, "proftracker.usage"))
+		return err
+	}
+
+	if remove {
+		db.SRem(profileWatchKey, userID)
+		_, err := eOR(m, fmt.Sprintf(tr(m, "proftracker.removed"), userID))
+		return err
+	}
+
+	snap, err := fetchProfile(userID)
+	if err != nil {
+		_, err := eOR(m, fmt.Sprintf(tr(m, "proftracker.fetch_error"), html.EscapeString(err.Error())))
+		return err
+	}
+	db.SAdd(profileWatchKey, userID)
+	recordProfile(snap, false)
+
+	_, err = eOR(m, fmt.Sprintf(tr(m, "proftracker.added"), html.EscapeString(snap.name()), userID))
+	return err
+}
+
+// HistoryCmd shows the recorded profile changes of a user, newest first.
+func HistoryCmd(m *telegram.NewMessage) error {
+	userID, _ := ExtractUser(m)
+	if userID == 0 {
+		_, err := eOR(m, tr(m, "proftracker.history_usage"))
+		return err
+	}
+
+	snap, known := getProfileSnapshot(userID)
+	if !known {
+		_, err := eOR(m, tr(m, "proftracker.not_tracked"))
+		return err
+	}
+
+	history := getProfileHistory(userID)
+	text := fmt.Sprintf(tr(m, "proftracker.history_header"), html.EscapeString(snap.name()), userID, html.EscapeString(orNone(snap.usernames())))
+	if len(history) == 0 {
+		_, err := eOR(m, text+tr(m, "proftracker.history_empty"))
+		return err
+	}
+
+	for i := len(history) - 1; i >= 0 && i >= len(history)-profileShownHistory; i-- {
+		c := history[i]
+		date := c.Time.Format("2006-01-02")
+		if c.Field == "photo" {
+			text += fmt.Sprintf(tr(m, "proftracker.history_photo"), date)
+			continue
+		}
+		text += fmt.Sprintf(tr(m, "proftracker.history_entry"), date, tr(m, "proftracker.field_"+c.Field), html.EscapeString(orNone(c.Old)), html.EscapeString(orNone(c.New)))
+	}
+	_, err := eOR(m, text)
+	return err
+}
+
+func LoadProfileTrackerModule(c *telegram.Client) {
+	RegisterVars([]*ConfigVar{
+		{Key: "PROFILE_TRACKER", Module: "Profile Tracker", Type: VarChatID, Description: "Chat profile changes are logged to, the log chat when unset"},
+		{Key: "PROFILE_TRACK_CONTACTS", Module: "Profile Tracker", Type: VarBool, Default: "false", Description: "Track the profiles of all contacts, not only watched users"},
+		{Key: "PROFILE_TRACK_INTERVAL", Module: "Profile Tracker", Type: VarInt, Default: "120", Description: "Minutes between full refreshes of tracked profiles", Validate: intRange(15, 1440)},
+	})
+
+	handlers := []*Handler{
+		{Command: "profwatch", Func: ProfWatchCmd, Description: "Log profile changes of a user (<user>, remove <user>, list)", ModuleName: "Profile Tracker", DisAllowSudos: true},
+		{Command: "history", Func: HistoryCmd, Description: "Show past names, usernames and bios of a tracked user", ModuleName: "Profile Tracker"},
+	}
+	AddHandlers(handlers, c)
+
+	c.On(&telegram.UpdateUserName{}, profileUpdateHandler)
+	c.On(&telegram.UpdateUser{}, profileUpdateHandler)
+	startWorker("profile tracker", runProfileTracker)
+}
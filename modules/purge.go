@@ -0,0 +1,207 @@
+package modules
+
+import (
+	"NovaUserbot/db"
+	"NovaUserbot/logger"
+	"encoding/json"
+	"fmt"
+	"html"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+const (
+	selfDestructKey   = "SELF_DESTRUCT"
+	deleteBatchSize   = 100
+	purgeMeMax        = 3000
+	selfDestructMax   = 7 * 24 * time.Hour
+	purgeNoticeExpiry = 3 * time.Second
+)
+
+// SelfDestruct is a message that is deleted at At. Pending ones are kept in
+// the database so they still go after a restart.
+type SelfDestruct struct {
+	ChatID int64     `json:"chat_id"`
+	MsgID  int32     `json:"msg_id"`
+	At     time.Time `json:"at"`
+}
+
+var selfDestructMutex sync.Mutex
+
+// deleteMessages deletes ids in batches, waiting out flood waits, and returns
+// how many were deleted.
+func deleteMessages(chatID int64, ids []int32) (int, error) {
+	deleted := 0
+	for batch := range slices.Chunk(ids, deleteBatchSize) {
+		_, err := client.DeleteMessages(chatID, batch)
+		for wait := telegram.GetFloodWait(err); wait > 0; wait = telegram.GetFloodWait(err) {
+			logger.Warnf("Delete messages: flood wait of %ds", wait)
+			time.Sleep(time.Duration(wait) * time.Second)
+			_, err = client.DeleteMessages(chatID, batch)
+		}
+		if err != nil {
+			return deleted, err
+		}
+		deleted += len(batch)
+	}
+	return deleted, nil
+}
+
+// collectMessageIDs returns the IDs of the messages matching opts in the chat.
+// Only IDs of this chat are ever returned, which matters in private chats and
+// basic groups where message IDs are shared across the account.
+func collectMessageIDs(chatID int64, opts *telegram.SearchOption) ([]int32, error) {
+	var ids []int32
+	err := client.IterMessages(chatID, func(msg *telegram.NewMessage) error {
+		ids = append(ids, msg.ID)
+		return nil
+	}, opts)
+	return ids, err
+}
+
+func purgeResult(m *telegram.NewMessage, deleted int, err error) error {
+	text := fmt.Sprintf(tr(m, "admin.purged"), deleted)
+	if err != nil {
+		text += fmt.Sprintf(tr(m, "admin.purge_error"), html.EscapeString(err.Error()))
+	}
+	notice, sendErr := m.Respond(text)
+	if sendErr != nil {
+		return sendErr
+	}
+	scheduleSelfDestruct(notice.ChatID(), notice.ID, time.Now().Add(purgeNoticeExpiry))
+	return nil
+}
+
+// PurgeCmd deletes every message from the replied one up to the command.
+func PurgeCmd(m *telegram.NewMessage) error {
+	if !m.IsReply() {
+		_, err := eOR(m, tr(m, "admin.usage_purge"))
+		return err
+	}
+
+	from := m.ReplyToMsgID()
+	ids, err := collectMessageIDs(m.ChatID(), &telegram.SearchOption{MinID: from - 1, MaxID: m.ID, Limit: m.ID - from + 1})
+	if err != nil {
+		_, err := eOR(m, fmt.Sprintf(tr(m, "admin.delete_error"), html.EscapeString(err.Error())))
+		return err
+	}
+
+	deleted, err := deleteMessages(m.ChatID(), append(ids, m.ID))
+	return purgeResult(m, max(deleted-1, 0), err)
+}
+
+// PurgeMeCmd deletes the last N messages sent by the userbot in this chat.
+func PurgeMeCmd(m *telegram.NewMessage) error {
+	count, err := strconv.Atoi(strings.TrimSpace(m.Args()))
+	if err != nil || count <= 0 {
+		_, err := eOR(m, tr(m, "admin.usage_purgeme"))
+		return err
+	}
+	count = min(count, purgeMeMax)
+
+	ids, err := collectMessageIDs(m.ChatID(), &telegram.SearchOption{FromUser: ubId, MaxID: m.ID, Limit: int32(count)})
+	if err != nil {
+		_, err := eOR(m, fmt.Sprintf(tr(m, "admin.delete_error"), html.EscapeString(err.Error())))
+		return err
+	}
+
+	deleted, err := deleteMessages(m.ChatID(), append(ids, m.ID))
+	return purgeResult(m, max(deleted-1, 0), err)
+}
+
+// DelCmd deletes the replied message along with the command.
+func DelCmd(m *telegram.NewMessage) error {
+	if !m.IsReply() {
+		_, err := eOR(m, tr(m, "admin.usage_del"))
+		return err
+	}
+
+	if _, err := deleteMessages(m.ChatID(), []int32{m.ReplyToMsgID(), m.ID}); err != nil {
+		_, err := eOR(m, fmt.Sprintf(tr(m, "admin.delete_error"), html.EscapeString(err.Error())))
+		return err
+	}
+	return nil
+}
+
+func getSelfDestructs() []SelfDestruct {
+	data := db.Get(selfDestructKey)
+	if data == "" {
+		return nil
+	}
+	var pending []SelfDestruct
+	if err := json.Unmarshal([]byte(data), &pending); err != nil {
+		logger.Errorf("Failed to parse self-destructing messages: %v", err)
+		return nil
+	}
+	return pending
+}
+
+func saveSelfDestructs(pending []SelfDestruct) {
+	if len(pending) == 0 {
+		db.Del(selfDestructKey)
+		return
+	}
+	data, _ := json.Marshal(pending)
+	db.Set(selfDestructKey, string(data))
+}
+
+// scheduleSelfDestruct stores the message and arms a timer deleting it at at.
+func scheduleSelfDestruct(chatID int64, msgID int32, at time.Time) {
+	sd := SelfDestruct{ChatID: chatID, MsgID: msgID, At: at}
+
+	selfDestructMutex.Lock()
+	saveSelfDestructs(append(getSelfDestructs(), sd))
+	selfDestructMutex.Unlock()
+
+	armSelfDestruct(sd)
+}
+
+func armSelfDestruct(sd SelfDestruct) {
+	time.AfterFunc(time.Until(sd.At), func() {
+		if _, err := deleteMessages(sd.ChatID, []int32{sd.MsgID}); err != nil {
+			logger.Debugf("Self-destruct of %d in %d failed: %v", sd.MsgID, sd.ChatID, err)
+		}
+
+		selfDestructMutex.Lock()
+		defer selfDestructMutex.Unlock()
+		saveSelfDestructs(slices.DeleteFunc(getSelfDestructs(), func(p SelfDestruct) bool {
+			return p.ChatID == sd.ChatID && p.MsgID == sd.MsgID
+		}))
+	})
+}
+
+// SelfDestructCmd replaces the command with text and deletes it after the
+// given time, plain seconds or a duration like 5m.
+func SelfDestructCmd(m *telegram.NewMessage) error {
+	arg, text, _ := strings.Cut(m.Args(), " ")
+	text = strings.TrimSpace(text)
+
+	delay, err := parseDurationString(arg)
+	if seconds, convErr := strconv.Atoi(arg); convErr == nil {
+		delay, err = time.Duration(seconds)*time.Second, nil
+	}
+	if err != nil || delay <= 0 || delay > selfDestructMax || text == "" {
+		_, err := eOR(m, tr(m, "admin.usage_sd"))
+		return err
+	}
+
+	msg, err := eOR(m, text)
+	if err != nil {
+		return err
+	}
+	scheduleSelfDestruct(msg.ChatID(), msg.ID, time.Now().Add(delay))
+	return nil
+}
+
+// resumeSelfDestructs re-arms the timers of messages still pending from
+// before a restart; overdue ones are deleted right away.
+func resumeSelfDestructs() {
+	for _, sd := range getSelfDestructs() {
+		armSelfDestruct(sd)
+	}
+}
@@ -4,7 +4,7 @@ import (
 	"NovaUserbot/db"
 	"NovaUserbot/locales"
 	"NovaUserbot/logger"
-	"NovaUserbot/utils"
+	"context"
 	"encoding/json"
 	"fmt"
 	"regexp"
@@ -166,40 +166,40 @@ func deleteReminderByID(reminderID uint) error {
 func remindCommand(m *telegram.NewMessage) error {
 	args := strings.Fields(m.Args())
 	if len(args) < 2 {
-		_, err := eOR(m, locales.Tr("reminders.usage"))
+		_, err := eOR(m, tr(m, "reminders.usage"))
 		return err
 	}
 
 	duration, err := parseDurationString(args[0])
 	if err != nil {
-		_, err := eOR(m, locales.Tr("reminders.invalid_duration"))
+		_, err := eOR(m, tr(m, "reminders.invalid_duration"))
 		return err
 	}
 
 	if duration > 30*24*time.Hour {
-		_, err := eOR(m, locales.Tr("reminders.too_long"))
+		_, err := eOR(m, tr(m, "reminders.too_long"))
 		return err
 	}
 
 	if duration < 1*time.Minute {
-		_, err := eOR(m, locales.Tr("reminders.too_short"))
+		_, err := eOR(m, tr(m, "reminders.too_short"))
 		return err
 	}
 
 	reminders, err := getReminders()
 	if err != nil {
-		_, err := eOR(m, locales.Tr("reminders.error"))
+		_, err := eOR(m, tr(m, "reminders.error"))
 		return err
 	}
 
 	if len(reminders) >= maxReminders {
-		_, err := eOR(m, locales.Tr("reminders.limit_reached"))
+		_, err := eOR(m, tr(m, "reminders.limit_reached"))
 		return err
 	}
 
 	reminderText := strings.Join(args[1:], " ")
 	if len(reminderText) > 500 {
-		_, err := eOR(m, locales.Tr("reminders.text_too_long"))
+		_, err := eOR(m, tr(m, "reminders.text_too_long"))
 		return err
 	}
 
@@ -207,27 +207,27 @@ func remindCommand(m *telegram.NewMessage) error {
 	messageLink := msgLink(m)
 
 	if err := createReminder(messageLink, reminderText, remindAt); err != nil {
-		_, err := eOR(m, locales.Tr("reminders.error"))
+		_, err := eOR(m, tr(m, "reminders.error"))
 		return err
 	}
 
-	_, err = eOR(m, fmt.Sprintf(locales.Tr("reminders.created"), formatDurationHuman(duration), reminderText))
+	_, err = eOR(m, fmt.Sprintf(tr(m, "reminders.created"), formatDurationHuman(duration), reminderText))
 	return err
 }
 
 func remindersListCommand(m *telegram.NewMessage) error {
 	reminders, err := getReminders()
 	if err != nil {
-		_, err := eOR(m, locales.Tr("reminders.error"))
+		_, err := eOR(m, tr(m, "reminders.error"))
 		return err
 	}
 
 	if len(reminders) == 0 {
-		_, err := eOR(m, locales.Tr("reminders.none"))
+		_, err := eOR(m, tr(m, "reminders.none"))
 		return err
 	}
 
-	text := locales.Tr("reminders.list_header") + "\n\n"
+	text := tr(m, "reminders.list_header") + "\n\n"
 
 	for i, reminder := range reminders {
 		timeUntil := time.Until(reminder.RemindAt)
@@ -255,44 +255,44 @@ func remindersListCommand(m *telegram.NewMessage) error {
 func delReminderCommand(m *telegram.NewMessage) error {
 	args := strings.TrimSpace(m.Args())
 	if args == "" {
-		_, err := eOR(m, locales.Tr("reminders.usage_delreminder"))
+		_, err := eOR(m, tr(m, "reminders.usage_delreminder"))
 		return err
 	}
 
 	index, err := strconv.Atoi(args)
 	if err != nil || index < 1 {
-		_, err := eOR(m, locales.Tr("reminders.invalid_index"))
+		_, err := eOR(m, tr(m, "reminders.invalid_index"))
 		return err
 	}
 
 	reminders, err := getReminders()
 	if err != nil {
-		_, err := eOR(m, locales.Tr("reminders.error"))
+		_, err := eOR(m, tr(m, "reminders.error"))
 		return err
 	}
 
 	if index > len(reminders) {
-		_, err := eOR(m, locales.Tr("reminders.not_found"))
+		_, err := eOR(m, tr(m, "reminders.not_found"))
 		return err
 	}
 
 	reminderID := reminders[index-1].ID
 	if err := deleteReminderByID(reminderID); err != nil {
-		_, err := eOR(m, locales.Tr("reminders.error"))
+		_, err := eOR(m, tr(m, "reminders.error"))
 		return err
 	}
 
-	_, err = eOR(m, locales.Tr("reminders.deleted"))
+	_, err = eOR(m, tr(m, "reminders.deleted"))
 	return err
 }
 
 func clearRemindersCommand(m *telegram.NewMessage) error {
 	if err := db.Del("REMINDERS"); err != nil {
-		_, err := eOR(m, locales.Tr("reminders.error"))
+		_, err := eOR(m, tr(m, "reminders.error"))
 		return err
 	}
 
-	_, err := eOR(m, locales.Tr("reminders.cleared"))
+	_, err := eOR(m, tr(m, "reminders.cleared"))
 	return err
 }
 
@@ -306,7 +306,8 @@ func StartReminderChecker() {
 	reminderCheckerStop = make(chan bool)
 	reminderMutex.Unlock()
 
-	go func() {
+	stop := reminderCheckerStop
+	startWorker("reminders", func(ctx context.Context) {
 		ticker := time.NewTicker(30 * time.Second)
 		defer ticker.Stop()
 
@@ -314,12 +315,14 @@ func StartReminderChecker() {
 			select {
 			case <-ticker.C:
 				checkAndTriggerReminders()
-			case <-reminderCheckerStop:
+			case <-stop:
 				logger.Info("Reminder checker stopped")
 				return
+			case <-ctx.Done():
+				return
 			}
 		}
-	}()
+	})
 }
 
 func StopReminderChecker() {
@@ -335,10 +338,6 @@ func StopReminderChecker() {
 }
 
 func checkAndTriggerReminders() {
-	if tgbot == nil {
-		return
-	}
-
 	reminders, err := getReminders()
 	if err != nil || len(reminders) == 0 {
 		return
@@ -359,10 +358,6 @@ func checkAndTriggerReminders() {
 }
 
 func sendReminderNotification(reminder Reminder) {
-	if tgbot == nil {
-		return
-	}
-
 	ownerMention := fmt.Sprintf("<a href='tg://user?id=%d'>Owner</a>", ubId)
 
 	var text string
@@ -374,23 +369,7 @@ func sendReminderNotification(reminder Reminder) {
 
 	text = ownerMention + "\n" + text
 
-	logChatStr := db.Get("LOG_CHAT")
-	logChat := utils.StringToInt64(logChatStr)
-	if logChat == 0 {
-		logChat = ubId
-	}
-
-	peer, err := tgbot.GetSendablePeer(logChat)
-	if err != nil {
-		logger.Errorf("Failed to get peer for reminder %d: %v", reminder.ID, err)
-		return
-	}
-
-	_, err = tgbot.SendMessage(peer, text, &telegram.SendOptions{
-		ParseMode: "HTML",
-	})
-
-	if err != nil {
+	if err := logTo(logger.CategorySystem, text, &telegram.SendOptions{ParseMode: "HTML"}); err != nil {
 		logger.Errorf("Failed to send reminder %d: %v", reminder.ID, err)
 	}
 }
@@ -0,0 +1,482 @@
+package modules
+
+import (
+	"NovaUserbot/db"
+	"NovaUserbot/logger"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+const (
+	rotationKey       = "PROFILE_ROTATION"
+	rotationTick      = time.Minute
+	weatherCacheTime  = 30 * time.Minute
+	rotationMinPicGap = 30
+	// Telegram's limits; Premium accounts may use longer bios.
+	maxNameLength       = 64
+	maxBioLength        = 70
+	maxPremiumBioLength = 140
+)
+
+// ProfileRotation holds the names, bios and pictures cycled through by the
+// rotation worker. Pictures are stored by file ID. The last uploaded rotation
+// photo is remembered so it can be removed when the next one is set.
+type ProfileRotation struct {
+	Enabled     bool      `json:"enabled"`
+	Names       []string  `json:"names,omitempty"`
+	Bios        []string  `json:"bios,omitempty"`
+	Pics        []string  `json:"pics,omitempty"`
+	NameIndex   int       `json:"name_index"`
+	BioIndex    int       `json:"bio_index"`
+	PicIndex    int       `json:"pic_index"`
+	LastRotated time.Time `json:"last_rotated"`
+	LastPic     time.Time `json:"last_pic"`
+	PausedUntil time.Time `json:"paused_until"`
+
+	LastPhotoID   int64  `json:"last_photo_id,omitempty"`
+	LastPhotoHash int64  `json:"last_photo_hash,omitempty"`
+	LastPhotoRef  []byte `json:"last_photo_ref,omitempty"`
+}
+
+var (
+	rotationMutex sync.Mutex
+
+	// What the worker last set, so unchanged templates cost no request.
+	appliedName, appliedBio string
+	// Index+1 of an entry Telegram rejected, skipped until the set rotates.
+	rejectedName, rejectedBio int
+
+	weatherMutex   sync.Mutex
+	weatherCity    string
+	weatherValue   [2]string
+	weatherFetched time.Time
+
+	clockEmojis     = []string{"🕐", "🕑", "🕒", "🕓", "🕔", "🕕", "🕖", "🕗", "🕘", "🕙", "🕚", "🕛"}
+	halfClockEmojis = []string{"🕜", "🕝", "🕞", "🕟", "🕠", "🕡", "🕢", "🕣", "🕤", "🕥", "🕦", "🕧"}
+)
+
+func getRotation() ProfileRotation {
+	var r ProfileRotation
+	data := db.Get(rotationKey)
+	if data == "" {
+		return r
+	}
+	if err := json.Unmarshal([]byte(data), &r); err != nil {
+		logger.Errorf("Corrupted %s: %v", rotationKey, err)
+	}
+	return r
+}
+
+func saveRotation(r ProfileRotation) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return db.Set(rotationKey, string(data))
+}
+
+func rotationLocation() *time.Location {
+	name := getVar("ROTATE_TZ")
+	if name == "" {
+		return time.Local
+	}
+	if loc, err := time.LoadLocation(name); err == nil {
+		return loc
+	}
+	return time.Local
+}
+
+func validTimezone(value string) (string, error) {
+	if _, err := time.LoadLocation(value); err != nil {
+		return "", fmt.Errorf("unknown time zone, use a name like Asia/Kolkata")
+	}
+	return value, nil
+}
+
+func clockEmoji(t time.Time) string {
+	i := (t.Hour() + 11) % 12
+	if t.Minute() >= 30 {
+		return halfClockEmojis[i]
+	}
+	return clockEmojis[i]
+}
+
+// currentWeather returns the condition emoji and temperature for ROTATE_CITY
+// from wttr.in, cached for weatherCacheTime.
+func currentWeather() (string, string) {
+	city := getVar("ROTATE_CITY")
+	if city == "" {
+		return "", ""
+	}
+
+	weatherMutex.Lock()
+	defer weatherMutex.Unlock()
+	if city == weatherCity && time.Since(weatherFetched) < weatherCacheTime {
+		return weatherValue[0], weatherValue[1]
+	}
+
+	httpClient := &http.Client{Timeout: 15 * time.Second}
+	resp, err := httpClient.Get("https://wttr.in/" + url.PathEscape(city) + "?format=%c|%t")
+	if err != nil {
+		logger.Warnf("Weather lookup failed: %v", err)
+		return weatherValue[0], weatherValue[1]
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 256))
+	if err != nil || resp.StatusCode != http.StatusOK {
+		logger.Warnf("Weather lookup failed: %s", resp.Status)
+		return weatherValue[0], weatherValue[1]
+	}
+
+	emoji, temp, _ := strings.Cut(strings.TrimSpace(string(body)), "|")
+	weatherCity, weatherFetched = city, time.Now()
+	weatherValue = [2]string{strings.TrimSpace(emoji), strings.TrimPrefix(strings.TrimSpace(temp), "+")}
+	return weatherValue[0], weatherValue[1]
+}
+
+// renderTemplate fills the placeholders in a rotation entry.
+func renderTemplate(text string) string {
+	if !strings.Contains(text, "{") {
+		return text
+	}
+
+	now := time.Now().In(rotationLocation())
+	pairs := []string{
+		"{time}", now.Format("15:04"),
+		"{time12}", now.Format("3:04 PM"),
+		"{date}", now.Format("02 Jan"),
+		"{day}", now.Format("Monday"),
+		"{clock}", clockEmoji(now),
+	}
+	if strings.Contains(text, "{weather}") || strings.Contains(text, "{temp}") {
+		emoji, temp := currentWeather()
+		pairs = append(pairs, "{weather}", emoji, "{temp}", temp)
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+func splitName(name string) (string, string) {
+	first, last, _ := strings.Cut(name, "//")
+	return strings.TrimSpace(first), strings.TrimSpace(last)
+}
+
+// pauseRotation stops the worker until a flood wait is over.
+func pauseRotation(r *ProfileRotation, err error) bool {
+	wait := telegram.GetFloodWait(err)
+	if wait == 0 {
+		return false
+	}
+	r.PausedUntil = time.Now().Add(time.Duration(wait+5) * time.Second)
+	logger.Warnf("Profile rotation paused for %ds by a flood wait", wait)
+	return true
+}
+
+func setRotationPic(r *ProfileRotation) error {
+	media, err := telegram.ResolveBotFileID(r.Pics[r.PicIndex])
+	if err != nil {
+		return err
+	}
+	path, err := client.DownloadMedia(media)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+
+	file, err := client.UploadFile(path)
+	if err != nil {
+		return err
+	}
+	photo, err := client.PhotosUploadProfilePhoto(&telegram.PhotosUploadProfilePhotoParams{File: file})
+	if err != nil {
+		return err
+	}
+
+	if r.LastPhotoID != 0 {
+		client.PhotosDeletePhotos([]telegram.InputPhoto{&telegram.InputPhotoObj{ID: r.LastPhotoID, AccessHash: r.LastPhotoHash, FileReference: r.LastPhotoRef}})
+	}
+	r.LastPhotoID, r.LastPhotoHash, r.LastPhotoRef = 0, 0, nil
+	if p, ok := photo.Photo.(*telegram.PhotoObj); ok {
+		r.LastPhotoID, r.LastPhotoHash, r.LastPhotoRef = p.ID, p.AccessHash, p.FileReference
+	}
+	return nil
+}
+
+func minutesVar(key string) time.Duration {
+	minutes, _ := strconv.Atoi(getVar(key))
+	return time.Duration(max(minutes, 1)) * time.Minute
+}
+
+// rotateProfile advances the sets that are due and applies the rendered name
+// and bio when they differ from what was last set.
+func rotateProfile() {
+	rotationMutex.Lock()
+	defer rotationMutex.Unlock()
+
+	r := getRotation()
+	if !r.Enabled || time.Now().Before(r.PausedUntil) {
+		return
+	}
+
+	if time.Since(r.LastRotated) >= minutesVar("ROTATE_INTERVAL") {
+		if len(r.Names) > 0 {
+			r.NameIndex = (r.NameIndex + 1) % len(r.Names)
+		}
+		if len(r.Bios) > 0 {
+			r.BioIndex = (r.BioIndex + 1) % len(r.Bios)
+		}
+		r.LastRotated = time.Now()
+		rejectedName, rejectedBio = 0, 0
+	}
+
+	if len(r.Names) > 0 && rejectedName != r.NameIndex%len(r.Names)+1 {
+		name := renderTemplate(r.Names[r.NameIndex%len(r.Names)])
+		if name != appliedName {
+			first, last := splitName(name)
+			if _, err := client.AccountUpdateProfile(first, last, ""); err != nil {
+				if !pauseRotation(&r, err) {
+					rejectedName = r.NameIndex%len(r.Names) + 1
+					logger.Warnf("Profile rotation: name #%d was rejected, skipping it: %v", rejectedName, err)
+				}
+			} else {
+				appliedName, rejectedName = name, 0
+			}
+		}
+	}
+
+	if len(r.Bios) > 0 && rejectedBio != r.BioIndex%len(r.Bios)+1 && time.Now().After(r.PausedUntil) {
+		bio := renderTemplate(r.Bios[r.BioIndex%len(r.Bios)])
+		if bio != appliedBio {
+			if _, err := client.AccountUpdateProfile("", "", bio); err != nil {
+				if !pauseRotation(&r, err) {
+					rejectedBio = r.BioIndex%len(r.Bios) + 1
+					logger.Warnf("Profile rotation: bio #%d was rejected, skipping it: %v", rejectedBio, err)
+				}
+			} else {
+				appliedBio, rejectedBio = bio, 0
+			}
+		}
+	}
+
+	if len(r.Pics) > 0 && time.Now().After(r.PausedUntil) && time.Since(r.LastPic) >= minutesVar("ROTATE_PIC_INTERVAL") {
+		r.PicIndex = (r.PicIndex + 1) % len(r.Pics)
+		if err := setRotationPic(&r); pauseRotation(&r, err) {
+			r.PicIndex--
+		} else {
+			if err != nil {
+				logger.Errorf("Profile rotation: picture update failed: %v", err)
+			}
+			r.LastPic = time.Now()
+		}
+	}
+
+	if err := saveRotation(r); err != nil {
+		logger.Errorf("Could not save profile rotation: %v", err)
+	}
+}
+
+func runProfileRotation(ctx context.Context) {
+	ticker := time.NewTicker(rotationTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			rotateProfile()
+		}
+	}
+}
+
+func rotationList(m *telegram.NewMessage, r ProfileRotation) string {
+	status := tr(m, "rotation.status_off")
+	if r.Enabled {
+		status = tr(m, "rotation.status_on")
+	}
+	text := fmt.Sprintf(tr(m, "rotation.list_header"), status, int(minutesVar("ROTATE_INTERVAL").Minutes()), int(minutesVar("ROTATE_PIC_INTERVAL").Minutes()))
+	if time.Now().Before(r.PausedUntil) {
+		text += fmt.Sprintf(tr(m, "rotation.paused"), formatDurationHuman(time.Until(r.PausedUntil)))
+	}
+
+	sets := []struct {
+		key     string
+		entries []string
+		current int
+	}{
+		{"rotation.names", r.Names, r.NameIndex},
+		{"rotation.bios", r.Bios, r.BioIndex},
+	}
+	for _, set := range sets {
+		if len(set.entries) == 0 {
+			continue
+		}
+		text += "\n" + tr(m, set.key) + "\n"
+		for i, e := range set.entries {
+			marker := ""
+			if i == set.current%len(set.entries) {
+				marker = " ◀"
+			}
+			text += fmt.Sprintf(tr(m, "rotation.entry"), i+1, html.EscapeString(e), html.EscapeString(renderTemplate(e)), marker)
+		}
+	}
+	if len(r.Pics) > 0 {
+		text += "\n" + fmt.Sprintf(tr(m, "rotation.pics"), len(r.Pics))
+	}
+	return text
+}
+
+func rotationSet(r *ProfileRotation, kind string) *[]string {
+	switch kind {
+	case "name", "names":
+		return &r.Names
+	case "bio", "bios":
+		return &r.Bios
+	case "pic", "pics":
+		return &r.Pics
+	}
+	return nil
+}
+
+// rotationLimit returns the length limit a rendered name or bio exceeds, or
+// 0 when it fits. First and last names are limited separately.
+func rotationLimit(kind, value string) int {
+	rendered := renderTemplate(value)
+	if kind == "name" {
+		first, last := splitName(rendered)
+		if max(utf8.RuneCountInString(first), utf8.RuneCountInString(last)) > maxNameLength {
+			return maxNameLength
+		}
+		return 0
+	}
+
+	limit := maxBioLength
+	if client.Me() != nil && client.Me().Premium {
+		limit = maxPremiumBioLength
+	}
+	if utf8.RuneCountInString(rendered) > limit {
+		return limit
+	}
+	return 0
+}
+
+// RotateCmd manages the rotation sets: .rotate name|bio <text>, .rotate pic
+// (reply), .rotate del <kind> <n>, .rotate clear <kind|all>, .rotate on|off.
+func RotateCmd(m *telegram.NewMessage) error {
+	kind, value, _ := strings.Cut(strings.TrimSpace(m.Args()), " ")
+	kind = strings.ToLower(kind)
+	value = strings.TrimSpace(value)
+
+	rotationMutex.Lock()
+	defer rotationMutex.Unlock()
+	r := getRotation()
+
+	var reply string
+	switch kind {
+	case "", "list":
+		_, err := eOR(m, rotationList(m, r))
+		return err
+
+	case "on", "off":
+		r.Enabled = kind == "on"
+		if r.Enabled && len(r.Names)+len(r.Bios)+len(r.Pics) == 0 {
+			_, err := eOR(m, tr(m, "rotation.empty"))
+			return err
+		}
+		appliedName, appliedBio = "", ""
+		rejectedName, rejectedBio = 0, 0
+		reply = fmt.Sprintf(tr(m, "rotation.toggled"), kind)
+
+	case "name", "bio":
+		if value == "" {
+			_, err := eOR(m, tr(m, "rotation.usage"))
+			return err
+		}
+		if limit := rotationLimit(kind, value); limit > 0 {
+			_, err := eOR(m, fmt.Sprintf(tr(m, "rotation.too_long"), kind, limit))
+			return err
+		}
+		set := rotationSet(&r, kind)
+		*set = append(*set, value)
+		reply = fmt.Sprintf(tr(m, "rotation.added"), kind, len(*set), html.EscapeString(renderTemplate(value)))
+
+	case "pic":
+		msg, err := m.GetReplyMessage()
+		if err != nil || msg.Photo() == nil || msg.File == nil {
+			_, err := eOR(m, tr(m, "rotation.pic_reply"))
+			return err
+		}
+		r.Pics = append(r.Pics, msg.File.FileID)
+		reply = fmt.Sprintf(tr(m, "rotation.pic_added"), len(r.Pics))
+
+	case "del":
+		fields := strings.Fields(value)
+		if len(fields) != 2 {
+			_, err := eOR(m, tr(m, "rotation.usage"))
+			return err
+		}
+		set := rotationSet(&r, strings.ToLower(fields[0]))
+		n, err := strconv.Atoi(fields[1])
+		if set == nil || err != nil || n < 1 || n > len(*set) {
+			_, err := eOR(m, tr(m, "rotation.invalid_index"))
+			return err
+		}
+		*set = append((*set)[:n-1], (*set)[n:]...)
+		rejectedName, rejectedBio = 0, 0
+		reply = fmt.Sprintf(tr(m, "rotation.deleted"), fields[0], n)
+
+	case "clear":
+		if strings.ToLower(value) == "all" {
+			r.Names, r.Bios, r.Pics = nil, nil, nil
+		} else if set := rotationSet(&r, strings.ToLower(value)); set != nil {
+			*set = nil
+		} else {
+			_, err := eOR(m, tr(m, "rotation.usage"))
+			return err
+		}
+		if len(r.Names)+len(r.Bios)+len(r.Pics) == 0 {
+			r.Enabled = false
+		}
+		reply = fmt.Sprintf(tr(m, "rotation.cleared"), value)
+
+	default:
+		_, err := eOR(m, tr(m, "rotation.usage"))
+		return err
+	}
+
+	if err := saveRotation(r); err != nil {
+		_, err := eOR(m, fmt.Sprintf(tr(m, "profile.update_error"), html.EscapeString(err.Error())))
+		return err
+	}
+	_, err := eOR(m, reply)
+	return err
+}
+
+func LoadRotationModule(c *telegram.Client) {
+	RegisterVars([]*ConfigVar{
+		{Key: "ROTATE_INTERVAL", Module: "Profile", Type: VarInt, Default: "60", Description: "Minutes before the next name and bio in the rotation are used", Validate: intRange(1, 7*24*60)},
+		{Key: "ROTATE_PIC_INTERVAL", Module: "Profile", Type: VarInt, Default: "360", Description: "Minutes between profile picture changes in the rotation", Validate: intRange(rotationMinPicGap, 7*24*60)},
+		{Key: "ROTATE_TZ", Module: "Profile", Description: "Time zone for {time}, {date}, {day} and {clock} in rotation templates", Validate: validTimezone},
+		{Key: "ROTATE_CITY", Module: "Profile", Description: "City for {weather} and {temp} in rotation templates"},
+	})
+
+	handlers := []*Handler{
+		{Command: "rotate", Func: RotateCmd, Description: "Rotate names, bios and pictures on a schedule (name|bio <text>, pic, del, clear, on|off, list)", ModuleName: "Profile", DisAllowSudos: true},
+	}
+	AddHandlers(handlers, c)
+
+	startWorker("profile rotation", runProfileRotation)
+}
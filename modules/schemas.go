@@ -0,0 +1,49 @@
+package modules
+
+import (
+	"NovaUserbot/db"
+	"NovaUserbot/logger"
+)
+
+// storedSchemas lists every JSON value the modules persist, so corrupted data
+// is reported at startup rather than read back as zero values.
+var storedSchemas = []db.Schema{
+	{Pattern: "AFK_DATA", New: func() any { return &AFKData{} }},
+	{Pattern: "AUTO_AFK", New: func() any { return &AutoAFKConfig{} }},
+	{Pattern: "REMINDERS", New: func() any { return &[]Reminder{} }},
+	{Pattern: selfDestructKey, New: func() any { return &[]SelfDestruct{} }},
+	{Pattern: "GBAN:*", New: func() any { return &BanInfo{} }},
+	{Pattern: "BANGUARD:*", New: func() any { return &BanGuardConfig{} }},
+	{Pattern: banGuardLogPrefix + "*", New: func() any { return &[]GuardedAction{} }},
+	{Pattern: antiFloodPrefix + "*", New: func() any { return &AntiFloodConfig{} }},
+	{Pattern: antiRaidPrefix + "*", New: func() any { return &AntiRaidConfig{} }},
+	{Pattern: captchaPrefix + "*", New: func() any { return &map[int64]Captcha{} }},
+	{Pattern: warnsPrefix + "*", New: func() any { return &map[int64][]Warn{} }},
+	{Pattern: warnSettingsPrefix + "*", New: func() any { return &WarnSettings{} }},
+	{Pattern: "GDRIVE_CONFIG", New: func() any { return &GDriveConfig{} }},
+	{Pattern: "LOG_ROUTES", New: func() any { return &map[logger.Category]LogRoute{} }},
+	{Pattern: "MSG_LOGGER", New: func() any { return &MsgLoggerConfig{} }},
+	{Pattern: scheduledStoriesKey, New: func() any { return &[]ScheduledStory{} }},
+	{Pattern: storyWatchPrefix + "*", New: func() any { return &StoryWatch{} }},
+	{Pattern: rotationKey, New: func() any { return &ProfileRotation{} }},
+	{Pattern: profileSnapPrefix + "*", New: func() any { return &ProfileSnapshot{} }},
+	{Pattern: profileHistPrefix + "*", New: func() any { return &[]ProfileChange{} }},
+	{Pattern: statsHistoryKey, New: func() any { return &[]accountStats{} }},
+	{Pattern: cloneJobsKey, New: func() any { return &[]CloneJob{} }},
+}
+
+// prepareDatabase brings the stored data up to the current schema and checks
+// it decodes. Either failing stops startup.
+func prepareDatabase() {
+	from := db.SchemaVersion()
+	if err := db.Migrate(); err != nil {
+		logger.Fatalf("Database migration error: %v", err)
+	}
+	if to := db.SchemaVersion(); to != from {
+		logger.Infof("Database schema migrated from version %d to %d", from, to)
+	}
+
+	if err := db.Verify(storedSchemas); err != nil {
+		logger.Fatalf("Refusing to start: %v (prefix %q). Fix or delete these keys in Redis and start again.", err, db.Prefix())
+	}
+}
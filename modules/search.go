@@ -1,7 +1,6 @@
 package modules
 
 import (
-	"NovaUserbot/locales"
 	"encoding/json"
 	"fmt"
 	"html"
@@ -50,40 +49,40 @@ type GoogleSearchResult struct {
 func gitHubSearch(m *telegram.NewMessage) error {
 	username := strings.TrimSpace(m.Args())
 	if username == "" {
-		_, err := eOR(m, locales.Tr("search.github_usage"))
+		_, err := eOR(m, tr(m, "search.github_usage"))
 		return err
 	}
 
-	msg, _ := eOR(m, locales.Tr("search.fetching"))
+	msg, _ := eOR(m, tr(m, "search.fetching"))
 
 	apiURL := fmt.Sprintf("https://api.github.com/users/%s", url.PathEscape(username))
 	resp, err := http.Get(apiURL)
 	if err != nil {
-		_, err := msg.Edit(locales.Tr("search.github_error"))
+		_, err := msg.Edit(tr(m, "search.github_error"))
 		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		_, err := msg.Edit(locales.Tr("search.github_not_found"))
+		_, err := msg.Edit(tr(m, "search.github_not_found"))
 		return err
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		_, err := msg.Edit(locales.Tr("search.github_error"))
+		_, err := msg.Edit(tr(m, "search.github_error"))
 		return err
 	}
 
 	var user GitHubUser
 	if err := json.Unmarshal(body, &user); err != nil {
-		_, err := msg.Edit(locales.Tr("search.github_error"))
+		_, err := msg.Edit(tr(m, "search.github_error"))
 		return err
 	}
 
 	profilePic := fmt.Sprintf("https://avatars.githubusercontent.com/u/%d", user.ID)
 
-	result := fmt.Sprintf(locales.Tr("search.github_result"),
+	result := fmt.Sprintf(tr(m, "search.github_result"),
 		user.HTMLURL,
 		user.Name,
 		user.Login,
@@ -107,15 +106,15 @@ func gitHubSearch(m *telegram.NewMessage) error {
 func googleSearch(m *telegram.NewMessage) error {
 	query := strings.TrimSpace(m.Args())
 	if query == "" {
-		_, err := eOR(m, locales.Tr("search.google_usage"))
+		_, err := eOR(m, tr(m, "search.google_usage"))
 		return err
 	}
 
-	msg, _ := eOR(m, locales.Tr("search.searching"))
+	msg, _ := eOR(m, tr(m, "search.searching"))
 
 	results, err := performDuckDuckGoSearch(query)
 	if err != nil || len(results) == 0 {
-		_, err := msg.Edit(fmt.Sprintf(locales.Tr("search.no_results"), query))
+		_, err := msg.Edit(fmt.Sprintf(tr(m, "search.no_results"), query))
 		return err
 	}
 
@@ -124,7 +123,7 @@ func googleSearch(m *telegram.NewMessage) error {
 		output.WriteString(fmt.Sprintf(" 👉🏻  <a href='%s'>%s</a>\n<code>%s</code>\n\n", res.Link, res.Title, res.Description))
 	}
 
-	response := fmt.Sprintf(locales.Tr("search.google_result"), query, output.String())
+	response := fmt.Sprintf(tr(m, "search.google_result"), query, output.String())
 	_, err = msg.Edit(response, &telegram.SendOptions{ParseMode: "HTML", LinkPreview: false})
 	return err
 }
@@ -190,7 +189,7 @@ func performDuckDuckGoSearch(query string) ([]GoogleSearchResult, error) {
 func imageSearch(m *telegram.NewMessage) error {
 	args := strings.TrimSpace(m.Args())
 	if args == "" {
-		_, err := eOR(m, locales.Tr("search.img_usage"))
+		_, err := eOR(m, tr(m, "search.img_usage"))
 		return err
 	}
 
@@ -210,18 +209,18 @@ func imageSearch(m *telegram.NewMessage) error {
 		}
 	}
 
-	msg, _ := eOR(m, locales.Tr("search.searching_images"))
+	msg, _ := eOR(m, tr(m, "search.searching_images"))
 
 	images := fetchUnsplashPhotoURLs(query, limit)
 	if len(images) == 0 {
-		_, err := msg.Edit(fmt.Sprintf(locales.Tr("search.no_images"), query))
+		_, err := msg.Edit(fmt.Sprintf(tr(m, "search.no_images"), query))
 		return err
 	}
 
 	msg.Delete()
 
 	for _, img := range images {
-		_, err := m.Respond(fmt.Sprintf(locales.Tr("search.image_caption"), query), &telegram.SendOptions{
+		_, err := m.Respond(fmt.Sprintf(tr(m, "search.image_caption"), query), &telegram.SendOptions{
 			Media: img,
 		})
 		if err != nil {
@@ -235,43 +234,43 @@ func imageSearch(m *telegram.NewMessage) error {
 func ipInfoSearch(m *telegram.NewMessage) error {
 	ipAddr := strings.TrimSpace(m.Args())
 	if ipAddr == "" {
-		_, err := eOR(m, locales.Tr("search.ipinfo_usage"))
+		_, err := eOR(m, tr(m, "search.ipinfo_usage"))
 		return err
 	}
 
 	if net.ParseIP(ipAddr) == nil {
-		_, err := eOR(m, locales.Tr("search.ipinfo_not_found"))
+		_, err := eOR(m, tr(m, "search.ipinfo_not_found"))
 		return err
 	}
 
-	msg, _ := eOR(m, locales.Tr("search.fetching"))
+	msg, _ := eOR(m, tr(m, "search.fetching"))
 
 	apiURL := fmt.Sprintf("https://ipinfo.io/%s/json", url.PathEscape(ipAddr))
 	resp, err := http.Get(apiURL)
 	if err != nil {
-		_, err := msg.Edit(locales.Tr("search.ipinfo_error"))
+		_, err := msg.Edit(tr(m, "search.ipinfo_error"))
 		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		_, err := msg.Edit(locales.Tr("search.ipinfo_not_found"))
+		_, err := msg.Edit(tr(m, "search.ipinfo_not_found"))
 		return err
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		_, err := msg.Edit(locales.Tr("search.ipinfo_error"))
+		_, err := msg.Edit(tr(m, "search.ipinfo_error"))
 		return err
 	}
 
 	var info IPInfo
 	if err := json.Unmarshal(body, &info); err != nil {
-		_, err := msg.Edit(locales.Tr("search.ipinfo_error"))
+		_, err := msg.Edit(tr(m, "search.ipinfo_error"))
 		return err
 	}
 
-	result := fmt.Sprintf(locales.Tr("search.ipinfo_result"),
+	result := fmt.Sprintf(tr(m, "search.ipinfo_result"),
 		html.EscapeString(info.IP),
 		html.EscapeString(info.Hostname),
 		html.EscapeString(info.City),
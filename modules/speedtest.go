@@ -1,7 +1,6 @@
 package modules
 
 import (
-	"NovaUserbot/locales"
 	"NovaUserbot/utils"
 	"fmt"
 	"strconv"
@@ -41,15 +40,15 @@ func runSpeedTest() (float64, float64, int, string, error) {
 }
 
 func speedTestHandler(m *telegram.NewMessage) error {
-	msg, _ := eOR(m, locales.Tr("speedtest.running"))
+	msg, _ := eOR(m, tr(m, "speedtest.running"))
 
 	download, upload, ping, imageURL, err := runSpeedTest()
 	if err != nil {
-		_, err := msg.Edit(locales.Tr("speedtest.failed"))
+		_, err := msg.Edit(tr(m, "speedtest.failed"))
 		return err
 	}
 
-	resultText := fmt.Sprintf(locales.Tr("speedtest.result"), download, upload, ping)
+	resultText := fmt.Sprintf(tr(m, "speedtest.result"), download, upload, ping)
 
 	args := strings.ToLower(strings.TrimSpace(m.Args()))
 	if args == "image" && imageURL != "" {
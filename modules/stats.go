@@ -0,0 +1,431 @@
+package modules
+
+import (
+	"NovaUserbot/db"
+	"NovaUserbot/logger"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+const (
+	statsHistoryKey     = "STATS_HISTORY"
+	statsRetention      = 180 * 24 * time.Hour
+	statsCheckEvery     = time.Hour
+	statsDefaultHistory = 30 * 24 * time.Hour
+)
+
+// accountStats is one snapshot of the counters .stats reports. The group and
+// channel IDs are kept so joins and leaves can be told apart from the counts.
+type accountStats struct {
+	Time          time.Time `json:"time"`
+	Users         int       `json:"users"`
+	Bots          int       `json:"bots"`
+	Groups        int       `json:"groups"`
+	Channels      int       `json:"channels"`
+	Contacts      int       `json:"contacts"`
+	Mutuals       int       `json:"mutuals"`
+	Blocked       int       `json:"blocked"`
+	Pinned        int       `json:"pinned"`
+	Unread        int       `json:"unread"`
+	Notify        int       `json:"notify"`
+	Mentions      int       `json:"mentions"`
+	Reactions     int       `json:"reactions"`
+	Creator       int       `json:"creator"`
+	AdminGroups   int       `json:"admin_groups"`
+	AdminChannels int       `json:"admin_channels"`
+	Deleted       int       `json:"deleted"`
+	Total         int       `json:"total"`
+	GroupIDs      []int64   `json:"group_ids,omitempty"`
+	ChannelIDs    []int64   `json:"channel_ids,omitempty"`
+}
+
+// collectStats walks every dialog and counts them. It stops early when ctx is
+// cancelled.
+func collectStats(ctx context.Context) (accountStats, error) {
+	s := accountStats{Time: time.Now().UTC()}
+
+	err := client.IterDialogs(func(d *telegram.TLDialog) error {
+		dialog, ok := d.Dialog.(*telegram.DialogObj)
+		if !ok {
+			return nil
+		}
+		if dialog.NotifySettings != nil && !dialog.NotifySettings.Silent {
+			s.Notify++
+		}
+		s.Unread += int(dialog.UnreadCount)
+		s.Mentions += int(dialog.UnreadMentionsCount)
+		s.Reactions += int(dialog.UnreadReactionsCount)
+
+		if dialog.Pinned {
+			s.Pinned++
+		}
+		switch p := dialog.Peer.(type) {
+		case *telegram.PeerChannel:
+			s.Total++
+			ch, err := client.GetChannel(p.ChannelID)
+			if err != nil {
+				return nil
+			}
+
+			if ch.Creator {
+				s.Creator++
+			}
+			id := -1000000000000 - p.ChannelID
+			if ch.Broadcast {
+				if ch.AdminRights != nil {
+					s.AdminChannels++
+				}
+				s.Channels++
+				s.ChannelIDs = append(s.ChannelIDs, id)
+			} else {
+				if ch.AdminRights != nil {
+					s.AdminGroups++
+				}
+				s.Groups++
+				s.GroupIDs = append(s.GroupIDs, id)
+			}
+		case *telegram.PeerUser:
+			s.Total++
+			user, err := client.GetUser(p.UserID)
+			if err != nil {
+				return nil
+			}
+
+			if user.Deleted {
+				s.Deleted++
+			}
+			if user.Bot {
+				s.Bots++
+			} else {
+				s.Users++
+			}
+
+			if user.MutualContact {
+				s.Mutuals++
+			}
+			if user.Contact {
+				s.Contacts++
+			}
+		case *telegram.PeerChat:
+			s.Total++
+			chat, err := client.GetChat(p.ChatID)
+			if err != nil {
+				return nil
+			}
+			if chat.AdminRights != nil {
+				s.AdminGroups++
+			}
+			s.Groups++
+			s.GroupIDs = append(s.GroupIDs, -p.ChatID)
+		}
+
+		return nil
+	}, &telegram.DialogOptions{SleepThresholdMs: 10, Limit: 5000, Context: ctx})
+	if err != nil {
+		return s, err
+	}
+
+	blocked, _ := client.ContactsGetBlocked(false, 0, 5000)
+	if b, ok := blocked.(*telegram.ContactsBlockedObj); ok {
+		s.Blocked = len(b.Users)
+	}
+	return s, nil
+}
+
+func getStatsHistory() []accountStats {
+	data := db.Get(statsHistoryKey)
+	if data == "" {
+		return nil
+	}
+
+	var history []accountStats
+	if err := json.Unmarshal([]byte(data), &history); err != nil {
+		logger.Errorf("Corrupted %s: %v", statsHistoryKey, err)
+		return nil
+	}
+	return history
+}
+
+// saveStatsSnapshot records s, keeping at most one snapshot per day and
+// dropping those older than statsRetention.
+func saveStatsSnapshot(s accountStats) error {
+	history := getStatsHistory()
+	if n := len(history); n > 0 && history[n-1].Time.Format(time.DateOnly) == s.Time.Format(time.DateOnly) {
+		history = history[:n-1]
+	}
+	history = append(history, s)
+
+	cutoff := time.Now().Add(-statsRetention)
+	history = slices.DeleteFunc(history, func(h accountStats) bool { return h.Time.Before(cutoff) })
+
+	data, err := json.Marshal(history)
+	if err != nil {
+		return err
+	}
+	return db.Set(statsHistoryKey, string(data))
+}
+
+// validStatsInterval allows 0 or 24 to 168 hours, as only one snapshot is
+// stored per day.
+func validStatsInterval(value string) (string, error) {
+	if value == "0" {
+		return value, nil
+	}
+	return intRange(24, 24*7)(value)
+}
+
+func getStatsInterval() time.Duration {
+	hours, err := strconv.Atoi(getVar("STATS_INTERVAL"))
+	if err != nil || hours <= 0 {
+		return 0
+	}
+	return time.Duration(max(hours, 24)) * time.Hour
+}
+
+func runStatsSnapshots(ctx context.Context) {
+	ticker := time.NewTicker(statsCheckEvery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		interval := getStatsInterval()
+		if interval == 0 {
+			continue
+		}
+		if history := getStatsHistory(); len(history) > 0 && time.Since(history[len(history)-1].Time) < interval {
+			continue
+		}
+
+		s, err := collectStats(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.Errorf("Stats snapshot failed: %v", err)
+			}
+			continue
+		}
+		if err := saveStatsSnapshot(s); err != nil {
+			logger.Errorf("Could not save stats snapshot: %v", err)
+		}
+	}
+}
+
+// statsSeries is one counter drawn in the history chart. The colors match the
+// square emojis used as the legend in the caption.
+type statsSeries struct {
+	color color.RGBA
+	value func(accountStats) int
+}
+
+var statsChartSeries = []statsSeries{
+	{color.RGBA{0x3b, 0x82, 0xf6, 0xff}, func(s accountStats) int { return s.Groups }},
+	{color.RGBA{0x22, 0xc5, 0x5e, 0xff}, func(s accountStats) int { return s.Channels }},
+	{color.RGBA{0xea, 0xb3, 0x08, 0xff}, func(s accountStats) int { return s.Contacts }},
+	{color.RGBA{0xef, 0x44, 0x44, 0xff}, func(s accountStats) int { return s.Unread }},
+	{color.RGBA{0xa8, 0x55, 0xf7, 0xff}, func(s accountStats) int { return s.Mentions }},
+	{color.RGBA{0xf9, 0x73, 0x16, 0xff}, func(s accountStats) int { return s.AdminGroups + s.AdminChannels }},
+}
+
+const (
+	chartPanelW  = 480
+	chartPanelH  = 240
+	chartPadding = 20
+	chartColumns = 2
+)
+
+// renderStatsChart draws one panel per series, each scaled to its own range,
+// in a grid. Values are in the caption, so the image has no text.
+func renderStatsChart(history []accountStats) image.Image {
+	rows := (len(statsChartSeries) + chartColumns - 1) / chartColumns
+	img := image.NewRGBA(image.Rect(0, 0, chartColumns*(chartPanelW+chartPadding)+chartPadding, rows*(chartPanelH+chartPadding)+chartPadding))
+	draw.Draw(img, img.Bounds(), &image.Uniform{color.RGBA{0xf8, 0xfa, 0xfc, 0xff}}, image.Point{}, draw.Src)
+
+	for i, series := range statsChartSeries {
+		x := chartPadding + (i%chartColumns)*(chartPanelW+chartPadding)
+		y := chartPadding + (i/chartColumns)*(chartPanelH+chartPadding)
+		drawStatsPanel(img, image.Rect(x, y, x+chartPanelW, y+chartPanelH), history, series)
+	}
+	return img
+}
+
+func drawStatsPanel(img *image.RGBA, r image.Rectangle, history []accountStats, series statsSeries) {
+	draw.Draw(img, r, &image.Uniform{color.White}, image.Point{}, draw.Src)
+	grid := color.RGBA{0xe2, 0xe8, 0xf0, 0xff}
+	for i := 0; i <= 4; i++ {
+		y := r.Min.Y + 16 + i*(r.Dy()-32)/4
+		draw.Draw(img, image.Rect(r.Min.X+8, y, r.Max.X-8, y+1), &image.Uniform{grid}, image.Point{}, draw.Src)
+	}
+	draw.Draw(img, image.Rect(r.Min.X, r.Min.Y, r.Max.X, r.Min.Y+6), &image.Uniform{series.color}, image.Point{}, draw.Src)
+
+	lo, hi := series.value(history[0]), series.value(history[0])
+	for _, h := range history {
+		lo, hi = min(lo, series.value(h)), max(hi, series.value(h))
+	}
+	pad := max((hi-lo)/10, 1)
+	lo, hi = lo-pad, hi+pad
+
+	start, span := history[0].Time, history[len(history)-1].Time.Sub(history[0].Time)
+	plot := image.Rect(r.Min.X+16, r.Min.Y+16, r.Max.X-16, r.Max.Y-16)
+	point := func(h accountStats) image.Point {
+		fx := 0.5
+		if span > 0 {
+			fx = float64(h.Time.Sub(start)) / float64(span)
+		}
+		fy := float64(series.value(h)-lo) / float64(hi-lo)
+		return image.Pt(plot.Min.X+int(fx*float64(plot.Dx())), plot.Max.Y-int(fy*float64(plot.Dy())))
+	}
+
+	fill := color.NRGBA{series.color.R, series.color.G, series.color.B, 0x30}
+	for i := 1; i < len(history); i++ {
+		a, b := point(history[i-1]), point(history[i])
+		for x := a.X; x < b.X; x++ {
+			y := a.Y
+			if b.X != a.X {
+				y = a.Y + (b.Y-a.Y)*(x-a.X)/(b.X-a.X)
+			}
+			draw.Draw(img, image.Rect(x, y, x+1, plot.Max.Y), &image.Uniform{fill}, image.Point{}, draw.Over)
+		}
+		drawLine(img, a, b, series.color)
+	}
+	for _, h := range history {
+		p := point(h)
+		draw.Draw(img, image.Rect(p.X-3, p.Y-3, p.X+4, p.Y+4), &image.Uniform{series.color}, image.Point{}, draw.Src)
+	}
+}
+
+// drawLine draws a 3px wide line from a to b.
+func drawLine(img *image.RGBA, a, b image.Point, c color.RGBA) {
+	steps := max(abs(b.X-a.X), abs(b.Y-a.Y), 1)
+	for i := 0; i <= steps; i++ {
+		x := a.X + (b.X-a.X)*i/steps
+		y := a.Y + (b.Y-a.Y)*i/steps
+		draw.Draw(img, image.Rect(x-1, y-1, x+2, y+2), &image.Uniform{c}, image.Point{}, draw.Src)
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func formatDelta(n int) string {
+	if n > 0 {
+		return "+" + strconv.Itoa(n)
+	}
+	return strconv.Itoa(n)
+}
+
+// chatChanges counts the IDs in now that are not in before, and the reverse.
+func chatChanges(before, now []int64) (joined, left int) {
+	for _, id := range now {
+		if !slices.Contains(before, id) {
+			joined++
+		}
+	}
+	for _, id := range before {
+		if !slices.Contains(now, id) {
+			left++
+		}
+	}
+	return joined, left
+}
+
+// statsHistory sends a chart of the snapshots within the requested range,
+// with the current values, their change over the range and the groups and
+// channels joined or left in the last week.
+func statsHistory(m *telegram.NewMessage, arg string) error {
+	span := statsDefaultHistory
+	if arg != "" {
+		var err error
+		if span, err = parseDurationString(arg); err != nil {
+			_, err := eOR(m, tr(m, "userinfo.history_usage"))
+			return err
+		}
+	}
+
+	cutoff := time.Now().Add(-span)
+	var history []accountStats
+	for _, h := range getStatsHistory() {
+		if !h.Time.Before(cutoff) {
+			history = append(history, h)
+		}
+	}
+	if len(history) < 2 {
+		_, err := eOR(m, fmt.Sprintf(tr(m, "userinfo.history_not_enough"), len(history)))
+		return err
+	}
+
+	msg, _ := eOR(m, tr(m, "userinfo.history_rendering"))
+
+	first, last := history[0], history[len(history)-1]
+	args := []any{formatDurationHuman(span), len(history)}
+	for _, series := range statsChartSeries {
+		args = append(args, series.value(last), formatDelta(series.value(last)-series.value(first)))
+	}
+	caption := fmt.Sprintf(tr(m, "userinfo.history_caption"), args...)
+
+	week := history[0]
+	for _, h := range history {
+		if h.Time.After(last.Time.Add(-7 * 24 * time.Hour)) {
+			break
+		}
+		week = h
+	}
+	groupsJoined, groupsLeft := chatChanges(week.GroupIDs, last.GroupIDs)
+	channelsJoined, channelsLeft := chatChanges(week.ChannelIDs, last.ChannelIDs)
+	caption += fmt.Sprintf(tr(m, "userinfo.history_week"), week.Time.Format("2 Jan"), groupsJoined, groupsLeft, channelsJoined, channelsLeft)
+
+	path := filepath.Join(os.TempDir(), fmt.Sprintf("stats_%d.png", time.Now().UnixNano()))
+	f, err := os.Create(path)
+	if err != nil {
+		_, err = msg.Edit(fmt.Sprintf(tr(m, "userinfo.history_error"), err))
+		return err
+	}
+	defer os.Remove(path)
+	err = png.Encode(f, renderStatsChart(history))
+	f.Close()
+	if err != nil {
+		_, err = msg.Edit(fmt.Sprintf(tr(m, "userinfo.history_error"), err))
+		return err
+	}
+
+	if _, err := m.Client.SendMedia(m.ChatID(), path, &telegram.MediaOptions{Caption: caption}); err != nil {
+		_, err = msg.Edit(fmt.Sprintf(tr(m, "userinfo.history_error"), err))
+		return err
+	}
+	_, err = msg.Delete()
+	return err
+}
+
+// historyArg returns the range given after --history, and whether the flag
+// was present.
+func historyArg(args string) (string, bool) {
+	fields := strings.Fields(args)
+	i := slices.Index(fields, "--history")
+	if i < 0 {
+		return "", false
+	}
+	if i+1 < len(fields) {
+		return fields[i+1], true
+	}
+	return "", true
+}
@@ -1,96 +1,533 @@
 package modules
 
 import (
+	"NovaUserbot/db"
 	"NovaUserbot/locales"
+	"NovaUserbot/logger"
+	"context"
+	"encoding/json"
 	"fmt"
+	"html"
+	"mime"
 	"os"
+	"path/filepath"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
+	"unicode/utf16"
 
 	"github.com/amarnathcjd/gogram/telegram"
 )
 
 const maxStoriesToDownload = 5
 
+// storyPeriods are the active periods Telegram accepts, in hours. Anything
+// but 24 needs Premium.
+var storyPeriods = []int{6, 12, 24, 48}
+
+// storyOptions are the .setstory options. They are stored as-is for
+// scheduled stories, so users and the target are resolved when posting.
+type storyOptions struct {
+	Privacy string   `json:"privacy"`
+	Allow   []string `json:"allow,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+	Period  int      `json:"period,omitempty"`
+	Target  string   `json:"target,omitempty"`
+	Caption string   `json:"caption,omitempty"`
+}
+
+// ScheduledStory is a story waiting to be posted. MsgID is a copy of the
+// media in Saved Messages, so it is still there when the story is due.
+type ScheduledStory struct {
+	ID      int          `json:"id"`
+	MsgID   int32        `json:"msg_id"`
+	Copied  bool         `json:"copied"`
+	PostAt  time.Time    `json:"post_at"`
+	Options storyOptions `json:"options"`
+}
+
+const scheduledStoriesKey = "SCHEDULED_STORIES"
+
+var storyScheduleMutex sync.Mutex
+
+func splitUserList(value string) []string {
+	var users []string
+	for _, u := range strings.Split(value, ",") {
+		if u = strings.TrimSpace(u); u != "" {
+			users = append(users, u)
+		}
+	}
+	return users
+}
+
+// parseStoryArgs reads the leading privacy keyword and flags; the rest of the
+// text is the caption.
+func parseStoryArgs(args string) (storyOptions, time.Duration, error) {
+	opts := storyOptions{Privacy: "all"}
+	var delay time.Duration
+
+	rest := strings.TrimSpace(args)
+	peek := func() string {
+		if i := strings.IndexFunc(rest, unicode.IsSpace); i >= 0 {
+			return rest[:i]
+		}
+		return rest
+	}
+	next := func() string {
+		token := peek()
+		rest = strings.TrimSpace(rest[len(token):])
+		return token
+	}
+
+	if slices.Contains([]string{"all", "contacts", "friends", "selected"}, strings.ToLower(peek())) {
+		opts.Privacy = strings.ToLower(next())
+	}
+
+	for rest != "" && strings.HasPrefix(rest, "-") {
+		flag := next()
+		value := next()
+		if value == "" {
+			return opts, 0, fmt.Errorf("%s needs a value", flag)
+		}
+
+		switch flag {
+		case "-u":
+			opts.Allow = append(opts.Allow, splitUserList(value)...)
+		case "-x":
+			opts.Exclude = append(opts.Exclude, splitUserList(value)...)
+		case "-p":
+			hours, err := strconv.Atoi(strings.TrimSuffix(value, "h"))
+			if err != nil || !slices.Contains(storyPeriods, hours) {
+				return opts, 0, fmt.Errorf("period must be one of 6, 12, 24 or 48 hours")
+			}
+			opts.Period = hours
+		case "-in":
+			d, err := parseDurationString(value)
+			if err != nil {
+				return opts, 0, err
+			}
+			delay = d
+		case "-to":
+			opts.Target = value
+		default:
+			return opts, 0, fmt.Errorf("unknown option %s", flag)
+		}
+	}
+	opts.Caption = rest
+
+	switch {
+	case opts.Privacy == "selected" && len(opts.Allow) == 0:
+		return opts, 0, fmt.Errorf("selected needs users with -u")
+	case opts.Privacy != "selected" && len(opts.Allow) > 0:
+		return opts, 0, fmt.Errorf("-u only works with selected")
+	case opts.Privacy == "friends" && len(opts.Exclude) > 0:
+		return opts, 0, fmt.Errorf("-x does not work with friends")
+	}
+	return opts, delay, nil
+}
+
+func resolveStoryUsers(c *telegram.Client, users []string) ([]telegram.InputUser, error) {
+	var resolved []telegram.InputUser
+	for _, u := range users {
+		var peer any = strings.TrimPrefix(u, "@")
+		if id, err := strconv.ParseInt(u, 10, 64); err == nil {
+			peer = id
+		}
+		user, err := c.GetSendableUser(peer)
+		if err != nil {
+			return nil, fmt.Errorf("user %s not found", u)
+		}
+		resolved = append(resolved, user)
+	}
+	return resolved, nil
+}
+
+func (o storyOptions) privacyRules(c *telegram.Client) ([]telegram.InputPrivacyRule, error) {
+	var rules []telegram.InputPrivacyRule
+	switch o.Privacy {
+	case "contacts":
+		rules = append(rules, &telegram.InputPrivacyValueAllowContacts{})
+	case "friends":
+		rules = append(rules, &telegram.InputPrivacyValueAllowCloseFriends{})
+	case "selected":
+		users, err := resolveStoryUsers(c, o.Allow)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, &telegram.InputPrivacyValueAllowUsers{Users: users})
+	default:
+		rules = append(rules, &telegram.InputPrivacyValueAllowAll{})
+	}
+
+	if len(o.Exclude) > 0 {
+		users, err := resolveStoryUsers(c, o.Exclude)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, &telegram.InputPrivacyValueDisallowUsers{Users: users})
+	}
+	return rules, nil
+}
+
+// describe summarizes the options for the confirmation messages.
+func (o storyOptions) describe() string {
+	text := o.Privacy
+	if len(o.Allow) > 0 {
+		text += ": " + strings.Join(o.Allow, ", ")
+	}
+	if len(o.Exclude) > 0 {
+		text += ", except " + strings.Join(o.Exclude, ", ")
+	}
+	if o.Period > 0 {
+		text += fmt.Sprintf(", %dh", o.Period)
+	}
+	if o.Target != "" {
+		text += ", to " + o.Target
+	}
+	return text
+}
+
+// storyMedia uploads the media of msg as a story media, keeping the original
+// document's MIME type and attributes so videos play as sent.
+func storyMedia(c *telegram.Client, msg *telegram.NewMessage) (telegram.InputMedia, error) {
+	file, err := msg.Download()
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file)
+
+	uploaded, err := c.UploadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if msg.Photo() != nil {
+		return &telegram.InputMediaUploadedPhoto{File: uploaded}, nil
+	}
+
+	media := &telegram.InputMediaUploadedDocument{File: uploaded}
+	if doc := msg.Document(); doc != nil {
+		media.MimeType = doc.MimeType
+		media.Attributes = doc.Attributes
+	}
+	if media.MimeType == "" {
+		media.MimeType = mime.TypeByExtension(filepath.Ext(file))
+	}
+	if media.MimeType == "" {
+		return nil, fmt.Errorf("unsupported media type")
+	}
+	return media, nil
+}
+
+// postStory posts the media of msg as a story with opts.
+func postStory(c *telegram.Client, msg *telegram.NewMessage, opts storyOptions) error {
+	var peer telegram.InputPeer = &telegram.InputPeerSelf{}
+	if opts.Target != "" {
+		var target any = strings.TrimPrefix(opts.Target, "@")
+		if id, err := strconv.ParseInt(opts.Target, 10, 64); err == nil {
+			target = id
+		}
+		p, err := c.ResolvePeer(target)
+		if err != nil {
+			return fmt.Errorf("channel %s not found", opts.Target)
+		}
+		if _, ok := p.(*telegram.InputPeerChannel); !ok {
+			return fmt.Errorf("%s is not a channel", opts.Target)
+		}
+		peer = p
+	}
+
+	rules, err := opts.privacyRules(c)
+	if err != nil {
+		return err
+	}
+
+	media, err := storyMedia(c, msg)
+	if err != nil {
+		return err
+	}
+
+	params := &telegram.StoriesSendStoryParams{
+		Peer:         peer,
+		Media:        media,
+		PrivacyRules: rules,
+		RandomID:     time.Now().UnixNano(),
+		Period:       int32(opts.Period * 3600),
+	}
+	if opts.Caption != "" {
+		params.Entities, params.Caption = c.FormatMessage(opts.Caption, telegram.HTML)
+	} else if msg.Message != nil {
+		params.Caption, params.Entities = msg.Message.Message, msg.Message.Entities
+	}
+
+	_, err = c.StoriesSendStory(params)
+	return err
+}
+
+// captionHTML renders caption, the end of m's text, as HTML carrying the
+// formatting of that part of the message, so it can be stored with the other
+// options and parsed again when the story is posted.
+func captionHTML(m *telegram.NewMessage, caption string) string {
+	text := m.Text()
+	start := strings.LastIndex(text, caption)
+	if caption == "" || start < 0 {
+		return html.EscapeString(caption)
+	}
+
+	units := utf16.Encode([]rune(text))
+	offset := int32(len(utf16.Encode([]rune(text[:start]))))
+	end := offset + int32(len(utf16.Encode([]rune(caption))))
+
+	tags := telegram.ParseEntitiesToTags(m.Message.Entities)
+	slices.SortStableFunc(tags, func(a, b telegram.Tag) int {
+		if a.Offset != b.Offset {
+			return int(a.Offset - b.Offset)
+		}
+		return int(b.Length - a.Length)
+	})
+	opens := make(map[int32][]telegram.Tag)
+	closes := make(map[int32][]telegram.Tag)
+	for _, tag := range tags {
+		from, to := max(tag.Offset, offset), min(tag.Offset+tag.Length, end)
+		if from >= to {
+			continue
+		}
+		opens[from] = append(opens[from], tag)
+		closes[to] = append(closes[to], tag)
+	}
+
+	var b strings.Builder
+	written := offset
+	for i := offset; i <= end; i++ {
+		if len(opens[i])+len(closes[i]) == 0 {
+			continue
+		}
+		b.WriteString(html.EscapeString(string(utf16.Decode(units[written:i]))))
+		written = i
+		for j := len(closes[i]) - 1; j >= 0; j-- {
+			fmt.Fprintf(&b, "</%s>", closes[i][j].Type)
+		}
+		for _, tag := range opens[i] {
+			b.WriteString("<" + tag.Type)
+			for k, v := range tag.Attrs {
+				// The parser takes attribute values verbatim.
+				fmt.Fprintf(&b, ` %s="%s"`, k, strings.ReplaceAll(v, `"`, "%22"))
+			}
+			b.WriteString(">")
+		}
+	}
+	b.WriteString(html.EscapeString(string(utf16.Decode(units[written:end]))))
+	return b.String()
+}
+
 func setStory(m *telegram.NewMessage) error {
 	if !m.IsReply() {
-		_, err := eOR(m, locales.Tr("stories.reply_required"))
+		_, err := eOR(m, tr(m, "stories.reply_required"))
 		return err
 	}
 
 	reply, err := m.GetReplyMessage()
 	if err != nil {
-		_, err := eOR(m, locales.Tr("stories.error_fetching_reply"))
+		_, err := eOR(m, tr(m, "stories.error_fetching_reply"))
 		return err
 	}
 
-	if reply.Media() == nil {
-		_, err := eOR(m, locales.Tr("stories.no_media"))
+	if reply.Photo() == nil && reply.Video() == nil {
+		_, err := eOR(m, tr(m, "stories.no_media"))
 		return err
 	}
 
-	args := strings.ToLower(strings.TrimSpace(m.Args()))
-	var privacyRules []telegram.InputPrivacyRule
-
-	switch args {
-	case "contacts":
-		privacyRules = []telegram.InputPrivacyRule{&telegram.InputPrivacyValueAllowContacts{}}
-	case "all", "":
-		privacyRules = []telegram.InputPrivacyRule{&telegram.InputPrivacyValueAllowAll{}}
-	default:
-		_, err := eOR(m, locales.Tr("stories.privacy_usage"))
+	opts, delay, err := parseStoryArgs(m.Args())
+	if err != nil {
+		_, err := eOR(m, fmt.Sprintf(tr(m, "stories.invalid_options"), html.EscapeString(err.Error()))+tr(m, "stories.privacy_usage"))
 		return err
 	}
+	opts.Caption = captionHTML(m, opts.Caption)
 
-	msg, _ := eOR(m, locales.Tr("stories.uploading"))
+	if delay > 0 {
+		return scheduleStory(m, reply, opts, delay)
+	}
 
-	file, err := reply.Download()
-	if err != nil {
-		_, err := msg.Edit(fmt.Sprintf(locales.Tr("stories.upload_error"), err.Error()))
+	msg, _ := eOR(m, tr(m, "stories.uploading"))
+	if err := postStory(m.Client, reply, opts); err != nil {
+		_, err := msg.Edit(fmt.Sprintf(tr(m, "stories.upload_error"), html.EscapeString(err.Error())))
 		return err
 	}
-	defer os.Remove(file)
 
-	uploaded, err := m.Client.UploadFile(file)
+	_, err = msg.Edit(fmt.Sprintf(tr(m, "stories.story_live_with_privacy"), html.EscapeString(opts.describe())))
+	return err
+}
+
+func getScheduledStories() ([]ScheduledStory, error) {
+	data := db.Get(scheduledStoriesKey)
+	if data == "" {
+		return nil, nil
+	}
+
+	var stories []ScheduledStory
+	if err := json.Unmarshal([]byte(data), &stories); err != nil {
+		return nil, err
+	}
+	return stories, nil
+}
+
+func saveScheduledStories(stories []ScheduledStory) error {
+	if len(stories) == 0 {
+		return db.Del(scheduledStoriesKey)
+	}
+
+	data, err := json.Marshal(stories)
 	if err != nil {
-		_, err := msg.Edit(fmt.Sprintf(locales.Tr("stories.upload_error"), err.Error()))
 		return err
 	}
+	return db.Set(scheduledStoriesKey, string(data))
+}
 
-	var inputMedia telegram.InputMedia
-	if reply.Video() != nil {
-		inputMedia = &telegram.InputMediaUploadedDocument{
-			File:     uploaded,
-			MimeType: "video/mp4",
+// scheduleStory keeps the media in Saved Messages until the story is due, as
+// the original chat may not be resolvable after a restart.
+func scheduleStory(m *telegram.NewMessage, reply *telegram.NewMessage, opts storyOptions, delay time.Duration) error {
+	story := ScheduledStory{MsgID: reply.ID, PostAt: time.Now().Add(delay), Options: opts}
+	if reply.ChatID() != ubId {
+		copied, err := reply.ForwardTo(ubId)
+		if err != nil {
+			_, err := eOR(m, fmt.Sprintf(tr(m, "stories.upload_error"), html.EscapeString(err.Error())))
+			return err
 		}
-	} else {
-		inputMedia = &telegram.InputMediaUploadedPhoto{
-			File: uploaded,
+		story.MsgID, story.Copied = copied.ID, true
+	}
+
+	storyScheduleMutex.Lock()
+	stories, err := getScheduledStories()
+	if err == nil {
+		for _, s := range stories {
+			story.ID = max(story.ID, s.ID)
 		}
+		story.ID++
+		err = saveScheduledStories(append(stories, story))
 	}
+	storyScheduleMutex.Unlock()
 
-	_, err = m.Client.StoriesSendStory(&telegram.StoriesSendStoryParams{
-		Peer:         &telegram.InputPeerSelf{},
-		Media:        inputMedia,
-		PrivacyRules: privacyRules,
-		RandomID:     time.Now().UnixNano(),
-	})
+	if err != nil {
+		_, err := eOR(m, fmt.Sprintf(tr(m, "stories.upload_error"), html.EscapeString(err.Error())))
+		return err
+	}
+
+	_, err = eOR(m, fmt.Sprintf(tr(m, "stories.scheduled"), story.ID, formatDurationHuman(delay), html.EscapeString(opts.describe())))
+	return err
+}
+
+func scheduledStoriesCommand(m *telegram.NewMessage) error {
+	stories, err := getScheduledStories()
+	if err != nil {
+		_, err := eOR(m, fmt.Sprintf(tr(m, "stories.upload_error"), html.EscapeString(err.Error())))
+		return err
+	}
+	if len(stories) == 0 {
+		_, err := eOR(m, tr(m, "stories.no_scheduled"))
+		return err
+	}
+
+	text := tr(m, "stories.scheduled_header")
+	for _, s := range stories {
+		text += fmt.Sprintf(tr(m, "stories.scheduled_entry"), s.ID, formatDurationHuman(time.Until(s.PostAt)), html.EscapeString(s.Options.describe()))
+	}
+	_, err = eOR(m, text)
+	return err
+}
+
+func cancelStoryCommand(m *telegram.NewMessage) error {
+	id, err := strconv.Atoi(strings.TrimSpace(m.Args()))
+	if err != nil {
+		_, err := eOR(m, tr(m, "stories.cancel_usage"))
+		return err
+	}
+
+	storyScheduleMutex.Lock()
+	defer storyScheduleMutex.Unlock()
 
+	stories, err := getScheduledStories()
 	if err != nil {
-		_, err := msg.Edit(fmt.Sprintf(locales.Tr("stories.upload_error"), err.Error()))
+		_, err := eOR(m, fmt.Sprintf(tr(m, "stories.upload_error"), html.EscapeString(err.Error())))
+		return err
+	}
+	i := slices.IndexFunc(stories, func(s ScheduledStory) bool { return s.ID == id })
+	if i < 0 {
+		_, err := eOR(m, fmt.Sprintf(tr(m, "stories.scheduled_not_found"), id))
 		return err
 	}
 
-	privacyText := "all"
-	if args == "contacts" {
-		privacyText = "contacts"
+	if stories[i].Copied {
+		client.DeleteMessages(ubId, []int32{stories[i].MsgID})
+	}
+	if err := saveScheduledStories(slices.Delete(stories, i, i+1)); err != nil {
+		_, err := eOR(m, fmt.Sprintf(tr(m, "stories.upload_error"), html.EscapeString(err.Error())))
+		return err
 	}
-	_, err = msg.Edit(fmt.Sprintf(locales.Tr("stories.story_live_with_privacy"), privacyText))
+
+	_, err = eOR(m, fmt.Sprintf(tr(m, "stories.scheduled_cancelled"), id))
 	return err
 }
 
+// postDueStories posts the scheduled stories that are due and reports each
+// result to the log chat. Failed stories are dropped, not retried.
+func postDueStories() {
+	storyScheduleMutex.Lock()
+	stories, err := getScheduledStories()
+	if err != nil {
+		storyScheduleMutex.Unlock()
+		logger.Errorf("Corrupted %s: %v", scheduledStoriesKey, err)
+		return
+	}
+
+	var due, pending []ScheduledStory
+	for _, s := range stories {
+		if time.Now().Before(s.PostAt) {
+			pending = append(pending, s)
+		} else {
+			due = append(due, s)
+		}
+	}
+	if len(due) > 0 {
+		saveScheduledStories(pending)
+	}
+	storyScheduleMutex.Unlock()
+
+	for _, s := range due {
+		msg, err := client.GetMessageByID(ubId, s.MsgID)
+		if err == nil {
+			err = postStory(client, msg, s.Options)
+		}
+
+		if err != nil {
+			logger.Errorf("Scheduled story #%d failed: %v", s.ID, err)
+			logTo(logger.CategoryError, fmt.Sprintf(locales.Tr("stories.scheduled_failed"), s.ID, html.EscapeString(err.Error())))
+			continue
+		}
+		if s.Copied {
+			client.DeleteMessages(ubId, []int32{s.MsgID})
+		}
+		logTo(logger.CategorySystem, fmt.Sprintf(locales.Tr("stories.scheduled_posted"), s.ID, html.EscapeString(s.Options.describe())))
+	}
+}
+
+func runStoryScheduler(ctx context.Context) {
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			postDueStories()
+		}
+	}
+}
+
 func downloadStory(m *telegram.NewMessage) error {
 	args := strings.TrimSpace(m.Args())
 	var username string
@@ -117,15 +554,15 @@ func downloadStory(m *telegram.NewMessage) error {
 	}
 
 	if username == "" {
-		_, err := eOR(m, locales.Tr("stories.usage"))
+		_, err := eOR(m, tr(m, "stories.usage"))
 		return err
 	}
 
-	msg, _ := eOR(m, locales.Tr("stories.fetching"))
+	msg, _ := eOR(m, tr(m, "stories.fetching"))
 
 	peer, err := m.Client.GetSendablePeer(username)
 	if err != nil {
-		_, err := msg.Edit(fmt.Sprintf(locales.Tr("stories.user_not_found"), username))
+		_, err := msg.Edit(fmt.Sprintf(tr(m, "stories.user_not_found"), username))
 		return err
 	}
 
@@ -136,19 +573,19 @@ func downloadStory(m *telegram.NewMessage) error {
 	case *telegram.InputPeerChannel:
 		peerInput = &telegram.InputPeerChannel{ChannelID: p.ChannelID, AccessHash: p.AccessHash}
 	default:
-		_, err := msg.Edit(locales.Tr("stories.invalid_peer"))
+		_, err := msg.Edit(tr(m, "stories.invalid_peer"))
 		return err
 	}
 
 	if storyID > 0 {
 		stories, err := m.Client.StoriesGetStoriesByID(peerInput, []int32{int32(storyID)})
 		if err != nil {
-			_, err := msg.Edit(fmt.Sprintf(locales.Tr("stories.fetch_error"), err.Error()))
+			_, err := msg.Edit(fmt.Sprintf(tr(m, "stories.fetch_error"), err.Error()))
 			return err
 		}
 
 		if len(stories.Stories) == 0 {
-			_, err := msg.Edit(locales.Tr("stories.story_not_found"))
+			_, err := msg.Edit(tr(m, "stories.story_not_found"))
 			return err
 		}
 
@@ -161,7 +598,7 @@ func downloadStory(m *telegram.NewMessage) error {
 			}
 		}
 
-		_, err = msg.Edit(locales.Tr("stories.uploaded"))
+		_, err = msg.Edit(tr(m, "stories.uploaded"))
 		return err
 	}
 
@@ -170,7 +607,7 @@ func downloadStory(m *telegram.NewMessage) error {
 	case *telegram.InputPeerUser:
 		fullUser, err := m.Client.UsersGetFullUser(&telegram.InputUserObj{UserID: p.UserID, AccessHash: p.AccessHash})
 		if err != nil {
-			_, err := msg.Edit(fmt.Sprintf(locales.Tr("stories.fetch_error"), err.Error()))
+			_, err := msg.Edit(fmt.Sprintf(tr(m, "stories.fetch_error"), err.Error()))
 			return err
 		}
 		if fullUser.FullUser.Stories != nil {
@@ -179,7 +616,7 @@ func downloadStory(m *telegram.NewMessage) error {
 	case *telegram.InputPeerChannel:
 		fullChannel, err := m.Client.ChannelsGetFullChannel(&telegram.InputChannelObj{ChannelID: p.ChannelID, AccessHash: p.AccessHash})
 		if err != nil {
-			_, err := msg.Edit(fmt.Sprintf(locales.Tr("stories.fetch_error"), err.Error()))
+			_, err := msg.Edit(fmt.Sprintf(tr(m, "stories.fetch_error"), err.Error()))
 			return err
 		}
 		if cf, ok := fullChannel.FullChat.(*telegram.ChannelFull); ok && cf.Stories != nil {
@@ -188,7 +625,7 @@ func downloadStory(m *telegram.NewMessage) error {
 	}
 
 	if len(storyItems) == 0 {
-		_, err := msg.Edit(locales.Tr("stories.no_stories"))
+		_, err := msg.Edit(tr(m, "stories.no_stories"))
 		return err
 	}
 
@@ -207,17 +644,19 @@ func downloadStory(m *telegram.NewMessage) error {
 	}
 
 	if count == 0 {
-		_, err := msg.Edit(locales.Tr("stories.download_failed"))
+		_, err := msg.Edit(tr(m, "stories.download_failed"))
 		return err
 	}
 
-	_, err = msg.Edit(fmt.Sprintf(locales.Tr("stories.uploaded_count"), count))
+	_, err = msg.Edit(fmt.Sprintf(tr(m, "stories.uploaded_count"), count))
 	return err
 }
 
-func downloadAndSendStoryItem(m *telegram.NewMessage, story *telegram.StoryItemObj) error {
+// downloadStoryMedia saves the media of a story to a temp file, which the
+// caller removes.
+func downloadStoryMedia(c *telegram.Client, story *telegram.StoryItemObj) (string, bool, error) {
 	if story.Media == nil {
-		return fmt.Errorf("no media in story")
+		return "", false, fmt.Errorf("no media in story")
 	}
 
 	ext := ".jpg"
@@ -232,11 +671,16 @@ func downloadAndSendStoryItem(m *telegram.NewMessage, story *telegram.StoryItemO
 		}
 	}
 
-	tmpFile := fmt.Sprintf("/tmp/story_%d_%d%s", story.ID, time.Now().UnixNano(), ext)
+	tmpFile := filepath.Join(os.TempDir(), fmt.Sprintf("story_%d_%d%s", story.ID, time.Now().UnixNano(), ext))
 
-	file, err := m.Client.DownloadMedia(story.Media, &telegram.DownloadOptions{
+	file, err := c.DownloadMedia(story.Media, &telegram.DownloadOptions{
 		FileName: tmpFile,
 	})
+	return file, isVideo, err
+}
+
+func downloadAndSendStoryItem(m *telegram.NewMessage, story *telegram.StoryItemObj) error {
+	file, isVideo, err := downloadStoryMedia(m.Client, story)
 	if err != nil {
 		return err
 	}
@@ -278,21 +722,21 @@ func downloadArchiveStory(m *telegram.NewMessage) error {
 		}
 	}
 
-	msg, _ := eOR(m, locales.Tr("stories.fetching"))
+	msg, _ := eOR(m, tr(m, "stories.fetching"))
 
 	archived, err := m.Client.StoriesGetStoriesArchive(&telegram.InputPeerSelf{}, 0, 100)
 	if err != nil {
-		_, err := msg.Edit(fmt.Sprintf(locales.Tr("stories.fetch_error"), err.Error()))
+		_, err := msg.Edit(fmt.Sprintf(tr(m, "stories.fetch_error"), err.Error()))
 		return err
 	}
 
 	if len(archived.Stories) == 0 {
-		_, err := msg.Edit(locales.Tr("stories.no_stories"))
+		_, err := msg.Edit(tr(m, "stories.no_stories"))
 		return err
 	}
 
 	if index >= len(archived.Stories) {
-		_, err := msg.Edit(fmt.Sprintf(locales.Tr("stories.invalid_index"), len(archived.Stories), len(archived.Stories)))
+		_, err := msg.Edit(fmt.Sprintf(tr(m, "stories.invalid_index"), len(archived.Stories), len(archived.Stories)))
 		return err
 	}
 
@@ -300,22 +744,26 @@ func downloadArchiveStory(m *telegram.NewMessage) error {
 	if storyItem, ok := story.(*telegram.StoryItemObj); ok {
 		err := downloadAndSendStoryItem(m, storyItem)
 		if err != nil {
-			_, err := msg.Edit(locales.Tr("stories.download_failed"))
+			_, err := msg.Edit(tr(m, "stories.download_failed"))
 			return err
 		}
-		_, err = msg.Edit(fmt.Sprintf(locales.Tr("stories.archived_downloaded"), index+1))
+		_, err = msg.Edit(fmt.Sprintf(tr(m, "stories.archived_downloaded"), index+1))
 		return err
 	}
 
-	_, err = msg.Edit(locales.Tr("stories.download_failed"))
+	_, err = msg.Edit(tr(m, "stories.download_failed"))
 	return err
 }
 
 func LoadStoriesModule(c *telegram.Client) {
 	handlers := []*Handler{
-		{Command: "setstory", Description: "Set replied media as story (all/contacts)", Func: setStory, ModuleName: "Stories", DisAllowSudos: true},
+		{Command: "setstory", Description: "Post replied media as a story [all|contacts|friends|selected] [-u users] [-x users] [-p hours] [-in time] [-to channel] [caption]", Func: setStory, ModuleName: "Stories", DisAllowSudos: true},
+		{Command: "storyqueue", Description: "List scheduled stories", Func: scheduledStoriesCommand, ModuleName: "Stories", DisAllowSudos: true},
+		{Command: "cancelstory", Description: "Cancel a scheduled story by ID", Func: cancelStoryCommand, ModuleName: "Stories", DisAllowSudos: true},
 		{Command: "storydl", Description: "Download user stories", Func: downloadStory, ModuleName: "Stories"},
 		{Command: "archdl", Description: "Download archived story (-n <index> to pick)", Func: downloadArchiveStory, ModuleName: "Stories", DisAllowSudos: true},
 	}
 	AddHandlers(handlers, c)
+
+	startWorker("story scheduler", runStoryScheduler)
 }
@@ -0,0 +1,377 @@
+package modules
+
+import (
+	"NovaUserbot/db"
+	"NovaUserbot/locales"
+	"NovaUserbot/logger"
+	"NovaUserbot/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+const (
+	storyWatchPrefix    = "STORYWATCH:"
+	storyWatchSeenKey   = "STORYWATCH_SEEN:"
+	storyWatchCheckTick = time.Minute
+)
+
+// StoryWatch is a peer whose active stories are archived. Username and Name
+// are refreshed on every poll; the username is a fallback for resolving the
+// peer when its ID is not cached after a restart.
+type StoryWatch struct {
+	ID       int64     `json:"id"`
+	Username string    `json:"username,omitempty"`
+	Name     string    `json:"name"`
+	Added    time.Time `json:"added"`
+}
+
+// resolve looks the peer up by its ID. The stored username is only a
+// fallback, and only while it still belongs to the same peer.
+func (w StoryWatch) resolve() (telegram.InputPeer, error) {
+	peer, err := client.ResolvePeer(w.ID)
+	if err == nil || w.Username == "" {
+		return peer, err
+	}
+	if peer, err = client.ResolvePeer(w.Username); err != nil {
+		return nil, err
+	}
+	if client.GetPeerID(peer) != w.ID {
+		return nil, fmt.Errorf("@%s now belongs to another peer", w.Username)
+	}
+	return peer, nil
+}
+
+func getStoryWatches() []StoryWatch {
+	keys, err := db.Scan(storyWatchPrefix + "*")
+	if err != nil {
+		logger.Errorf("Failed to list story watches: %v", err)
+		return nil
+	}
+
+	var watches []StoryWatch
+	for _, key := range keys {
+		var w StoryWatch
+		if err := json.Unmarshal([]byte(db.Get(key)), &w); err != nil {
+			logger.Errorf("Corrupted %s: %v", key, err)
+			continue
+		}
+		watches = append(watches, w)
+	}
+	slices.SortFunc(watches, func(a, b StoryWatch) int { return a.Added.Compare(b.Added) })
+	return watches
+}
+
+func resolveStoryWatch(arg string) (StoryWatch, error) {
+	arg = strings.TrimPrefix(strings.TrimSpace(arg), "@")
+	var ref any = arg
+	if id, err := strconv.ParseInt(arg, 10, 64); err == nil {
+		ref = id
+	}
+
+	peer, err := client.ResolvePeer(ref)
+	if err != nil {
+		return StoryWatch{}, err
+	}
+
+	w, err := describeStoryPeer(peer)
+	if err != nil {
+		return StoryWatch{}, err
+	}
+	w.Added = time.Now()
+	if w.Name == "" {
+		w.Name = arg
+	}
+	return w, nil
+}
+
+// describeStoryPeer fills the ID, username and name of a watch from peer.
+func describeStoryPeer(peer telegram.InputPeer) (StoryWatch, error) {
+	var w StoryWatch
+	switch p := peer.(type) {
+	case *telegram.InputPeerUser:
+		w.ID = p.UserID
+		if user, err := client.GetUser(p.UserID); err == nil {
+			w.Username = user.Username
+			w.Name = strings.TrimSpace(user.FirstName + " " + user.LastName)
+		}
+	case *telegram.InputPeerChannel:
+		w.ID = p.ChannelID
+		if ch, err := client.GetChannel(p.ChannelID); err == nil {
+			w.Username = ch.Username
+			w.Name = ch.Title
+		}
+	default:
+		return StoryWatch{}, fmt.Errorf("not a user or channel")
+	}
+	return w, nil
+}
+
+// refreshStoryWatch stores the current username and name of a watched peer.
+func refreshStoryWatch(w *StoryWatch, peer telegram.InputPeer) {
+	cur, err := describeStoryPeer(peer)
+	if err != nil || cur.ID != w.ID || cur.Name == "" || (cur.Username == w.Username && cur.Name == w.Name) {
+		return
+	}
+	w.Username, w.Name = cur.Username, cur.Name
+	key := storyWatchPrefix + strconv.FormatInt(w.ID, 10)
+	if !db.Exists(key) {
+		return
+	}
+	data, _ := json.Marshal(w)
+	db.Set(key, string(data))
+}
+
+// activeStories returns the full items of the peer's active stories. Items
+// sent as skipped are fetched by ID.
+func activeStories(peer telegram.InputPeer) ([]*telegram.StoryItemObj, error) {
+	resp, err := client.StoriesGetPeerStories(peer)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Stories == nil {
+		return nil, nil
+	}
+
+	var stories []*telegram.StoryItemObj
+	var skipped []int32
+	for _, item := range resp.Stories.Stories {
+		switch s := item.(type) {
+		case *telegram.StoryItemObj:
+			if s.Min || s.Media == nil {
+				skipped = append(skipped, s.ID)
+			} else {
+				stories = append(stories, s)
+			}
+		case *telegram.StoryItemSkipped:
+			skipped = append(skipped, s.ID)
+		}
+	}
+
+	if len(skipped) > 0 {
+		full, err := client.StoriesGetStoriesByID(peer, skipped)
+		if err != nil {
+			return stories, err
+		}
+		for _, item := range full.Stories {
+			if s, ok := item.(*telegram.StoryItemObj); ok {
+				stories = append(stories, s)
+			}
+		}
+	}
+	return stories, nil
+}
+
+func storyArchiveChat() int64 {
+	if chat := utils.StringToInt64(getVar("STORY_ARCHIVE")); chat != 0 {
+		return chat
+	}
+	if chat := utils.StringToInt64(db.Get("LOG_CHAT")); chat != 0 {
+		return chat
+	}
+	return ubId
+}
+
+// archiveStory downloads a story and sends it to the archive chat through the
+// assistant bot.
+func archiveStory(w StoryWatch, story *telegram.StoryItemObj) error {
+	file, isVideo, err := downloadStoryMedia(client, story)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file)
+
+	peer, err := logClient().GetSendablePeer(storyArchiveChat())
+	if err != nil {
+		return err
+	}
+
+	caption := fmt.Sprintf(locales.Tr("stories.watch_caption"), html.EscapeString(w.Name), story.ID, time.Unix(int64(story.Date), 0).Format("2006-01-02 15:04"))
+	if story.Caption != "" {
+		caption += "\n\n" + html.EscapeString(story.Caption)
+	}
+
+	opts := &telegram.MediaOptions{Caption: caption}
+	if isVideo {
+		opts.Attributes = []telegram.DocumentAttribute{&telegram.DocumentAttributeVideo{SupportsStreaming: true}}
+	}
+	_, err = logClient().SendMedia(peer, file, opts)
+	return err
+}
+
+// pollStoryWatch archives the stories of w not archived before. Each story is
+// recorded once it was sent, so failed ones are retried on the next poll.
+func pollStoryWatch(ctx context.Context, w StoryWatch) {
+	peer, err := w.resolve()
+	if err != nil {
+		logger.Warnf("Story watch: could not resolve %s: %v", w.Name, err)
+		return
+	}
+
+	stories, err := activeStories(peer)
+	if err != nil {
+		logger.Warnf("Story watch: could not fetch stories of %s: %v", w.Name, err)
+		return
+	}
+	refreshStoryWatch(&w, peer)
+
+	seenKey := storyWatchSeenKey + strconv.FormatInt(w.ID, 10)
+	pruneSeenStories(seenKey, stories)
+	for _, story := range stories {
+		if ctx.Err() != nil {
+			return
+		}
+		if db.SIsMember(seenKey, story.ID) {
+			continue
+		}
+		if err := archiveStory(w, story); err != nil {
+			logger.Warnf("Story watch: could not archive story %d of %s: %v", story.ID, w.Name, err)
+			continue
+		}
+		db.SAdd(seenKey, story.ID)
+	}
+}
+
+// pruneSeenStories forgets the stories older than the oldest active one.
+// Story IDs only grow, so those can't come back. An empty list prunes
+// nothing, as stories hidden for a moment would otherwise be archived again.
+func pruneSeenStories(seenKey string, active []*telegram.StoryItemObj) {
+	if len(active) == 0 {
+		return
+	}
+	oldest := slices.MinFunc(active, func(a, b *telegram.StoryItemObj) int { return int(a.ID - b.ID) }).ID
+
+	seen, err := db.SMembers(seenKey)
+	if err != nil {
+		return
+	}
+	var expired []any
+	for _, member := range seen {
+		if id, err := strconv.Atoi(member); err == nil && int32(id) < oldest {
+			expired = append(expired, member)
+		}
+	}
+	if len(expired) > 0 {
+		db.SRem(seenKey, expired...)
+	}
+}
+
+func getStoryWatchInterval() time.Duration {
+	minutes, err := strconv.Atoi(getVar("STORYWATCH_INTERVAL"))
+	if err != nil || minutes <= 0 {
+		minutes = 30
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+func runStoryWatcher(ctx context.Context) {
+	ticker := time.NewTicker(storyWatchCheckTick)
+	defer ticker.Stop()
+
+	var lastPoll time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if time.Since(lastPoll) < getStoryWatchInterval() {
+			continue
+		}
+		lastPoll = time.Now()
+
+		for _, w := range getStoryWatches() {
+			if ctx.Err() != nil {
+				return
+			}
+			pollStoryWatch(ctx, w)
+		}
+	}
+}
+
+func StoryWatchCmd(m *telegram.NewMessage) error {
+	args := strings.Fields(m.Args())
+	if len(args) == 0 {
+		_, err := eOR(m, tr(m, "stories.watch_usage"))
+		return err
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "list":
+		watches := getStoryWatches()
+		if len(watches) == 0 {
+			_, err := eOR(m, tr(m, "stories.watch_empty"))
+			return err
+		}
+
+		text := fmt.Sprintf(tr(m, "stories.watch_list_header"), storyArchiveChat())
+		for _, w := range watches {
+			text += fmt.Sprintf(tr(m, "stories.watch_list_entry"), html.EscapeString(w.Name), w.ID, w.Added.Format("2006-01-02"))
+		}
+		_, err := eOR(m, text)
+		return err
+
+	case "remove", "rm", "del":
+		if len(args) < 2 {
+			_, err := eOR(m, tr(m, "stories.watch_usage"))
+			return err
+		}
+
+		id, _ := strconv.ParseInt(strings.TrimPrefix(args[1], "@"), 10, 64)
+		for _, w := range getStoryWatches() {
+			if w.ID == id || (w.Username != "" && strings.EqualFold(w.Username, strings.TrimPrefix(args[1], "@"))) {
+				key := strconv.FormatInt(w.ID, 10)
+				db.Del(storyWatchPrefix + key)
+				db.Del(storyWatchSeenKey + key)
+				_, err := eOR(m, fmt.Sprintf(tr(m, "stories.watch_removed"), html.EscapeString(w.Name)))
+				return err
+			}
+		}
+		_, err := eOR(m, fmt.Sprintf(tr(m, "stories.watch_not_found"), html.EscapeString(args[1])))
+		return err
+	}
+
+	w, err := resolveStoryWatch(args[0])
+	if err != nil {
+		_, err := eOR(m, fmt.Sprintf(tr(m, "stories.user_not_found"), html.EscapeString(args[0])))
+		return err
+	}
+
+	key := storyWatchPrefix + strconv.FormatInt(w.ID, 10)
+	if db.Exists(key) {
+		_, err := eOR(m, fmt.Sprintf(tr(m, "stories.watch_exists"), html.EscapeString(w.Name)))
+		return err
+	}
+
+	data, _ := json.Marshal(w)
+	if err := db.Set(key, string(data)); err != nil {
+		_, err := eOR(m, fmt.Sprintf(tr(m, "stories.upload_error"), html.EscapeString(err.Error())))
+		return err
+	}
+
+	_, err = eOR(m, fmt.Sprintf(tr(m, "stories.watch_added"), html.EscapeString(w.Name), int(getStoryWatchInterval().Minutes())))
+	return err
+}
+
+func LoadStoryWatchModule(c *telegram.Client) {
+	RegisterVars([]*ConfigVar{
+		{Key: "STORY_ARCHIVE", Module: "Stories", Type: VarChatID, Description: "Chat watched stories are archived to, the log chat when unset"},
+		{Key: "STORYWATCH_INTERVAL", Module: "Stories", Type: VarInt, Default: "30", Description: "Minutes between checks for new stories of watched peers", Validate: intRange(5, 1440)},
+	})
+
+	handlers := []*Handler{
+		{Command: "storywatch", Description: "Archive a peer's new stories automatically (<user>, list, remove <user>)", Func: StoryWatchCmd, ModuleName: "Stories", DisAllowSudos: true},
+	}
+	AddHandlers(handlers, c)
+
+	startWorker("story watcher", runStoryWatcher)
+}
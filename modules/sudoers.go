@@ -2,7 +2,6 @@ package modules
 
 import (
 	"NovaUserbot/db"
-	"NovaUserbot/locales"
 	"NovaUserbot/utils"
 	"fmt"
 
@@ -12,67 +11,67 @@ import (
 func AddSudo(m *telegram.NewMessage) error {
 	userId, userName := ExtractUser(m)
 	if userId == 0 {
-		_, err := eOR(m, locales.Tr("sudo.usage_add"))
+		_, err := eOR(m, tr(m, "sudo.usage_add"))
 		return err
 	}
 
-	msg, _ := eOR(m, locales.Tr("sudo.adding"))
+	msg, _ := eOR(m, tr(m, "sudo.adding"))
 
 	if utils.IsIn64Array(sudoers, userId) {
-		_, err := msg.Edit(locales.Tr("sudo.already_sudo"))
+		_, err := msg.Edit(tr(m, "sudo.already_sudo"))
 		return err
 	}
 
 	if err := db.SAdd("SUDOS", userId); err != nil {
-		_, err := msg.Edit(locales.Tr("sudo.add_error"))
+		_, err := msg.Edit(tr(m, "sudo.add_error"))
 		return err
 	}
 
 	sudoers = append(sudoers, userId)
-	_, err := msg.Edit(fmt.Sprintf(locales.Tr("sudo.added"), userId, userName))
+	_, err := msg.Edit(fmt.Sprintf(tr(m, "sudo.added"), userId, userName))
 	return err
 }
 
 func DelSudo(m *telegram.NewMessage) error {
 	userId, userName := ExtractUser(m)
 	if userId == 0 {
-		_, err := eOR(m, locales.Tr("sudo.usage_del"))
+		_, err := eOR(m, tr(m, "sudo.usage_del"))
 		return err
 	}
 
-	msg, _ := eOR(m, locales.Tr("sudo.deleting"))
+	msg, _ := eOR(m, tr(m, "sudo.deleting"))
 
 	if !utils.IsIn64Array(sudoers, userId) {
-		_, err := msg.Edit(locales.Tr("sudo.not_sudo"))
+		_, err := msg.Edit(tr(m, "sudo.not_sudo"))
 		return err
 	}
 
 	if err := db.SRem("SUDOS", userId); err != nil {
-		_, err := msg.Edit(locales.Tr("sudo.del_error"))
+		_, err := msg.Edit(tr(m, "sudo.del_error"))
 		return err
 	}
 
 	sudoers = utils.RemoveFrom64Array(sudoers, userId)
-	_, err := msg.Edit(fmt.Sprintf(locales.Tr("sudo.deleted"), userId, userName))
+	_, err := msg.Edit(fmt.Sprintf(tr(m, "sudo.deleted"), userId, userName))
 	return err
 }
 
 func ListSudo(m *telegram.NewMessage) error {
 	sudos, err := db.SMembers("SUDOS")
 	if err != nil {
-		_, err := eOR(m, locales.Tr("sudo.fetch_error"))
+		_, err := eOR(m, tr(m, "sudo.fetch_error"))
 		return err
 	}
 
-	msg, _ := eOR(m, locales.Tr("sudo.fetching"))
+	msg, _ := eOR(m, tr(m, "sudo.fetching"))
 
 	var entries string
 	for _, sudo := range sudos {
 		userId, userName := GetUserInfo(sudo)
-		entries += fmt.Sprintf(locales.Tr("sudo.list_entry"), userId, userName) + "\n"
+		entries += fmt.Sprintf(tr(m, "sudo.list_entry"), userId, userName) + "\n"
 	}
 
-	result := fmt.Sprintf(locales.Tr("sudo.list_header"), len(sudos)) + "\n\n" + entries
+	result := fmt.Sprintf(tr(m, "sudo.list_header"), len(sudos)) + "\n\n" + entries
 	_, err = msg.Edit(result, &telegram.SendOptions{ParseMode: "HTML"})
 	return err
 }
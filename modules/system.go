@@ -1,12 +1,11 @@
 package modules
 
 import (
-	"NovaUserbot/locales"
 	"NovaUserbot/utils"
 	"bytes"
-	"context"
 	"fmt"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/amarnathcjd/gogram/telegram"
@@ -32,7 +31,7 @@ func ping(ip string) (string, error) {
 }
 
 func DCPingHandler(m *telegram.NewMessage) error {
-	msg, _ := eOR(m, locales.Tr("ping.dc_pinging"))
+	msg, _ := eOR(m, tr(m, "ping.dc_pinging"))
 
 	dcs := map[string]string{
 		"DC1 (MIA, Miami FL, USA)": "149.154.175.53",
@@ -42,13 +41,13 @@ func DCPingHandler(m *telegram.NewMessage) error {
 		"DC5 (SIN, Singapore, SG)": "91.108.56.130",
 	}
 
-	response := locales.Tr("ping.dc_header") + "\n"
+	response := tr(m, "ping.dc_header") + "\n"
 	for dcName, dcIP := range dcs {
 		pingTime, err := ping(dcIP)
 		if err != nil {
-			response += fmt.Sprintf(locales.Tr("ping.dc_failed"), dcName) + "\n"
+			response += fmt.Sprintf(tr(m, "ping.dc_failed"), dcName) + "\n"
 		} else {
-			response += fmt.Sprintf(locales.Tr("ping.dc_entry"), dcName, pingTime) + "\n"
+			response += fmt.Sprintf(tr(m, "ping.dc_entry"), dcName, pingTime) + "\n"
 		}
 		time.Sleep(100 * time.Millisecond)
 	}
@@ -61,8 +60,8 @@ func PingHandler(m *telegram.NewMessage) error {
 	msgTime := m.OriginalUpdate.(*telegram.MessageObj).Date
 	duration := time.Since(time.Unix(int64(msgTime), 0))
 
-	msg, _ := eOR(m, locales.Tr("ping.pinging"))
-	_, err := msg.Edit(fmt.Sprintf(locales.Tr("ping.result"), duration.Milliseconds(), time.Since(startTime).Truncate(time.Second)))
+	msg, _ := eOR(m, tr(m, "ping.pinging"))
+	_, err := msg.Edit(fmt.Sprintf(tr(m, "ping.result"), duration.Milliseconds(), time.Since(startTime).Truncate(time.Second)))
 	return err
 }
 
@@ -71,23 +70,31 @@ func Alive(m *telegram.NewMessage) error {
 	goVersion := runtime.Version()
 	gogramVersion := telegram.Version
 
-	message := fmt.Sprintf(locales.Tr("alive.message"),
+	message := fmt.Sprintf(tr(m, "alive.message"),
 		client.Me().FirstName+" "+client.Me().LastName, client.Me().ID, len(sudoers), goVersion, gogramVersion, uptime,
 	)
-	aliveimage, err := Db.Get(context.Background(), "ALIVE_IMAGE").Result()
-	if err == nil {
-		_, err = eOR(m, message, telegram.SendOptions{ParseMode: "HTML", Media: aliveimage})
-	} else {
-		_, err = eOR(m, message, telegram.SendOptions{ParseMode: "HTML", Media: "https://files.indrajeeth.in/nova.jpg"})
-	}
+	_, err := eOR(m, message, telegram.SendOptions{ParseMode: "HTML", Media: getVar("ALIVE_IMAGE")})
 	return err
 }
 
+func validCmdPrefix(value string) (string, error) {
+	if len([]rune(value)) > 3 || strings.ContainsAny(value, " \t\n") {
+		return "", fmt.Errorf("prefix must be 1-3 characters without spaces")
+	}
+	return value, nil
+}
+
 func LoadSystemModule(c *telegram.Client) {
+	RegisterVars([]*ConfigVar{
+		{Key: "CMD_HANDLER", Module: "System", Default: ".", Description: "Command prefix", Validate: validCmdPrefix, Restart: true},
+		{Key: "ALIVE_IMAGE", Module: "System", Type: VarURL, Default: "https://files.indrajeeth.in/nova.jpg", Description: "Image or video shown by .alive"},
+	})
+
 	handlers := []*Handler{
 		{ModuleName: "System", Command: "ping", Description: "Ping the userbot", Func: PingHandler},
 		{ModuleName: "System", Command: "dcping", Description: "Ping all data centers", Func: DCPingHandler},
 		{ModuleName: "System", Command: "alive", Description: "Check if the bot is alive", Func: Alive},
+		{ModuleName: "System", Command: "restart", Description: "Restart the userbot", Func: Restart, DisAllowSudos: true},
 	}
 	AddHandlers(handlers, c)
 }
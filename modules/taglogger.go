@@ -3,104 +3,489 @@ package modules
 import (
 	"NovaUserbot/db"
 	"NovaUserbot/locales"
+	"NovaUserbot/logger"
 	"NovaUserbot/utils"
+	"context"
 	"fmt"
+	"html"
+	"regexp"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 
 	"github.com/amarnathcjd/gogram/telegram"
 )
 
+type tagKeyword struct {
+	Pattern string
+	re      *regexp.Regexp
+}
+
+type tagLogEntry struct {
+	ChatTitle  string
+	SenderName string
+	Text       string
+	Keyword    string
+	Link       string
+}
+
+const (
+	tagBatchCheckEvery = 30 * time.Second
+	tagDigestPerMsg    = 15
+	tagDigestButtons   = 8
+	// tagSnippetLength caps the text of a queued message, and
+	// tagDigestLength the HTML of one digest, keeping it below Telegram's
+	// 4096 character limit.
+	tagSnippetLength = 300
+	tagDigestLength  = 3500
+)
+
+var (
+	tagKeywords     []tagKeyword
+	tagKeywordsLock sync.RWMutex
+
+	tagQueue     []tagLogEntry
+	tagQueueLock sync.Mutex
+	tagLastFlush = time.Now()
+)
+
 func SetTagLogger(m *telegram.NewMessage) error {
 	args := m.Args()
 	if args == "" {
-		_, err := m.Edit(locales.Tr("tag_logger.usage"))
+		_, err := m.Edit(tr(m, "tag_logger.usage"))
 		return err
 	}
 
 	chatId, err := strconv.ParseInt(args, 10, 64)
 	if err != nil {
-		_, err = m.Edit(locales.Tr("tag_logger.invalid_chat"))
+		_, err = m.Edit(tr(m, "tag_logger.invalid_chat"))
 		return err
 	}
 
-	peer, err := tgbot.GetSendablePeer(chatId)
+	peer, err := logClient().GetSendablePeer(chatId)
 	if err != nil {
-		_, err = m.Edit(locales.Tr("tag_logger.assistant_not_in_chat"))
+		_, err = m.Edit(tr(m, "tag_logger.assistant_not_in_chat"))
 		return err
 	}
 
-	_, err = tgbot.SendMessage(peer, fmt.Sprintf(locales.Tr("tag_logger.set_success"), args))
+	_, err = logClient().SendMessage(peer, fmt.Sprintf(tr(m, "tag_logger.set_success"), args))
 	if err != nil {
-		_, err = m.Edit(locales.Tr("tag_logger.send_error"))
+		_, err = m.Edit(tr(m, "tag_logger.send_error"))
 		return err
 	}
 
 	db.Set("TAG_LOGGER", strconv.FormatInt(chatId, 10))
-	_, err = m.Edit(fmt.Sprintf(locales.Tr("tag_logger.set_success"), args))
+	_, err = m.Edit(fmt.Sprintf(tr(m, "tag_logger.set_success"), args))
 	return err
 }
 
 func GetTagLogger(m *telegram.NewMessage) error {
 	config := db.Get("TAG_LOGGER")
 	if config == "" {
-		_, err := m.Edit(locales.Tr("tag_logger.not_set"))
+		_, err := m.Edit(tr(m, "tag_logger.not_set"))
 		return err
 	}
-	_, err := m.Edit(fmt.Sprintf(locales.Tr("tag_logger.get_result"), config))
+	_, err := m.Edit(fmt.Sprintf(tr(m, "tag_logger.get_result"), config))
 	return err
 }
 
 func DelTagLogger(m *telegram.NewMessage) error {
 	if !db.Exists("TAG_LOGGER") {
-		_, err := m.Edit(locales.Tr("tag_logger.not_found"))
+		_, err := m.Edit(tr(m, "tag_logger.not_found"))
 		return err
 	}
 	db.Del("TAG_LOGGER")
-	_, err := m.Edit(locales.Tr("tag_logger.deleted"))
+	_, err := m.Edit(tr(m, "tag_logger.deleted"))
 	return err
 }
 
+// ============================================================================
+// Keyword Watch List
+// ============================================================================
+
+// compileTagKeyword turns a watch entry into a case-insensitive regex. Entries
+// wrapped in slashes (/expr/) are used as raw regular expressions, anything
+// else is matched as a whole word.
+func compileTagKeyword(pattern string) (*regexp.Regexp, error) {
+	if len(pattern) > 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		return regexp.Compile("(?i)" + pattern[1:len(pattern)-1])
+	}
+	return regexp.Compile(`(?i)\b` + regexp.QuoteMeta(pattern) + `\b`)
+}
+
+func loadTagKeywords() {
+	patterns, err := db.SMembers("TAG_KEYWORDS")
+	if err != nil {
+		logger.Errorf("Tag logger keywords error: %v", err)
+		return
+	}
+
+	keywords := make([]tagKeyword, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := compileTagKeyword(p)
+		if err != nil {
+			logger.Warnf("Tag logger: skipping invalid keyword %q: %v", p, err)
+			continue
+		}
+		keywords = append(keywords, tagKeyword{Pattern: p, re: re})
+	}
+
+	tagKeywordsLock.Lock()
+	tagKeywords = keywords
+	tagKeywordsLock.Unlock()
+}
+
+func matchTagKeyword(text string) string {
+	if text == "" {
+		return ""
+	}
+
+	tagKeywordsLock.RLock()
+	defer tagKeywordsLock.RUnlock()
+
+	for _, k := range tagKeywords {
+		if k.re.MatchString(text) {
+			return k.Pattern
+		}
+	}
+	return ""
+}
+
+func AddTagKeyword(m *telegram.NewMessage) error {
+	pattern := strings.TrimSpace(m.Args())
+	if pattern == "" {
+		_, err := eOR(m, tr(m, "tag_logger.usage_watch"))
+		return err
+	}
+
+	if _, err := compileTagKeyword(pattern); err != nil {
+		_, err := eOR(m, fmt.Sprintf(tr(m, "tag_logger.invalid_regex"), html.EscapeString(err.Error())))
+		return err
+	}
+
+	if err := db.SAdd("TAG_KEYWORDS", pattern); err != nil {
+		_, err := eOR(m, tr(m, "tag_logger.set_error"))
+		return err
+	}
+	loadTagKeywords()
+
+	_, err := eOR(m, fmt.Sprintf(tr(m, "tag_logger.watch_added"), html.EscapeString(pattern)))
+	return err
+}
+
+func DelTagKeyword(m *telegram.NewMessage) error {
+	pattern := strings.TrimSpace(m.Args())
+	if pattern == "" {
+		_, err := eOR(m, tr(m, "tag_logger.usage_unwatch"))
+		return err
+	}
+
+	if !db.SIsMember("TAG_KEYWORDS", pattern) {
+		_, err := eOR(m, tr(m, "tag_logger.watch_not_found"))
+		return err
+	}
+
+	db.SRem("TAG_KEYWORDS", pattern)
+	loadTagKeywords()
+
+	_, err := eOR(m, fmt.Sprintf(tr(m, "tag_logger.watch_removed"), html.EscapeString(pattern)))
+	return err
+}
+
+func ListTagKeywords(m *telegram.NewMessage) error {
+	tagKeywordsLock.RLock()
+	keywords := make([]string, 0, len(tagKeywords))
+	for _, k := range tagKeywords {
+		keywords = append(keywords, k.Pattern)
+	}
+	tagKeywordsLock.RUnlock()
+
+	if len(keywords) == 0 {
+		_, err := eOR(m, tr(m, "tag_logger.watch_empty"))
+		return err
+	}
+
+	msg := fmt.Sprintf(tr(m, "tag_logger.watch_header"), len(keywords))
+	for _, k := range keywords {
+		msg += fmt.Sprintf(tr(m, "tag_logger.watch_entry"), html.EscapeString(k))
+	}
+	_, err := eOR(m, msg)
+	return err
+}
+
+// ============================================================================
+// Per-chat Mute
+// ============================================================================
+
+func tagMuteTarget(m *telegram.NewMessage) (int64, bool) {
+	args := strings.TrimSpace(m.Args())
+	if args == "" {
+		return m.ChatID(), true
+	}
+	chatID, err := strconv.ParseInt(args, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return normalizeChatID(chatID), true
+}
+
+func MuteTagChat(m *telegram.NewMessage) error {
+	chatID, ok := tagMuteTarget(m)
+	if !ok {
+		_, err := eOR(m, tr(m, "tag_logger.invalid_chat"))
+		return err
+	}
+
+	if err := db.SAdd("TAG_MUTED", chatID); err != nil {
+		_, err := eOR(m, tr(m, "tag_logger.set_error"))
+		return err
+	}
+	_, err := eOR(m, fmt.Sprintf(tr(m, "tag_logger.chat_muted"), chatID))
+	return err
+}
+
+func UnmuteTagChat(m *telegram.NewMessage) error {
+	chatID, ok := tagMuteTarget(m)
+	if !ok {
+		_, err := eOR(m, tr(m, "tag_logger.invalid_chat"))
+		return err
+	}
+
+	if !db.SIsMember("TAG_MUTED", chatID) {
+		_, err := eOR(m, tr(m, "tag_logger.chat_not_muted"))
+		return err
+	}
+
+	db.SRem("TAG_MUTED", chatID)
+	_, err := eOR(m, fmt.Sprintf(tr(m, "tag_logger.chat_unmuted"), chatID))
+	return err
+}
+
+// ============================================================================
+// Digest Batching
+// ============================================================================
+
+func getTagBatchInterval() time.Duration {
+	minutes, err := strconv.Atoi(db.Get("TAG_BATCH"))
+	if err != nil || minutes <= 0 {
+		return 0
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+func SetTagBatch(m *telegram.NewMessage) error {
+	args := strings.ToLower(strings.TrimSpace(m.Args()))
+
+	switch args {
+	case "":
+		interval := getTagBatchInterval()
+		if interval == 0 {
+			_, err := eOR(m, tr(m, "tag_logger.batch_off"))
+			return err
+		}
+		_, err := eOR(m, fmt.Sprintf(tr(m, "tag_logger.batch_status"), int(interval.Minutes())))
+		return err
+	case "off", "0":
+		db.Del("TAG_BATCH")
+		flushTagQueue()
+		_, err := eOR(m, tr(m, "tag_logger.batch_disabled"))
+		return err
+	}
+
+	minutes, err := strconv.Atoi(args)
+	if err != nil || minutes < 1 || minutes > 1440 {
+		_, err := eOR(m, tr(m, "tag_logger.usage_batch"))
+		return err
+	}
+
+	db.Set("TAG_BATCH", strconv.Itoa(minutes))
+	_, err = eOR(m, fmt.Sprintf(tr(m, "tag_logger.batch_set"), minutes))
+	return err
+}
+
+func queueTagEntry(entry tagLogEntry) {
+	tagQueueLock.Lock()
+	tagQueue = append(tagQueue, entry)
+	tagQueueLock.Unlock()
+}
+
+// flushTagQueue posts all queued entries as digest messages, splitting them by
+// count and rendered length so each message stays below Telegram's limit.
+func flushTagQueue() {
+	tagQueueLock.Lock()
+	entries := tagQueue
+	tagQueue = nil
+	tagLastFlush = time.Now()
+	tagQueueLock.Unlock()
+
+	if len(entries) == 0 {
+		return
+	}
+
+	peer, err := tagLoggerPeer()
+	if err != nil {
+		logger.Errorf("Tag logger digest peer error: %v", err)
+		return
+	}
+
+	rendered := make([]string, len(entries))
+	for i, e := range entries {
+		rendered[i] = fmt.Sprintf(locales.Tr("tag_logger.digest_entry"), i+1, e.SenderName, e.ChatTitle, e.Text)
+		if e.Keyword != "" {
+			rendered[i] += fmt.Sprintf(locales.Tr("tag_logger.digest_keyword"), e.Keyword)
+		}
+	}
+
+	btn := telegram.ButtonBuilder{}
+	for start, end := 0, 0; start < len(entries); start = end {
+		size := 0
+		for end < len(entries) && end-start < tagDigestPerMsg && (end == start || size+utf8.RuneCountInString(rendered[end]) <= tagDigestLength) {
+			size += utf8.RuneCountInString(rendered[end])
+			end++
+		}
+
+		text := fmt.Sprintf(locales.Tr("tag_logger.digest_header"), len(entries), start+1, end)
+		var buttons []telegram.KeyboardButton
+		for i, e := range entries[start:end] {
+			n := start + i + 1
+			text += rendered[n-1]
+			if e.Link != "" && len(buttons) < tagDigestButtons {
+				buttons = append(buttons, btn.URL(fmt.Sprintf(locales.Tr("tag_logger.digest_btn"), n), e.Link))
+			}
+		}
+
+		opts := &telegram.SendOptions{ParseMode: "HTML"}
+		if len(buttons) > 0 {
+			opts.ReplyMarkup = telegram.NewKeyboard().NewColumn(4, buttons...).Build()
+		}
+		if _, err := logClient().SendMessage(peer, text, opts); err != nil {
+			logger.Errorf("Tag logger digest error: %v", err)
+		}
+	}
+}
+
+func runTagBatcher(ctx context.Context) {
+	ticker := time.NewTicker(tagBatchCheckEvery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushTagQueue()
+			return
+		case <-ticker.C:
+		}
+
+		interval := getTagBatchInterval()
+
+		tagQueueLock.Lock()
+		due := len(tagQueue) > 0 && (interval == 0 || time.Since(tagLastFlush) >= interval)
+		tagQueueLock.Unlock()
+
+		if due {
+			flushTagQueue()
+		}
+	}
+}
+
+// ============================================================================
+// Message Handler
+// ============================================================================
+
+func tagLoggerPeer() (telegram.InputPeer, error) {
+	chatId := utils.StringToInt64(db.Get("TAG_LOGGER"))
+	if chatId == 0 {
+		return nil, fmt.Errorf("tag logger not set")
+	}
+	return logClient().GetSendablePeer(chatId)
+}
+
 func CheckForTags(m *telegram.NewMessage) error {
-	if !m.Message.Mentioned || m.Message.Out || m.Sender.Bot {
+	if m.Message.Out || m.Sender == nil || m.Sender.Bot {
 		return nil
 	}
 
+	keyword := ""
+	if !m.Message.Mentioned {
+		if keyword = matchTagKeyword(m.Text()); keyword == "" {
+			return nil
+		}
+	}
+
 	config := db.Get("TAG_LOGGER")
 	if config == "" {
 		return nil
 	}
 
 	chatId := utils.StringToInt64(config)
-	if m.ChatID() == chatId {
+	if m.ChatID() == normalizeChatID(chatId) || db.SIsMember("TAG_MUTED", m.ChatID()) {
+		return nil
+	}
+
+	msgText := m.Text()
+	if msgText == "" {
+		msgText = locales.Tr("tag_logger.no_text")
+	}
+
+	entry := tagLogEntry{
+		ChatTitle:  html.EscapeString(chatTitle(m)),
+		SenderName: html.EscapeString(strings.TrimSpace(m.Sender.FirstName + " " + m.Sender.LastName)),
+		Text:       html.EscapeString(msgText),
+		Keyword:    html.EscapeString(keyword),
+		Link:       msgLink(m),
+	}
+
+	if getTagBatchInterval() > 0 {
+		if runes := []rune(msgText); len(runes) > tagSnippetLength {
+			entry.Text = html.EscapeString(string(runes[:tagSnippetLength])) + "…"
+		}
+		queueTagEntry(entry)
 		return nil
 	}
 
-	peer, err := tgbot.GetSendablePeer(chatId)
+	peer, err := logClient().GetSendablePeer(chatId)
 	if err != nil {
 		return err
 	}
 
-	msgText := m.Text()
-	if msgText == "" {
-		msgText = locales.Tr("tag_logger.no_text")
+	notification := fmt.Sprintf(locales.Tr("tag_logger.notification"), entry.ChatTitle, entry.SenderName, entry.Text)
+	if keyword != "" {
+		notification = fmt.Sprintf(locales.Tr("tag_logger.keyword_notification"), entry.Keyword, entry.ChatTitle, entry.SenderName, entry.Text)
 	}
 
-	btn := telegram.ButtonBuilder{}
-	notification := fmt.Sprintf(locales.Tr("tag_logger.notification"), m.Channel.Title, m.Sender.FirstName, msgText)
+	opts := &telegram.SendOptions{ParseMode: "HTML"}
+	if entry.Link != "" {
+		btn := telegram.ButtonBuilder{}
+		opts.ReplyMarkup = telegram.NewKeyboard().NewRow(1, btn.URL(locales.Tr("tag_logger.go_to_message"), entry.Link)).Build()
+	}
 
-	_, err = tgbot.SendMessage(peer, notification, &telegram.SendOptions{
-		ParseMode:   "HTML",
-		ReplyMarkup: telegram.NewKeyboard().NewRow(1, btn.URL(locales.Tr("tag_logger.go_to_message"), msgLink(m))).Build(),
-	})
+	_, err = logClient().SendMessage(peer, notification, opts)
 	return err
 }
 
 func LoadTagLogger(c *telegram.Client) {
+	RegisterVars([]*ConfigVar{
+		{Key: "TAG_LOGGER", Module: "Tag Logger", Type: VarChatID, Description: "Chat mentions and watched keywords are forwarded to"},
+		{Key: "TAG_BATCH", Module: "Tag Logger", Type: VarInt, Default: "0", Description: "Minutes between tag digests, 0 sends each tag at once", Validate: intRange(0, 1440)},
+	})
+
 	handlers := []*Handler{
 		{Command: "taglogger", Func: SetTagLogger, Description: "Set tag logger chat", ModuleName: "Tag Logger"},
 		{Command: "gettaglogger", Func: GetTagLogger, Description: "Get tag logger chat", ModuleName: "Tag Logger"},
 		{Command: "deltaglogger", Func: DelTagLogger, Description: "Delete tag logger", ModuleName: "Tag Logger"},
+		{Command: "tagwatch", Func: AddTagKeyword, Description: "Alert on a keyword or /regex/ even without a mention", ModuleName: "Tag Logger"},
+		{Command: "deltagwatch", Func: DelTagKeyword, Description: "Remove a watched keyword", ModuleName: "Tag Logger"},
+		{Command: "tagwatches", Func: ListTagKeywords, Description: "List watched keywords", ModuleName: "Tag Logger"},
+		{Command: "tagmute", Func: MuteTagChat, Description: "Mute tag logger for this chat (or chat id)", ModuleName: "Tag Logger"},
+		{Command: "tagunmute", Func: UnmuteTagChat, Description: "Unmute tag logger for this chat (or chat id)", ModuleName: "Tag Logger"},
+		{Command: "tagbatch", Func: SetTagBatch, Description: "Batch tags into one digest every N minutes (or off)", ModuleName: "Tag Logger"},
 	}
 	AddHandlers(handlers, c)
+
+	loadTagKeywords()
+	startWorker("tag batcher", runTagBatcher)
+
 	c.AddMessageHandler(telegram.OnNewMessage, CheckForTags)
 }
@@ -1,7 +1,6 @@
 package modules
 
 import (
-	"NovaUserbot/locales"
 	"fmt"
 	"io"
 	"math/rand"
@@ -19,7 +18,7 @@ import (
 func unsplashSearch(m *telegram.NewMessage) error {
 	args := strings.TrimSpace(m.Args())
 	if args == "" {
-		_, err := eOR(m, locales.Tr("unsplash.usage"))
+		_, err := eOR(m, tr(m, "unsplash.usage"))
 		return err
 	}
 
@@ -39,11 +38,11 @@ func unsplashSearch(m *telegram.NewMessage) error {
 		}
 	}
 
-	msg, _ := eOR(m, locales.Tr("unsplash.searching"))
+	msg, _ := eOR(m, tr(m, "unsplash.searching"))
 
 	photos := fetchUnsplashPhotoURLs(query, limit)
 	if len(photos) == 0 {
-		_, err := msg.Edit(fmt.Sprintf(locales.Tr("unsplash.no_results"), query))
+		_, err := msg.Edit(fmt.Sprintf(tr(m, "unsplash.no_results"), query))
 		return err
 	}
 
@@ -59,7 +58,7 @@ func unsplashSearch(m *telegram.NewMessage) error {
 	}
 
 	if len(downloadedFiles) == 0 {
-		_, err := m.Respond(fmt.Sprintf(locales.Tr("unsplash.download_error"), query))
+		_, err := m.Respond(fmt.Sprintf(tr(m, "unsplash.download_error"), query))
 		return err
 	}
 
@@ -69,7 +68,7 @@ func unsplashSearch(m *telegram.NewMessage) error {
 		}
 	}()
 
-	caption := fmt.Sprintf(locales.Tr("unsplash.caption"), query)
+	caption := fmt.Sprintf(tr(m, "unsplash.caption"), query)
 
 	if len(downloadedFiles) > 1 {
 		_, err := m.RespondAlbum(downloadedFiles, &telegram.MediaOptions{
@@ -87,7 +86,7 @@ func unsplashSearch(m *telegram.NewMessage) error {
 		}
 	}
 
-	_, err := m.Respond(fmt.Sprintf(locales.Tr("unsplash.uploaded"), len(downloadedFiles)))
+	_, err := m.Respond(fmt.Sprintf(tr(m, "unsplash.uploaded"), len(downloadedFiles)))
 	return err
 }
 
@@ -2,7 +2,6 @@ package modules
 
 import (
 	"NovaUserbot/db"
-	"NovaUserbot/locales"
 	"NovaUserbot/utils"
 	"fmt"
 	"regexp"
@@ -18,7 +17,10 @@ const (
 
 var validBranchName = regexp.MustCompile(`^[a-zA-Z0-9._/-]+$`)
 
-var validRepoURL = regexp.MustCompile(`^https?://[a-zA-Z0-9._@:/-]+\.git$`)
+var (
+	validRepoURL         = regexp.MustCompile(`^https?://[a-zA-Z0-9._@:/-]+\.git$`)
+	validGitTokenPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+)
 
 func sanitizeBranch(branch string) (string, error) {
 	branch = strings.TrimSpace(branch)
@@ -56,7 +58,7 @@ func getRepoURL() (string, error) {
 	}
 
 	if gitToken != "" && len(sanitized) > 8 && sanitized[:8] == "https://" {
-		if !regexp.MustCompile(`^[a-zA-Z0-9_-]+$`).MatchString(gitToken) {
+		if !validGitTokenPattern.MatchString(gitToken) {
 			return "", fmt.Errorf("invalid git token")
 		}
 		return fmt.Sprintf("https://%s@%s", gitToken, sanitized[8:]), nil
@@ -113,43 +115,56 @@ func resetAndPullLatest() error {
 }
 
 func checkUpdateCmd(m *telegram.NewMessage) error {
-	msg, err := eOR(m, locales.Tr("updater.checking"))
+	msg, err := eOR(m, tr(m, "updater.checking"))
 	if err != nil {
 		return err
 	}
 
 	diff, err := checkForUpstreamChanges()
 	if err != nil {
-		_, err = msg.Edit(fmt.Sprintf(locales.Tr("updater.check_error"), err.Error()))
+		_, err = msg.Edit(fmt.Sprintf(tr(m, "updater.check_error"), err.Error()))
 		return err
 	}
 
 	if strings.TrimSpace(diff) == "" {
-		_, err = msg.Edit(locales.Tr("updater.up_to_date"))
+		_, err = msg.Edit(tr(m, "updater.up_to_date"))
 		return err
 	}
 
-	_, err = msg.Edit(locales.Tr("updater.updates_available"))
+	_, err = msg.Edit(tr(m, "updater.updates_available"))
 	return err
 }
 
 func updateCmd(m *telegram.NewMessage) error {
-	msg, err := eOR(m, locales.Tr("updater.updating"))
+	msg, err := eOR(m, tr(m, "updater.updating"))
 	if err != nil {
 		return err
 	}
 
 	err = resetAndPullLatest()
 	if err != nil {
-		_, err = msg.Edit(fmt.Sprintf(locales.Tr("updater.update_error"), err.Error()))
+		_, err = msg.Edit(fmt.Sprintf(tr(m, "updater.update_error"), err.Error()))
 		return err
 	}
 
-	_, err = msg.Edit(locales.Tr("updater.update_success"))
+	_, err = msg.Edit(tr(m, "updater.update_success"))
 	return err
 }
 
+func validGitToken(value string) (string, error) {
+	if !validGitTokenPattern.MatchString(value) {
+		return "", fmt.Errorf("invalid git token")
+	}
+	return value, nil
+}
+
 func LoadUpdaterModule(c *telegram.Client) {
+	RegisterVars([]*ConfigVar{
+		{Key: "GIT_TOKEN", Module: "Updater", Description: "GitHub token for private upstreams and gists", Validate: validGitToken, Secret: true},
+		{Key: "UPSTREAM_REPO", Module: "Updater", Default: defaultUpstreamRepo, Description: "Repository .update pulls from", Validate: sanitizeRepoURL},
+		{Key: "UPSTREAM_BRANCH", Module: "Updater", Default: defaultUpstreamBranch, Description: "Branch .update pulls from", Validate: sanitizeBranch},
+	})
+
 	handlers := []*Handler{
 		{ModuleName: "Updater", Command: "checkupdate", Description: "Check for updates", Func: checkUpdateCmd},
 		{ModuleName: "Updater", Command: "update", Description: "Update to latest version", Func: updateCmd, DisAllowSudos: true},
@@ -1,7 +1,8 @@
 package modules
 
 import (
-	"NovaUserbot/locales"
+	"NovaUserbot/logger"
+	"context"
 	"fmt"
 	"strconv"
 	"time"
@@ -26,113 +27,40 @@ func parseBirthday(day, month, year int32) string {
 }
 
 func StatsCmd(m *telegram.NewMessage) error {
-	var (
-		admingc, adminch, creator, users, bots, grps, channels, deleted, total, notify,
-		pinned, blockedc, contacts, mutuals int
-		unreadmsgs, mentions, reactions int32
-	)
-
-	msg, _ := eOR(m, locales.Tr("userinfo.fetching_stats"))
-	client.IterDialogs(func(d *telegram.TLDialog) error {
-		dialog, ok := d.Dialog.(*telegram.DialogObj)
-		if !ok {
-			return nil
-		}
-		if dialog.NotifySettings != nil && !dialog.NotifySettings.Silent {
-			notify++
-		}
-		unreadmsgs += dialog.UnreadCount
-		mentions += dialog.UnreadMentionsCount
-		reactions += dialog.UnreadReactionsCount
-
-		if dialog.Pinned {
-			pinned++
-		}
-		switch p := dialog.Peer.(type) {
-		case *telegram.PeerChannel:
-			total++
-			ch, err := client.GetChannel(p.ChannelID)
-			if err != nil {
-				return nil
-			}
-
-			if ch.Creator {
-				creator++
-			}
-			if ch.Broadcast {
-				if ch.AdminRights == nil {
-					adminch++
-				}
-				channels++
-			} else {
-				if ch.AdminRights != nil {
-					admingc++
-				}
-				grps++
-			}
-		case *telegram.PeerUser:
-			total++
-			user, err := client.GetUser(p.UserID)
-			if err != nil {
-				return nil
-			}
-
-			if user.Deleted {
-				deleted++
-			}
-			if user.Bot {
-				bots++
-			} else {
-				users++
-			}
-
-			if user.MutualContact {
-				mutuals++
-			}
-			if user.Contact {
-				contacts++
-			}
-		case *telegram.PeerChat:
-			total++
-			chat, err := client.GetChat(p.ChatID)
-			if err != nil {
-				return nil
-			}
-			if chat.AdminRights != nil {
-				admingc++
-			}
-			grps++
-		}
-
-		return nil
-	}, &telegram.DialogOptions{SleepThresholdMs: 10, Limit: 5000})
+	if arg, ok := historyArg(m.Args()); ok {
+		return statsHistory(m, arg)
+	}
 
-	blocked, _ := client.ContactsGetBlocked(false, 0, 5000)
-	if b, ok := blocked.(*telegram.ContactsBlockedObj); ok {
-		blockedc = len(b.Users)
+	msg, _ := eOR(m, tr(m, "userinfo.fetching_stats"))
+	s, err := collectStats(context.Background())
+	if err != nil {
+		logger.Warnf("Stats may be incomplete: %v", err)
+	} else if err := saveStatsSnapshot(s); err != nil {
+		logger.Errorf("Could not save stats snapshot: %v", err)
 	}
-	response := fmt.Sprintf(locales.Tr("userinfo.stats"),
-		users, bots, grps, channels, contacts, blockedc,
-		pinned, unreadmsgs, notify, mentions, reactions,
-		creator, admingc, adminch, deleted, mutuals,
-		total, blockedc,
+
+	response := fmt.Sprintf(tr(m, "userinfo.stats"),
+		s.Users, s.Bots, s.Groups, s.Channels, s.Contacts, s.Blocked,
+		s.Pinned, s.Unread, s.Notify, s.Mentions, s.Reactions,
+		s.Creator, s.AdminGroups, s.AdminChannels, s.Deleted, s.Mutuals,
+		s.Total, s.Blocked,
 	)
 
-	_, err := msg.Edit(response)
+	_, err = msg.Edit(response)
 	return err
 }
 
 func userInfo(m *telegram.NewMessage) error {
 	userId, _, _ := ExtractUserMsg(m)
 	if userId == 0 {
-		_, err := eOR(m, locales.Tr("userinfo.usage_info"))
+		_, err := eOR(m, tr(m, "userinfo.usage_info"))
 		return err
 	}
 
-	msg, _ := eOR(m, locales.Tr("userinfo.fetching_info"))
+	msg, _ := eOR(m, tr(m, "userinfo.fetching_info"))
 	peer, _ := client.GetSendablePeer(userId)
 
-	response := locales.Tr("userinfo.info_header")
+	response := tr(m, "userinfo.info_header")
 	var photo *telegram.InputMediaPhoto
 
 	switch p := peer.(type) {
@@ -142,33 +70,33 @@ func userInfo(m *telegram.NewMessage) error {
 		un := userinfo.Users[0].(*telegram.UserObj)
 
 		if un.FirstName != "" {
-			response += fmt.Sprintf(locales.Tr("userinfo.first_name"), un.FirstName)
+			response += fmt.Sprintf(tr(m, "userinfo.first_name"), un.FirstName)
 		}
 		if un.LastName != "" {
-			response += fmt.Sprintf(locales.Tr("userinfo.last_name"), un.LastName)
+			response += fmt.Sprintf(tr(m, "userinfo.last_name"), un.LastName)
 		}
-		response += fmt.Sprintf(locales.Tr("userinfo.user_id"), un.ID)
+		response += fmt.Sprintf(tr(m, "userinfo.user_id"), un.ID)
 		if un.Username != "" {
-			response += fmt.Sprintf(locales.Tr("userinfo.username"), un.Username)
+			response += fmt.Sprintf(tr(m, "userinfo.username"), un.Username)
 		}
 		if uf.About != "" {
-			response += fmt.Sprintf(locales.Tr("userinfo.about"), uf.About)
+			response += fmt.Sprintf(tr(m, "userinfo.about"), uf.About)
 		}
 		if un.Usernames != nil {
 			var names string
 			for _, v := range un.Usernames {
 				names += "@" + v.Username + " "
 			}
-			response += fmt.Sprintf(locales.Tr("userinfo.usernames"), names)
+			response += fmt.Sprintf(tr(m, "userinfo.usernames"), names)
 		}
 		if uf.Birthday != nil {
-			response += fmt.Sprintf(locales.Tr("userinfo.birthday"), parseBirthday(uf.Birthday.Day, uf.Birthday.Month, uf.Birthday.Year))
+			response += fmt.Sprintf(tr(m, "userinfo.birthday"), parseBirthday(uf.Birthday.Day, uf.Birthday.Month, uf.Birthday.Year))
 		}
-		response += fmt.Sprintf(locales.Tr("userinfo.user_link"), un.ID)
+		response += fmt.Sprintf(tr(m, "userinfo.user_link"), un.ID)
 
 		if uf.ProfilePhoto != nil {
 			pic := uf.ProfilePhoto.(*telegram.PhotoObj)
-			response += fmt.Sprintf(locales.Tr("userinfo.dc_id"), pic.DcID)
+			response += fmt.Sprintf(tr(m, "userinfo.dc_id"), pic.DcID)
 			if uf.PersonalPhoto != nil {
 				pic = uf.PersonalPhoto.(*telegram.PhotoObj)
 			}
@@ -178,38 +106,38 @@ func userInfo(m *telegram.NewMessage) error {
 			}
 		}
 
-		response += fmt.Sprintf(locales.Tr("userinfo.is_bot"), un.Bot)
-		response += fmt.Sprintf(locales.Tr("userinfo.is_deleted"), un.Deleted)
-		response += fmt.Sprintf(locales.Tr("userinfo.is_contact"), un.Contact)
-		response += fmt.Sprintf(locales.Tr("userinfo.is_mutual"), un.MutualContact)
-		response += fmt.Sprintf(locales.Tr("userinfo.is_premium"), un.Premium)
+		response += fmt.Sprintf(tr(m, "userinfo.is_bot"), un.Bot)
+		response += fmt.Sprintf(tr(m, "userinfo.is_deleted"), un.Deleted)
+		response += fmt.Sprintf(tr(m, "userinfo.is_contact"), un.Contact)
+		response += fmt.Sprintf(tr(m, "userinfo.is_mutual"), un.MutualContact)
+		response += fmt.Sprintf(tr(m, "userinfo.is_premium"), un.Premium)
 
 	case *telegram.InputPeerChannel:
 		chatInfo, _ := m.Client.ChannelsGetFullChannel(&telegram.InputChannelObj{ChannelID: p.ChannelID, AccessHash: p.AccessHash})
 		cf := chatInfo.FullChat.(*telegram.ChannelFull)
 		cobj := chatInfo.Chats[0].(*telegram.Channel)
 
-		response += fmt.Sprintf(locales.Tr("userinfo.chat_title"), cobj.Title)
-		response += fmt.Sprintf(locales.Tr("userinfo.chat_id"), cobj.ID)
+		response += fmt.Sprintf(tr(m, "userinfo.chat_title"), cobj.Title)
+		response += fmt.Sprintf(tr(m, "userinfo.chat_id"), cobj.ID)
 		if cobj.Username != "" {
-			response += fmt.Sprintf(locales.Tr("userinfo.chat_username"), cobj.Username)
+			response += fmt.Sprintf(tr(m, "userinfo.chat_username"), cobj.Username)
 		}
 		if cf.About != "" {
-			response += fmt.Sprintf(locales.Tr("userinfo.about"), cf.About)
+			response += fmt.Sprintf(tr(m, "userinfo.about"), cf.About)
 		}
 		if cf.ChatPhoto != nil {
 			pic := cf.ChatPhoto.(*telegram.PhotoObj)
-			response += fmt.Sprintf(locales.Tr("userinfo.dc_id"), pic.DcID)
+			response += fmt.Sprintf(tr(m, "userinfo.dc_id"), pic.DcID)
 			photo = &telegram.InputMediaPhoto{
 				ID:      &telegram.InputPhotoObj{ID: pic.ID, AccessHash: pic.AccessHash, FileReference: pic.FileReference},
 				Spoiler: true,
 			}
 		}
-		response += fmt.Sprintf(locales.Tr("userinfo.participants"), cf.ParticipantsCount)
-		response += fmt.Sprintf(locales.Tr("userinfo.admins_count"), cf.AdminsCount)
+		response += fmt.Sprintf(tr(m, "userinfo.participants"), cf.ParticipantsCount)
+		response += fmt.Sprintf(tr(m, "userinfo.admins_count"), cf.AdminsCount)
 
 	default:
-		response = locales.Tr("userinfo.unknown_peer")
+		response = tr(m, "userinfo.unknown_peer")
 	}
 
 	if photo != nil && photo.ID != nil {
@@ -226,15 +154,15 @@ func idCmd(m *telegram.NewMessage) error {
 		userId = m.SenderID()
 	}
 
-	response := fmt.Sprintf(locales.Tr("userinfo.id_user"), userId, userId)
-	response += fmt.Sprintf(locales.Tr("userinfo.id_chat"), msgLink(m), m.ChatID())
-	response += fmt.Sprintf(locales.Tr("userinfo.id_message"), msgLink(m), m.ID)
+	response := fmt.Sprintf(tr(m, "userinfo.id_user"), userId, userId)
+	response += fmt.Sprintf(tr(m, "userinfo.id_chat"), msgLink(m), m.ChatID())
+	response += fmt.Sprintf(tr(m, "userinfo.id_message"), msgLink(m), m.ID)
 
 	if m.IsReply() {
 		reply, _ := m.GetReplyMessage()
-		response += fmt.Sprintf(locales.Tr("userinfo.id_reply"), msgLink(reply), reply.ID)
+		response += fmt.Sprintf(tr(m, "userinfo.id_reply"), msgLink(reply), reply.ID)
 		if reply.File != nil {
-			response += fmt.Sprintf(locales.Tr("userinfo.id_file"), msgLink(reply), reply.File.FileID)
+			response += fmt.Sprintf(tr(m, "userinfo.id_file"), msgLink(reply), reply.File.FileID)
 		}
 	}
 
@@ -243,10 +171,16 @@ func idCmd(m *telegram.NewMessage) error {
 }
 
 func LoadMyinfo(c *telegram.Client) {
+	RegisterVars([]*ConfigVar{
+		{Key: "STATS_INTERVAL", Module: "User Info", Type: VarInt, Default: "24", Description: "Hours between account stats snapshots for .stats --history (24-168, one is kept per day), 0 disables them", Validate: validStatsInterval},
+	})
+
 	handlers := []*Handler{
-		{Command: "stats", Description: "Fetch user statistics", Func: StatsCmd, ModuleName: "User Info"},
+		{Command: "stats", Description: "Fetch user statistics [--history 30d]", Func: StatsCmd, ModuleName: "User Info"},
 		{Command: "info", Description: "Fetch user info", Func: userInfo, ModuleName: "User Info"},
 		{Command: "id", Description: "Fetch ID info", Func: idCmd, ModuleName: "User Info"},
 	}
 	AddHandlers(handlers, c)
+
+	startWorker("stats snapshots", runStatsSnapshots)
 }
@@ -0,0 +1,160 @@
+package modules
+
+import (
+	"NovaUserbot/db"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type VarType int
+
+const (
+	VarString VarType = iota
+	VarInt
+	VarBool
+	VarChatID
+	VarURL
+)
+
+func (t VarType) String() string {
+	switch t {
+	case VarInt:
+		return "int"
+	case VarBool:
+		return "bool"
+	case VarChatID:
+		return "chat id"
+	case VarURL:
+		return "url"
+	}
+	return "string"
+}
+
+// ConfigVar describes a database variable a module reads, so .setvar can
+// validate it and .vars can document it.
+type ConfigVar struct {
+	Key         string
+	Module      string
+	Type        VarType
+	Default     string
+	Description string
+	Secret      bool
+	// Restart marks variables that are only read at startup.
+	Restart bool
+	// Validate runs after the type check and may return a normalised value.
+	Validate func(value string) (string, error)
+	// OnChange is called after the value was set or deleted, with the new
+	// effective value.
+	OnChange func(value string)
+}
+
+var (
+	varRegistry     = make(map[string]*ConfigVar)
+	varRegistryLock sync.RWMutex
+)
+
+func RegisterVars(vars []*ConfigVar) {
+	varRegistryLock.Lock()
+	defer varRegistryLock.Unlock()
+
+	for _, v := range vars {
+		varRegistry[v.Key] = v
+	}
+}
+
+func lookupVar(key string) (*ConfigVar, bool) {
+	varRegistryLock.RLock()
+	defer varRegistryLock.RUnlock()
+	v, ok := varRegistry[key]
+	return v, ok
+}
+
+// registeredVars returns all variables sorted by module, then key.
+func registeredVars() []*ConfigVar {
+	varRegistryLock.RLock()
+	vars := make([]*ConfigVar, 0, len(varRegistry))
+	for _, v := range varRegistry {
+		vars = append(vars, v)
+	}
+	varRegistryLock.RUnlock()
+
+	slices.SortFunc(vars, func(a, b *ConfigVar) int {
+		if c := strings.Compare(a.Module, b.Module); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Key, b.Key)
+	})
+	return vars
+}
+
+// getVar returns the stored value of a variable, or its registered default.
+func getVar(key string) string {
+	if value := db.Get(key); value != "" {
+		return value
+	}
+	if v, ok := lookupVar(key); ok {
+		return v.Default
+	}
+	return ""
+}
+
+// parse checks value against the variable's type and validator and returns
+// the value to store.
+func (v *ConfigVar) parse(value string) (string, error) {
+	value = strings.TrimSpace(value)
+
+	switch v.Type {
+	case VarInt:
+		if _, err := strconv.Atoi(value); err != nil {
+			return "", fmt.Errorf("expected a whole number")
+		}
+	case VarBool:
+		switch strings.ToLower(value) {
+		case "true", "on", "yes", "1":
+			value = "true"
+		case "false", "off", "no", "0":
+			value = "false"
+		default:
+			return "", fmt.Errorf("expected on or off")
+		}
+	case VarChatID:
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return "", fmt.Errorf("expected a numeric chat id")
+		}
+	case VarURL:
+		u, err := url.Parse(value)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "", fmt.Errorf("expected an http(s) URL")
+		}
+	}
+
+	if v.Validate != nil {
+		return v.Validate(value)
+	}
+	return value, nil
+}
+
+// displayValue formats a value for chat output, masking secrets.
+func (v *ConfigVar) displayValue(value string) string {
+	if !v.Secret || value == "" {
+		return value
+	}
+	if len(value) <= 8 {
+		return "••••"
+	}
+	return value[:4] + "••••" + value[len(value)-2:]
+}
+
+func intRange(min, max int) func(string) (string, error) {
+	return func(value string) (string, error) {
+		n, _ := strconv.Atoi(value)
+		if n < min || n > max {
+			return "", fmt.Errorf("must be between %d and %d", min, max)
+		}
+		return value, nil
+	}
+}
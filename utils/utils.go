@@ -14,6 +14,8 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"sync"
+	"time"
 
 	"github.com/google/generative-ai-go/genai"
 	"github.com/nfnt/resize"
@@ -48,7 +50,7 @@ func compressImage(imagePath string) ([]byte, error) {
 
 func ProcessGemini(imagePath, text string) (string, error) {
 	ctx := context.Background()
-	key := db.RDb.Get(ctx, "GEMINI_API_KEY").Val()
+	key := db.Get("GEMINI_API_KEY")
 	if key == "" {
 		return "", fmt.Errorf("GEMINI_API_KEY is not set")
 	}
@@ -85,14 +87,41 @@ func ProcessGemini(imagePath, text string) (string, error) {
 	return fmt.Sprintf("%s", resp.Candidates[0].Content.Parts[0]), nil
 }
 
+var (
+	commandCtx, killCommands = context.WithCancel(context.Background())
+	commandJobs              sync.WaitGroup
+)
+
+// WaitCommands waits for running commands (ffmpeg jobs and the like) to
+// finish, killing whatever is left after timeout. It reports whether they all
+// finished on their own.
+func WaitCommands(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		commandJobs.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		killCommands()
+		return false
+	}
+}
+
 func RunCommand(cmd string) (string, error) {
+	commandJobs.Add(1)
+	defer commandJobs.Done()
+
 	var stderr bytes.Buffer
 	var stdout bytes.Buffer
 	var proc *exec.Cmd
 	if runtime.GOOS == "windows" {
-		proc = exec.Command("cmd", "/C", cmd)
+		proc = exec.CommandContext(commandCtx, "cmd", "/C", cmd)
 	} else {
-		proc = exec.Command("bash", "-c", cmd)
+		proc = exec.CommandContext(commandCtx, "bash", "-c", cmd)
 	}
 
 	proc.Stderr = &stderr